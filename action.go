@@ -6,8 +6,12 @@ type OptionFunc func(*Migrator) error
 type ActionConfigurator func(a *Action)
 
 type Action struct {
-	steps    int
-	versions []migration.Version
+	steps             int
+	versions          []migration.Version
+	allowDestructive  bool
+	allowIrreversible bool
+	seed              bool
+	seedConfigurators []SeedConfigurator
 }
 
 func WithSteps(steps int) ActionConfigurator {
@@ -22,6 +26,22 @@ func WithVersions(versions ...migration.Version) ActionConfigurator {
 	}
 }
 
+// AllowDestructive lets migrations flagged by the SQL analyzer run
+// when the destructive guard is on
+func AllowDestructive() ActionConfigurator {
+	return func(a *Action) {
+		a.allowDestructive = true
+	}
+}
+
+// AllowIrreversible lets irreversible migrations be rolled back,
+// their versions are removed from the migrations table without running anything
+func AllowIrreversible() ActionConfigurator {
+	return func(a *Action) {
+		a.allowIrreversible = true
+	}
+}
+
 func CreateConfigurators(steps int, versionStrings []string) ([]ActionConfigurator, error) {
 	var configurators []ActionConfigurator
 	if steps > 0 {
@@ -42,4 +62,3 @@ func CreateConfigurators(steps int, versionStrings []string) ([]ActionConfigurat
 
 	return configurators, nil
 }
-
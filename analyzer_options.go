@@ -0,0 +1,68 @@
+package tern
+
+import (
+	"github.com/denismitr/tern/v2/internal/analyzer"
+	"github.com/denismitr/tern/v2/migration"
+)
+
+// ErrDestructiveMigration - pending migrations were flagged by the SQL analyzer
+// and the destructive guard is on
+var ErrDestructiveMigration = analyzer.ErrDestructiveMigration
+
+type (
+	analyzerConfig struct {
+		disabledRules []string
+		guard         bool
+	}
+
+	AnalyzerConfigurator func(ac *analyzerConfig)
+)
+
+// UseSQLAnalyzer checks migrate statements of the pending migrations for dangerous SQL,
+// e.g. DROP TABLE, TRUNCATE or DELETE without WHERE, before any of them runs
+func UseSQLAnalyzer(configurators ...AnalyzerConfigurator) OptionFunc {
+	return func(m *Migrator) error {
+		ac := new(analyzerConfig)
+		for _, c := range configurators {
+			c(ac)
+		}
+
+		m.analyzerCfg = ac
+		return nil
+	}
+}
+
+// WithDisabledRules turns off the analyzer rules by their names
+func WithDisabledRules(rules ...string) AnalyzerConfigurator {
+	return func(ac *analyzerConfig) {
+		ac.disabledRules = append(ac.disabledRules, rules...)
+	}
+}
+
+// WithDestructiveGuard makes flagged migrations fail unless
+// AllowDestructive action configurator is used
+func WithDestructiveGuard() AnalyzerConfigurator {
+	return func(ac *analyzerConfig) {
+		ac.guard = true
+	}
+}
+
+// checkFunc - creates plan check that runs the analyzer over the scheduled migrations
+func (m *Migrator) checkFunc(act *Action) func(scheduled migration.Migrations) error {
+	if m.analyzer == nil {
+		return nil
+	}
+
+	return func(scheduled migration.Migrations) error {
+		findings := m.analyzer.Analyze(scheduled)
+		for _, f := range findings {
+			m.lg.Debugf("destructive statement: %s", f)
+		}
+
+		if !m.analyzerCfg.guard || act.allowDestructive {
+			return nil
+		}
+
+		return analyzer.Check(findings)
+	}
+}
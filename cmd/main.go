@@ -2,11 +2,14 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"github.com/denismitr/tern/v2"
 	"github.com/denismitr/tern/v2/internal/cli"
 	"github.com/denismitr/tern/v2/internal/database"
+	"github.com/denismitr/tern/v2/internal/source"
+	"github.com/denismitr/tern/v2/migration"
 	"github.com/logrusorgru/aurora/v3"
 	"github.com/pkg/errors"
 	"os"
@@ -22,14 +25,31 @@ func main() {
 
 	createCmd := flag.String("create", "", "create new migration")
 	noRollback := flag.Bool("no-rollback", false, "Create a new migration without a rollback")
+	goFlag := flag.Bool("go", false, "create a new migration as a Go file registering migrate and rollback functions")
 
 	migrateFlag := flag.Bool("migrate", false, "run the migrations")
 	rollbackFlag := flag.Bool("rollback", false, "rollback the migrations")
 	refreshFlag := flag.Bool("refresh", false, "refresh the migrations (rollback and then migrate again)")
+	resetFlag := flag.Bool("reset", false, "rollback all the migrations and migrate them again")
+	freshFlag := flag.Bool("fresh", false, "drop all tables and run all the migrations from scratch, not allowed in production")
+	lintFlag := flag.Bool("lint", false, "check the migrations without running them")
+	lintFormat := flag.String("lint-format", "text", "lint report format: text or json")
+	forceClean := flag.String("force-clean", "", "clear the dirty flag of the migration version after fixing the database by hand")
+	historyFlag := flag.Bool("history", false, "show the history of migrate and rollback steps, newest first")
+	historyLimit := flag.Int("history-limit", 20, "max number of history entries to show, 0 shows all of them")
+	seedFlag := flag.Bool("seed", false, "apply the seeds, together with migrate or fresh seeds run after the migrations")
+	labelList := flag.String("labels", "", "seed label list (comma separated) to limit the seeds to")
+	convertVersions := flag.String("convert-versions", "", "convert migration versions of the folder and the migrations table to datetime or timestamp")
 
 	timeout := flag.Int("timeout", defaultTimeout, "max timeout")
 	steps := flag.Int("steps", 0, "steps to execute")
 	versionList := flag.String("versions", "", "version list (comma separated) to perform action on")
+	allowDestructive := flag.Bool("allow-destructive", false, "run migrations flagged by the SQL analyzer")
+	allowIrreversible := flag.Bool("allow-irreversible", false, "rollback irreversible migrations by removing their versions only")
+	logFormat := flag.String("log-format", "color", "log format: color, text or json")
+	logLevel := flag.String("log-level", "sql", "min log level: sql, debug, info, warn or error")
+	sourceRef := flag.String("source-ref", "", "git revision (commit, branch or tag) to read the migrations folder at")
+	metricsTextfile := flag.String("metrics-textfile", "", "write Prometheus metrics of the run to the file, e.g. for the node exporter textfile collector")
 
 	flag.Parse()
 
@@ -51,19 +71,76 @@ func main() {
 		exitWithError(errors.New("choose between using steps and versions, you cannot have both"))
 	}
 
-	app, closer, err := cli.NewFromYaml(*configFile)
+	overrides := []cli.ConfigOverride{cli.WithLogFormat(*logFormat), cli.WithLogLevel(*logLevel)}
+	if *sourceRef != "" {
+		overrides = append(overrides, cli.WithSourceRef(*sourceRef))
+	}
+
+	if *metricsTextfile != "" {
+		overrides = append(overrides, cli.WithMetricsTextfile(*metricsTextfile))
+	}
+
+	if *allowDestructive {
+		overrides = append(overrides, cli.WithAllowDestructive())
+	}
+
+	if *allowIrreversible {
+		overrides = append(overrides, cli.WithAllowIrreversible())
+	}
+
+	if *labelList != "" {
+		overrides = append(overrides, cli.WithSeedLabels(strings.Split(*labelList, ",")...))
+	}
+
+	if *seedFlag && (*migrateFlag || *freshFlag) {
+		overrides = append(overrides, cli.WithSeedAfterMigrate())
+	}
+
+	app, closer, err := cli.NewFromYaml(*configFile, overrides...)
 	if err != nil {
 		exitWithError(err)
 	}
 
+	// metrics are written on exit, exitWithError skips the deferred functions
+	writeMetrics = app.WriteMetrics
+
+	// commands report failures with the exit code, so the closer still runs before exiting
+	exitCode := 0
+
 	defer func() {
+		flushMetrics()
+
 		if err := closer(); err != nil {
 			exitWithError(err)
 		}
+
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
 	}()
 
 	if *createCmd != "" {
-		createMigration(app, createCmd, noRollback)
+		createMigration(app, createCmd, noRollback, goFlag)
+		return
+	}
+
+	if *lintFlag {
+		exitCode = lint(app, *lintFormat, *timeout)
+		return
+	}
+
+	if *forceClean != "" {
+		clean(app, *forceClean, *timeout)
+		return
+	}
+
+	if *historyFlag {
+		history(app, *historyLimit, *timeout)
+		return
+	}
+
+	if *convertVersions != "" {
+		convert(app, migration.VersionFormat(*convertVersions), *timeout)
 		return
 	}
 
@@ -82,7 +159,162 @@ func main() {
 		return
 	}
 
-	exitWithError(errors.New("You need to choose on of commands: init-cfg, create, migrate, rollback, refresh"))
+	if *resetFlag {
+		reset(app, *timeout)
+		return
+	}
+
+	if *freshFlag {
+		fresh(app, *timeout)
+		return
+	}
+
+	if *seedFlag {
+		seed(app, *timeout)
+		return
+	}
+
+	exitWithError(errors.New("You need to choose on of commands: init-cfg, create, lint, history, force-clean, convert-versions, migrate, rollback, refresh, reset, fresh, seed"))
+}
+
+func clean(app *cli.App, version string, timeout int) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	if err := app.ForceClean(ctx, version); err != nil {
+		if errors.Is(err, database.ErrNoChangesRequired) {
+			green("Migration version %s is not dirty", version)
+			return
+		}
+
+		exitWithError(err)
+	}
+
+	green("Migration version %s is clean now", version)
+}
+
+func history(app *cli.App, limit int, timeout int) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	entries, err := app.History(ctx, limit)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	for _, e := range entries {
+		line := fmt.Sprintf(
+			"%s %-8s %s %s (%s) by %s@%s, tern %s",
+			e.StartedAt.Format(time.RFC3339), e.Operation, e.Table, e.Key, e.Duration, e.OSUser, e.Host, e.TernVersion,
+		)
+
+		if e.Success {
+			green("%s", line)
+		} else {
+			red("%s: %s", line, e.Error)
+		}
+	}
+
+	green("%d history entries", len(entries))
+}
+
+func seed(app *cli.App, timeout int) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	seeded, err := app.Seed(ctx)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	green("Seeding completed: %d seeds applied. All done...", len(seeded))
+}
+
+func reset(app *cli.App, timeout int) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	rolledBack, migrated, err := app.Reset(ctx)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	green("Reset completed: %d rolled back, %d migrated. All done...", len(rolledBack), len(migrated))
+}
+
+func fresh(app *cli.App, timeout int) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	dropped, migrated, err := app.Fresh(ctx)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	green("Fresh completed: %d tables dropped, %d migrated. All done...", len(dropped), len(migrated))
+}
+
+func convert(app *cli.App, to migration.VersionFormat, timeout int) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	conversions, err := app.ConvertVersions(ctx, to)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	if len(conversions) == 0 {
+		green("All migrations already use %s versions", to)
+		return
+	}
+
+	for _, c := range conversions {
+		green("%s -> %s", c.From, c.To)
+	}
+
+	green("%d migrations converted, set version_format to %s in the config file", len(conversions), to)
+}
+
+// lint - prints the report of the migrations check, exit code is 1 if it has errors
+func lint(app *cli.App, format string, timeout int) int {
+	if format != "text" && format != "json" {
+		exitWithError(errors.Errorf("unknown lint format [%s], use text or json", format))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	report, err := app.Lint(ctx)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	if format == "json" {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			exitWithError(err)
+		}
+	} else {
+		for _, issue := range report.Issues {
+			if issue.Severity == source.LintError {
+				red("%s [%s] %s: %s", issue.Severity, issue.Rule, issue.Path, issue.Message)
+			} else {
+				yellow("%s [%s] %s: %s", issue.Severity, issue.Rule, issue.Path, issue.Message)
+			}
+		}
+
+		green(
+			"%s: %d errors, %d warnings",
+			report.Location, report.Count(source.LintError), report.Count(source.LintWarning),
+		)
+	}
+
+	if report.HasErrors() {
+		return 1
+	}
+
+	return 0
 }
 
 func refresh(app *cli.App, steps int, versions []string, timeout int) {
@@ -135,8 +367,13 @@ func migrate(app *cli.App, steps int, versions []string, timeout int) {
 	green("Migration complete. All done...")
 }
 
-func createMigration(app *cli.App, createCmd *string, noRollback *bool) {
-	m, err := app.CreateMigration(*createCmd, !*noRollback)
+func createMigration(app *cli.App, createCmd *string, noRollback *bool, goFlag *bool) {
+	create := app.CreateMigration
+	if *goFlag {
+		create = app.CreateGoMigration
+	}
+
+	m, err := create(*createCmd, !*noRollback)
 	if err != nil {
 		exitWithError(err)
 	}
@@ -160,16 +397,35 @@ func createConfigFile(filename string) {
 }
 
 func green(s string, f ...interface{}) {
-	fmt.Printf(aurora.Green("tern-cli: ").String() + s, f...)
+	fmt.Printf(aurora.Green("tern-cli: ").String()+s, f...)
+	fmt.Println()
+}
+
+func yellow(s string, f ...interface{}) {
+	fmt.Printf(aurora.Yellow("tern-cli: ").String()+s, f...)
 	fmt.Println()
 }
 
 func red(s string, f ...interface{}) {
-	fmt.Printf(aurora.Red("tern-cli: ").String() + s, f...)
+	fmt.Printf(aurora.Red("tern-cli: ").String()+s, f...)
 	fmt.Println()
 }
 
+var writeMetrics = func() error { return nil }
+
+// flushMetrics - writes the metrics once, a failure to write them is reported but does not fail the command
+func flushMetrics() {
+	write := writeMetrics
+	writeMetrics = func() error { return nil }
+
+	if err := write(); err != nil {
+		yellow("could not write metrics: %s", err)
+	}
+}
+
 func exitWithError(err error) {
+	flushMetrics()
+
 	if errors.Is(err, tern.ErrNothingToMigrateOrRollback) {
 		green("Nothing to migrate or rollback")
 		os.Exit(0)
@@ -178,4 +434,4 @@ func exitWithError(err error) {
 	red(err.Error())
 	red("tern terminated with error")
 	os.Exit(1)
-}
\ No newline at end of file
+}
@@ -0,0 +1,110 @@
+package tern
+
+import (
+	"context"
+	"github.com/denismitr/tern/v2/internal/source"
+	"github.com/denismitr/tern/v2/migration"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+var ErrSourceCannotBeConverted = errors.New("migrations source does not support version conversion")
+
+// VersionConversion - key of a migration before and after the conversion
+type VersionConversion struct {
+	From string
+	To   string
+}
+
+// ConvertVersions - converts versions of all migrations to the format, renaming the source files
+// and the migrated versions in the migrations table, migrations already in the format are skipped.
+// Files are renamed back if the migrations table cannot be updated.
+func (m *Migrator) ConvertVersions(ctx context.Context, to migration.VersionFormat) ([]VersionConversion, error) {
+	renamer, ok := m.selector.(source.VersionRenamer)
+	if !ok {
+		return nil, ErrSourceCannotBeConverted
+	}
+
+	migrations, err := m.selector.Select(ctx, source.Filter{})
+	if err != nil {
+		return nil, err
+	}
+
+	conversions, versions, err := planConversions(migrations, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(conversions) == 0 {
+		return nil, nil
+	}
+
+	if connErr := m.gateway.Connect(ctx); connErr != nil {
+		return nil, connErr
+	}
+
+	keys := make(map[string]string, len(conversions))
+	reverted := make(map[string]string, len(conversions))
+	for _, c := range conversions {
+		keys[c.From] = c.To
+		reverted[c.To] = c.From
+	}
+
+	if err := renamer.RenameVersions(ctx, keys); err != nil {
+		return nil, errors.Wrap(err, "could not rename migration files")
+	}
+
+	if err := m.gateway.RenameVersions(ctx, versions); err != nil {
+		if revertErr := renamer.RenameVersions(context.Background(), reverted); revertErr != nil {
+			return nil, errors.Wrapf(err, "could not rename migration files back: %s", revertErr)
+		}
+
+		return nil, errors.Wrap(err, "could not rename migrated versions")
+	}
+
+	return conversions, nil
+}
+
+// planConversions - new keys of the migrations and the map of old to new versions,
+// fails if a converted version clashes with another migration
+func planConversions(
+	migrations migration.Migrations,
+	to migration.VersionFormat,
+) ([]VersionConversion, map[string]string, error) {
+	var conversions []VersionConversion
+	versions := make(map[string]string)
+	taken := make(map[string]string)
+
+	for _, mg := range migrations {
+		converted, err := migration.ConvertVersion(mg.Version, to)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "migration %s", mg.Key)
+		}
+
+		if other, ok := taken[converted.Value]; ok {
+			return nil, nil, errors.Wrapf(
+				source.ErrDuplicateVersion,
+				"%s and %s would both have version %s", other, mg.Key, converted.Value,
+			)
+		}
+
+		taken[converted.Value] = mg.Key
+
+		if converted.Value == mg.Version.Value {
+			continue
+		}
+
+		if !strings.HasPrefix(mg.Key, mg.Version.Value) {
+			return nil, nil, errors.Errorf("migration key %s does not start with its version %s", mg.Key, mg.Version.Value)
+		}
+
+		conversions = append(conversions, VersionConversion{
+			From: mg.Key,
+			To:   converted.Value + strings.TrimPrefix(mg.Key, mg.Version.Value),
+		})
+
+		versions[mg.Version.Value] = converted.Value
+	}
+
+	return conversions, versions, nil
+}
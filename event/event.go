@@ -0,0 +1,60 @@
+// Package event - typed progress events of the running operations, meant for applications
+// embedding tern that want to show live progress instead of parsing the log
+package event
+
+import (
+	"time"
+)
+
+// Kind - type of the event
+type Kind string
+
+const (
+	// PlanComputed - migrations scheduled by the operation are known, nothing has run yet
+	PlanComputed Kind = "plan_computed"
+	// LockWaiting - the operation is waiting for the database lock
+	LockWaiting Kind = "lock_waiting"
+	// LockAcquired - the database lock is held, Duration is the time spent waiting for it
+	LockAcquired Kind = "lock_acquired"
+	// MigrationStarted - migrate or rollback step of the migration started
+	MigrationStarted Kind = "migration_started"
+	// StatementExecuted - statement of the migration was executed, Err is set if it failed
+	StatementExecuted Kind = "statement_executed"
+	// MigrationFinished - migrate or rollback step of the migration finished, Err is set if it failed
+	MigrationFinished Kind = "migration_finished"
+	// OperationFinished - the operation succeeded or there was nothing to do
+	OperationFinished Kind = "operation_finished"
+	// OperationFailed - the operation failed and its transaction was rolled back
+	OperationFailed Kind = "operation_failed"
+)
+
+type (
+	// Event - progress of the running operation, fields that do not apply to the kind are empty
+	Event struct {
+		Kind      Kind
+		Time      time.Time
+		Operation string
+		// Table - migrations or seeds table the operation keeps versions in
+		Table     string
+		Key       string
+		Version   string
+		Statement string
+		// Migrate - keys of the migrations scheduled to be migrated, in order, PlanComputed only
+		Migrate []string
+		// Rollback - keys of the migrations scheduled to be rolled back, in order, PlanComputed only
+		Rollback []string
+		Duration time.Duration
+		Err      error
+	}
+
+	// Handler - receives the events synchronously from the goroutine running the operation
+	Handler func(e Event)
+)
+
+// Channel - handler sending every event to the channel, sends block
+// so the channel should be buffered and drained while the operation runs
+func Channel(ch chan<- Event) Handler {
+	return func(e Event) {
+		ch <- e
+	}
+}
@@ -0,0 +1,20 @@
+package tern
+
+import (
+	"github.com/denismitr/tern/v2/event"
+)
+
+// UseEventHandler delivers the progress events of every operation to the handler,
+// it is called synchronously from the goroutine running the operation
+func UseEventHandler(h event.Handler) OptionFunc {
+	return func(m *Migrator) error {
+		m.events = h
+		return nil
+	}
+}
+
+// UseEventChannel sends the progress events of every operation to the channel,
+// the channel should be buffered and drained while the operation runs
+func UseEventChannel(ch chan<- event.Event) OptionFunc {
+	return UseEventHandler(event.Channel(ch))
+}
@@ -0,0 +1,87 @@
+package tern
+
+import (
+	"context"
+	"github.com/denismitr/tern/v2/internal/database"
+	"github.com/denismitr/tern/v2/internal/source"
+	"github.com/denismitr/tern/v2/migration"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+var ErrProductionEnvironment = errors.New("operation is not allowed in production environment")
+
+// UseEnvironment sets the name of the environment the migrator runs in,
+// destructive operations like Fresh refuse to run in production
+func UseEnvironment(env string) OptionFunc {
+	return func(m *Migrator) error {
+		m.environment = env
+		return nil
+	}
+}
+
+// Reset rolls back all migrated migrations and migrates all of them again in a single operation,
+// irreversible migrations stop the reset unless AllowIrreversible is used
+func (m *Migrator) Reset(ctx context.Context, cfs ...ActionConfigurator) (migration.Migrations, migration.Migrations, error) {
+	act := new(Action)
+	for _, f := range cfs {
+		f(act)
+	}
+
+	migrations, err := m.migrations.Select(ctx, source.Filter{})
+	if err != nil {
+		m.lg.Error(err)
+		return nil, nil, err
+	}
+
+	if connErr := m.gateway.Connect(ctx); connErr != nil {
+		return nil, nil, connErr
+	}
+
+	p := database.Plan{Check: m.checkFunc(act), AllowIrreversible: act.allowIrreversible}
+	rolledBack, migrated, err := m.gateway.Reset(ctx, migrations, p)
+	if err != nil {
+		if errors.Is(err, database.ErrNoChangesRequired) {
+			return nil, nil, ErrNothingToMigrateOrRollback
+		}
+
+		m.lg.Error(err)
+		return nil, nil, err
+	}
+
+	return rolledBack, migrated, nil
+}
+
+// Fresh drops every view and table of the database, including the ones not created by migrations,
+// and migrates all of the migrations from scratch, it refuses to run in production environment
+func (m *Migrator) Fresh(ctx context.Context, cfs ...ActionConfigurator) ([]string, migration.Migrations, error) {
+	if m.isProduction() {
+		return nil, nil, errors.Wrapf(ErrProductionEnvironment, "fresh drops all tables of [%s] environment", m.environment)
+	}
+
+	if connErr := m.gateway.Connect(ctx); connErr != nil {
+		return nil, nil, connErr
+	}
+
+	dropped, err := m.gateway.DropAllTables(ctx)
+	if err != nil {
+		m.lg.Error(err)
+		return dropped, nil, errors.Wrap(err, "could not drop tables")
+	}
+
+	for _, table := range dropped {
+		m.lg.Successf("dropped: %s", table)
+	}
+
+	migrated, err := m.Migrate(ctx, cfs...)
+	if err != nil && !errors.Is(err, ErrNothingToMigrateOrRollback) {
+		return dropped, migrated, err
+	}
+
+	return dropped, migrated, nil
+}
+
+func (m *Migrator) isProduction() bool {
+	env := strings.ToLower(strings.TrimSpace(m.environment))
+	return env == "production" || env == "prod"
+}
@@ -0,0 +1,25 @@
+package tern
+
+import (
+	"context"
+	"github.com/denismitr/tern/v2/internal/database"
+)
+
+// HistoryEntry - migrate or rollback step kept in the append-only history table
+type HistoryEntry = database.HistoryEntry
+
+// History reads the latest entries of the history table, newest first, zero limit reads all of them.
+// Every migrate and rollback step of every operation is recorded there, including the failed ones.
+func (m *Migrator) History(ctx context.Context, limit int) ([]HistoryEntry, error) {
+	if connErr := m.gateway.Connect(ctx); connErr != nil {
+		return nil, connErr
+	}
+
+	entries, err := m.gateway.ReadHistory(ctx, limit)
+	if err != nil {
+		m.lg.Error(err)
+		return nil, err
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,103 @@
+package analyzer
+
+import (
+	"fmt"
+	"github.com/denismitr/tern/v2/migration"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+var ErrDestructiveMigration = errors.New("migration contains destructive statements")
+var ErrUnknownRule = errors.New("unknown analyzer rule")
+
+const (
+	DialectMySQL  = "mysql"
+	DialectSqlite = "sqlite"
+)
+
+type (
+	// Finding - statement of a migration flagged by one of the rules
+	Finding struct {
+		Rule      string
+		Key       string
+		Statement string
+	}
+
+	// Analyzer - checks migrate statements of the migrations against a set of rules
+	Analyzer struct {
+		rules []Rule
+	}
+)
+
+func (f Finding) String() string {
+	const maxStatementLength = 80
+
+	stmt := f.Statement
+	if len(stmt) > maxStatementLength {
+		stmt = stmt[:maxStatementLength] + "..."
+	}
+
+	return fmt.Sprintf("%s [%s] %s", f.Key, f.Rule, stmt)
+}
+
+// New creates an analyzer with all the rules of the dialect except the disabled ones
+func New(dialect string, disabled ...string) (*Analyzer, error) {
+	off := make(map[string]bool)
+	for _, name := range disabled {
+		if !isKnownRule(name) {
+			return nil, errors.Wrapf(ErrUnknownRule, "%s", name)
+		}
+
+		off[name] = true
+	}
+
+	var a Analyzer
+	for _, r := range rules {
+		if off[r.Name] || !r.appliesTo(dialect) {
+			continue
+		}
+
+		a.rules = append(a.rules, r)
+	}
+
+	return &a, nil
+}
+
+// Analyze - checks migrate statements of every migration, rollback statements are not checked
+// since they are expected to undo the changes
+func (a *Analyzer) Analyze(migrations migration.Migrations) []Finding {
+	var findings []Finding
+
+	for _, m := range migrations {
+		for _, script := range m.Migrate {
+			for _, stmt := range Statements(script) {
+				normalized := normalize(stmt)
+				for _, r := range a.rules {
+					if r.skips != nil && r.skips(m) {
+						continue
+					}
+
+					if r.matches(normalized) {
+						findings = append(findings, Finding{Rule: r.Name, Key: m.Key, Statement: stmt})
+					}
+				}
+			}
+		}
+	}
+
+	return findings
+}
+
+// Check - returns ErrDestructiveMigration listing all findings, nil if there are none
+func Check(findings []Finding) error {
+	if len(findings) == 0 {
+		return nil
+	}
+
+	described := make([]string, len(findings))
+	for i := range findings {
+		described[i] = findings[i].String()
+	}
+
+	return errors.Wrapf(ErrDestructiveMigration, "%s", strings.Join(described, "; "))
+}
@@ -0,0 +1,122 @@
+package analyzer
+
+import (
+	"github.com/denismitr/tern/v2/migration"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestStatements(t *testing.T) {
+	script := `
+-- creates foo; and bar
+CREATE TABLE foo (id INT, name VARCHAR(10) DEFAULT 'a;b');
+/* comment; */ INSERT INTO foo VALUES (1, "x;y");
+CREATE TABLE bar (id INT)
+`
+
+	assert.Equal(t, []string{
+		"-- creates foo; and bar\nCREATE TABLE foo (id INT, name VARCHAR(10) DEFAULT 'a;b')",
+		`/* comment; */ INSERT INTO foo VALUES (1, "x;y")`,
+		"CREATE TABLE bar (id INT)",
+	}, Statements(script))
+}
+
+func TestCodeEnd(t *testing.T) {
+	for stmt, code := range map[string]string{
+		"ALTER TABLE foo ADD x INT":                         "ALTER TABLE foo ADD x INT",
+		"ALTER TABLE foo ADD x INT -- note":                 "ALTER TABLE foo ADD x INT",
+		"ALTER TABLE foo ADD x INT /* a */ # b\n-- c":       "ALTER TABLE foo ADD x INT",
+		"ALTER TABLE foo COMMENT 'é -- not a comment' -- x": "ALTER TABLE foo COMMENT 'é -- not a comment'",
+		"-- only a comment":                                 "",
+	} {
+		assert.Equal(t, code, stmt[:CodeEnd(stmt)])
+	}
+}
+
+func TestAnalyzer_Analyze(t *testing.T) {
+	tt := []struct {
+		stmt    string
+		dialect string
+		rules   []string
+	}{
+		{stmt: "CREATE TABLE foo (id INT)", dialect: DialectMySQL},
+		{stmt: "drop table foo", dialect: DialectSqlite, rules: []string{RuleDropTable}},
+		{stmt: "DROP TABLE IF EXISTS foo", dialect: DialectMySQL, rules: []string{RuleDropTable}},
+		{stmt: "ALTER TABLE foo DROP COLUMN bar, ALGORITHM=INPLACE, LOCK=NONE", dialect: DialectMySQL, rules: []string{RuleDropColumn}},
+		{stmt: "ALTER TABLE foo DROP bar", dialect: DialectSqlite, rules: []string{RuleDropColumn}},
+		{stmt: "ALTER TABLE foo DROP INDEX idx_bar, ALGORITHM=INPLACE", dialect: DialectMySQL},
+		{stmt: "ALTER TABLE foo ADD COLUMN bar INT", dialect: DialectMySQL, rules: []string{RuleUnsafeAlter}},
+		{stmt: "ALTER TABLE foo ADD COLUMN bar INT", dialect: DialectSqlite},
+		{stmt: "TRUNCATE TABLE foo", dialect: DialectMySQL, rules: []string{RuleTruncate}},
+		{stmt: "DELETE FROM foo", dialect: DialectMySQL, rules: []string{RuleDeleteWithoutWhere}},
+		{stmt: "DELETE FROM foo WHERE id = 1", dialect: DialectMySQL},
+		{stmt: "UPDATE foo SET bar = 1", dialect: DialectMySQL, rules: []string{RuleUpdateWithoutWhere}},
+		{stmt: "update foo\n  set bar = 1\n  where id = 2", dialect: DialectMySQL},
+		{stmt: "RENAME TABLE foo TO bar", dialect: DialectMySQL, rules: []string{RuleRename}},
+		{stmt: "ALTER TABLE foo RENAME COLUMN a TO b", dialect: DialectSqlite, rules: []string{RuleRename}},
+		{stmt: "-- DROP TABLE foo\nSELECT 1", dialect: DialectMySQL},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.stmt, func(t *testing.T) {
+			a, err := New(tc.dialect)
+			require.NoError(t, err)
+
+			findings := a.Analyze(migration.Migrations{{Key: "1596897167_foo", Migrate: []string{tc.stmt}}})
+
+			var rules []string
+			for _, f := range findings {
+				rules = append(rules, f.Rule)
+			}
+
+			assert.Equal(t, tc.rules, rules)
+		})
+	}
+}
+
+func TestAnalyzer_LockDirective(t *testing.T) {
+	a, err := New(DialectMySQL)
+	require.NoError(t, err)
+
+	findings := a.Analyze(migration.Migrations{{
+		Key:      "1596897167_foo",
+		Migrate:  []string{"ALTER TABLE foo ADD COLUMN bar INT; ALTER TABLE foo DROP COLUMN baz;"},
+		LockMode: "none",
+	}})
+
+	require.Len(t, findings, 1)
+	assert.Equal(t, RuleDropColumn, findings[0].Rule)
+}
+
+func TestAnalyzer_DisabledRules(t *testing.T) {
+	migrations := migration.Migrations{{
+		Key:      "1596897167_foo",
+		Migrate:  []string{"RENAME TABLE foo TO bar; DROP TABLE baz;"},
+		Rollback: []string{"DROP TABLE bar;"},
+	}}
+
+	t.Run("disabled rules are skipped and rollback is not checked", func(t *testing.T) {
+		a, err := New(DialectMySQL, RuleRename)
+		require.NoError(t, err)
+
+		findings := a.Analyze(migrations)
+		require.Len(t, findings, 1)
+		assert.Equal(t, RuleDropTable, findings[0].Rule)
+		assert.Equal(t, "DROP TABLE baz", findings[0].Statement)
+
+		err = Check(findings)
+		assert.True(t, errors.Is(err, ErrDestructiveMigration))
+		assert.Contains(t, err.Error(), "1596897167_foo [drop-table] DROP TABLE baz")
+	})
+
+	t.Run("unknown rule", func(t *testing.T) {
+		_, err := New(DialectMySQL, "drop-database")
+		assert.True(t, errors.Is(err, ErrUnknownRule))
+	})
+
+	t.Run("no findings pass the check", func(t *testing.T) {
+		assert.NoError(t, Check(nil))
+	})
+}
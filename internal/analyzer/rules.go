@@ -0,0 +1,147 @@
+package analyzer
+
+import (
+	"github.com/denismitr/tern/v2/migration"
+	"regexp"
+	"strings"
+)
+
+const (
+	RuleDropTable          = "drop-table"
+	RuleDropColumn         = "drop-column"
+	RuleTruncate           = "truncate"
+	RuleDeleteWithoutWhere = "delete-without-where"
+	RuleUpdateWithoutWhere = "update-without-where"
+	RuleUnsafeAlter        = "alter-without-algorithm"
+	RuleRename             = "rename"
+)
+
+// Rule - flags statements matching the rule, statements are normalized
+// to upper case with comments removed and whitespace collapsed
+type Rule struct {
+	Name        string
+	Description string
+	dialects    []string
+	matches     func(stmt string) bool
+	// skips - the migration is known to be safe for the rule, e.g. because of its directives
+	skips func(m *migration.Migration) bool
+}
+
+var (
+	dropTableRx   = regexp.MustCompile(`^DROP\s+TABLE\b`)
+	alterTableRx  = regexp.MustCompile(`^ALTER\s+TABLE\b`)
+	dropRx        = regexp.MustCompile(`\bDROP\s+(?:COLUMN\s+)?([A-Z_` + "`" + `"]+)`)
+	truncateRx    = regexp.MustCompile(`^TRUNCATE\b`)
+	deleteRx      = regexp.MustCompile(`^DELETE\b`)
+	updateRx      = regexp.MustCompile(`^UPDATE\b`)
+	whereRx       = regexp.MustCompile(`\bWHERE\b`)
+	algorithmRx   = regexp.MustCompile(`\b(?:ALGORITHM|LOCK)\s*=`)
+	renameTableRx = regexp.MustCompile(`^RENAME\s+TABLES?\b`)
+	renameRx      = regexp.MustCompile(`\bRENAME\s+(?:COLUMN\b|TO\b|AS\b)`)
+)
+
+// keywords that can follow DROP in ALTER TABLE without dropping a column
+var nonColumnDrops = map[string]bool{
+	"INDEX":      true,
+	"KEY":        true,
+	"PRIMARY":    true,
+	"FOREIGN":    true,
+	"CONSTRAINT": true,
+	"CHECK":      true,
+	"PARTITION":  true,
+	"DEFAULT":    true,
+}
+
+var rules = []Rule{
+	{
+		Name:        RuleDropTable,
+		Description: "DROP TABLE removes the table with all of its data",
+		matches:     dropTableRx.MatchString,
+	},
+	{
+		Name:        RuleDropColumn,
+		Description: "ALTER TABLE ... DROP COLUMN removes the column data",
+		matches: func(stmt string) bool {
+			if !alterTableRx.MatchString(stmt) {
+				return false
+			}
+
+			for _, m := range dropRx.FindAllStringSubmatch(stmt, -1) {
+				if !nonColumnDrops[m[1]] {
+					return true
+				}
+			}
+
+			return false
+		},
+	},
+	{
+		Name:        RuleTruncate,
+		Description: "TRUNCATE removes all rows of the table",
+		matches:     truncateRx.MatchString,
+	},
+	{
+		Name:        RuleDeleteWithoutWhere,
+		Description: "DELETE without WHERE removes all rows of the table",
+		matches: func(stmt string) bool {
+			return deleteRx.MatchString(stmt) && !whereRx.MatchString(stmt)
+		},
+	},
+	{
+		Name:        RuleUpdateWithoutWhere,
+		Description: "UPDATE without WHERE changes all rows of the table",
+		matches: func(stmt string) bool {
+			return updateRx.MatchString(stmt) && !whereRx.MatchString(stmt)
+		},
+	},
+	{
+		Name:        RuleUnsafeAlter,
+		Description: "ALTER TABLE without ALGORITHM or LOCK clause may rewrite and lock the whole table",
+		dialects:    []string{DialectMySQL},
+		matches: func(stmt string) bool {
+			return alterTableRx.MatchString(stmt) && !algorithmRx.MatchString(stmt)
+		},
+		// the lock directive makes the gateway add the LOCK clause when the migration runs
+		skips: func(m *migration.Migration) bool {
+			return m.LockMode != ""
+		},
+	},
+	{
+		Name:        RuleRename,
+		Description: "renaming tables or columns breaks the code still using the old names",
+		matches: func(stmt string) bool {
+			return renameTableRx.MatchString(stmt) || (alterTableRx.MatchString(stmt) && renameRx.MatchString(stmt))
+		},
+	},
+}
+
+// Rules - all available rules
+func Rules() []Rule {
+	result := make([]Rule, len(rules))
+	copy(result, rules)
+	return result
+}
+
+func (r Rule) appliesTo(dialect string) bool {
+	if len(r.dialects) == 0 {
+		return true
+	}
+
+	for _, d := range r.dialects {
+		if strings.EqualFold(d, dialect) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isKnownRule(name string) bool {
+	for _, r := range rules {
+		if r.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,185 @@
+package analyzer
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Statements splits the script into separate statements by semicolons,
+// semicolons inside of quotes and comments do not end a statement
+func Statements(script string) []string {
+	var result []string
+	var current strings.Builder
+
+	flush := func() {
+		if stmt := strings.TrimSpace(current.String()); stmt != "" && !isComment(stmt) {
+			result = append(result, stmt)
+		}
+
+		current.Reset()
+	}
+
+	var quote rune
+	var lineComment, blockComment bool
+	runes := []rune(script)
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+
+		switch {
+		case lineComment:
+			if r == '\n' {
+				lineComment = false
+			}
+		case blockComment:
+			if r == '*' && next == '/' {
+				blockComment = false
+				current.WriteRune(r)
+				i++
+				r = next
+			}
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '-' && next == '-', r == '#':
+			lineComment = true
+		case r == '/' && next == '*':
+			blockComment = true
+		case r == ';':
+			flush()
+			continue
+		}
+
+		current.WriteRune(r)
+	}
+
+	flush()
+
+	return result
+}
+
+// normalize - removes comments, collapses whitespace and converts the statement to upper case
+func normalize(stmt string) string {
+	var b strings.Builder
+	var quote rune
+	var lineComment, blockComment, space bool
+	runes := []rune(stmt)
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+
+		switch {
+		case lineComment:
+			if r == '\n' {
+				lineComment = false
+				space = true
+			}
+			continue
+		case blockComment:
+			if r == '*' && next == '/' {
+				blockComment = false
+				space = true
+				i++
+			}
+			continue
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '-' && next == '-', r == '#':
+			lineComment = true
+			continue
+		case r == '/' && next == '*':
+			blockComment = true
+			continue
+		case unicode.IsSpace(r):
+			space = true
+			continue
+		}
+
+		if space && b.Len() > 0 {
+			b.WriteRune(' ')
+		}
+
+		space = false
+		b.WriteRune(unicode.ToUpper(r))
+	}
+
+	return b.String()
+}
+
+// Normalize - the statement without comments, with collapsed whitespace and in upper case,
+// e.g. to look for a clause that must not be matched inside of a comment
+func Normalize(stmt string) string {
+	return normalize(stmt)
+}
+
+// CodeEnd - byte offset right after the last character of the statement that is not
+// a part of a comment, e.g. to append a clause before the trailing comments
+func CodeEnd(stmt string) int {
+	var quote rune
+	var lineComment, blockComment bool
+	var end, offset int
+	runes := []rune(stmt)
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+
+		size := len(string(r))
+
+		switch {
+		case lineComment:
+			if r == '\n' {
+				lineComment = false
+			}
+		case blockComment:
+			if r == '*' && next == '/' {
+				blockComment = false
+				i++
+				size++
+			}
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+			end = offset + size
+		case r == '-' && next == '-', r == '#':
+			lineComment = true
+		case r == '/' && next == '*':
+			blockComment = true
+			i++
+			size++
+		case unicode.IsSpace(r):
+		default:
+			if r == '\'' || r == '"' || r == '`' {
+				quote = r
+			}
+			end = offset + size
+		}
+
+		offset += size
+	}
+
+	return end
+}
+
+func isComment(stmt string) bool {
+	return normalize(stmt) == ""
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/denismitr/tern/v2"
 	"github.com/denismitr/tern/v2/internal/source"
+	"github.com/denismitr/tern/v2/metrics"
 	"github.com/denismitr/tern/v2/migration"
 	"github.com/pkg/errors"
 	"io"
@@ -16,7 +17,11 @@ var (
 	ErrMigrationAlreadyExists = errors.New("migration already exists")
 	ErrFolderInvalid          = errors.New("migrations folder is invalid")
 	ErrSourceTypeIsNotValid   = errors.New("source type is not valid")
-	ErrInvalidVersionFormat   = errors.New("invalid version format: allowed formats are datetime and timestamp")
+	ErrInvalidVersionFormat   = errors.New("invalid version format: allowed formats are datetime, timestamp, number or a registered custom format")
+	ErrSourceIsReadOnly       = errors.New("migrations source is read only")
+	ErrSourceCannotBeLinted   = errors.New("migrations source cannot be linted")
+	ErrSourceCannotCreateGo   = errors.New("migrations source cannot create Go migrations")
+	ErrInvalidLogFormat       = errors.New("invalid log format: allowed formats are color, text and json")
 )
 
 type (
@@ -26,59 +31,259 @@ type (
 		DatabaseUrl      string
 		MigrationsFolder string
 		VersionFormat    migration.VersionFormat
+		Recursive        bool
+		CreatePath       string
+		SingleFile       bool
+		ArchivePath      string
+		ArchiveFolder    string
+		SourceRef        string
+		Environment      string
+		SeedsFolder      string
+		SeedLabels       []string
+		SeedAfterMigrate bool
+		LogFormat        string
+		LogLevel         string
+		MetricsTextfile  string
+		MetricsKeyLabel  bool
+		SessionInit      []string
+		StatementTimeout time.Duration
+		MigrationTimeout time.Duration
+
+		Analyzer                bool
+		RequireAllowDestructive bool
+		DisabledRules           []string
+		AllowDestructive        bool
+		AllowIrreversible       bool
 	}
 
+	// ConfigOverride - overrides config file values with the command line arguments
+	ConfigOverride func(cfg *Config)
+
 	App struct {
 		source   source.Source
 		migrator *tern.Migrator
 		vf       migration.VersionFormat
+		driver   string
+
+		allowDestructive  bool
+		allowIrreversible bool
+		seedLabels        []string
+		seedAfterMigrate  bool
+		metrics           *metrics.Prometheus
+		metricsTextfile   string
 	}
 )
 
-func NewFromYaml(path string) (*App, CloserFunc, error) {
+func NewFromYaml(path string, overrides ...ConfigOverride) (*App, CloserFunc, error) {
 	cfg, err := createConfigFromYaml(path)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	for _, o := range overrides {
+		o(&cfg)
+	}
+
 	return New(cfg)
 }
 
+// WithSourceRef makes migrations to be read from the local folder
+// as it exists at the ref revision of the git repository
+func WithSourceRef(ref string) ConfigOverride {
+	return func(cfg *Config) {
+		cfg.SourceRef = ref
+	}
+}
+
+// WithAllowDestructive lets migrations flagged by the SQL analyzer run
+func WithAllowDestructive() ConfigOverride {
+	return func(cfg *Config) {
+		cfg.AllowDestructive = true
+	}
+}
+
+// WithAllowIrreversible lets irreversible migrations be rolled back
+func WithAllowIrreversible() ConfigOverride {
+	return func(cfg *Config) {
+		cfg.AllowIrreversible = true
+	}
+}
+
+// WithLogFormat sets the format of the log: color, text or json
+func WithLogFormat(format string) ConfigOverride {
+	return func(cfg *Config) {
+		cfg.LogFormat = format
+	}
+}
+
+// WithLogLevel sets the min level of the log entries: sql, debug, info, warn or error
+func WithLogLevel(level string) ConfigOverride {
+	return func(cfg *Config) {
+		cfg.LogLevel = level
+	}
+}
+
+// WithMetricsTextfile writes the metrics of the run to the node exporter textfile
+func WithMetricsTextfile(path string) ConfigOverride {
+	return func(cfg *Config) {
+		cfg.MetricsTextfile = path
+	}
+}
+
+// WithSeedLabels limits seeding to the seeds having at least one of the labels
+func WithSeedLabels(labels ...string) ConfigOverride {
+	return func(cfg *Config) {
+		cfg.SeedLabels = labels
+	}
+}
+
+// WithSeedAfterMigrate runs the seeds after migrate and fresh
+func WithSeedAfterMigrate() ConfigOverride {
+	return func(cfg *Config) {
+		cfg.SeedAfterMigrate = true
+	}
+}
+
 func New(cfg Config) (*App, CloserFunc, error) {
-	m, closer, err := createMigrator(cfg)
+	var extra []tern.OptionFunc
+	var collector *metrics.Prometheus
+	if cfg.MetricsTextfile != "" {
+		collector = metrics.NewPrometheus()
+		if cfg.MetricsKeyLabel {
+			collector.WithKeyLabel()
+		}
+		extra = append(extra, tern.UseMetrics(collector))
+	}
+
+	m, closer, err := createMigrator(cfg, extra...)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	s := m.Source()
-	if s == nil {
-		return nil, nil, ErrSourceTypeIsNotValid
+	driver, err := driverFromURL(cfg.DatabaseUrl)
+	if err != nil {
+		return nil, nil, err
 	}
 
+	// source is nil for read only sources e.g. archives
 	return &App{
-		source:   s,
+		source:   m.Source(),
 		migrator: m,
 		vf:       cfg.VersionFormat,
+		driver:   driver,
+
+		allowDestructive:  cfg.AllowDestructive,
+		allowIrreversible: cfg.AllowIrreversible,
+		seedLabels:        cfg.SeedLabels,
+		seedAfterMigrate:  cfg.SeedAfterMigrate,
+		metrics:           collector,
+		metricsTextfile:   cfg.MetricsTextfile,
 	}, CloserFunc(closer), nil
 }
 
+// WriteMetrics - writes the metrics of the run to the textfile if one is configured,
+// it is meant to be called once the command finished, whether it failed or not
+func (app *App) WriteMetrics() error {
+	if app.metrics == nil {
+		return nil
+	}
+
+	return app.metrics.WriteTextfile(app.metricsTextfile)
+}
+
 func (app *App) CreateMigration(
 	name string,
 	withRollback bool,
 ) (*migration.Migration, error) {
+	v, name, err := app.nextMigration(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return app.source.Create(v.Value, name, withRollback)
+}
+
+// CreateGoMigration - creates a Go migration file registering its functions with migration.Register
+func (app *App) CreateGoMigration(name string, withRollback bool) (*migration.Migration, error) {
+	creator, ok := app.source.(source.GoCreator)
+	if !ok {
+		return nil, ErrSourceCannotCreateGo
+	}
+
+	v, name, err := app.nextMigration(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return creator.CreateGo(v.Value, name, withRollback)
+}
+
+// nextMigration - version and normalized name of a new migration
+func (app *App) nextMigration(name string) (migration.Version, string, error) {
+	if app.source == nil {
+		return migration.Version{}, "", ErrSourceIsReadOnly
+	}
+
 	if !app.source.IsValid() {
-		return nil, ErrFolderInvalid
+		return migration.Version{}, "", ErrFolderInvalid
 	}
 
 	name = strings.ReplaceAll(name, "-", "_")
 
-	v := migration.GenerateVersion(time.Now, app.vf)
+	existing, err := app.existingVersions()
+	if err != nil {
+		return migration.Version{}, "", err
+	}
+
+	v, err := migration.NextVersion(time.Now, app.vf, existing...)
+	if err != nil {
+		return migration.Version{}, "", err
+	}
 
 	if app.source.AlreadyExists(v.Value, name) {
-		return nil, errors.Wrapf(ErrMigrationAlreadyExists, "dt [%s] name [%s]", v.Value, name)
+		return migration.Version{}, "", errors.Wrapf(ErrMigrationAlreadyExists, "dt [%s] name [%s]", v.Value, name)
 	}
 
-	return app.source.Create(v.Value, name, withRollback)
+	return v, name, nil
+}
+
+// Lint - checks migrations of the source without running them
+func (app *App) Lint(ctx context.Context) (*source.LintReport, error) {
+	linter := app.migrator.Linter()
+	if linter == nil {
+		return nil, ErrSourceCannotBeLinted
+	}
+
+	return linter.Lint(ctx, source.LintRules{MaxNameLength: nameColumnLength(app.driver)})
+}
+
+// ConvertVersions - converts the migration files and the migrated versions to the time based format
+func (app *App) ConvertVersions(ctx context.Context, to migration.VersionFormat) ([]tern.VersionConversion, error) {
+	if to != migration.DatetimeFormat && to != migration.TimestampFormat {
+		return nil, errors.Wrapf(ErrInvalidVersionFormat, "versions can be converted only to datetime or timestamp, got %s", to)
+	}
+
+	return app.migrator.ConvertVersions(ctx, to)
+}
+
+// existingVersions - versions of the source migrations, time based versions
+// do not depend on the existing ones
+func (app *App) existingVersions() ([]migration.Version, error) {
+	if app.vf == migration.TimestampFormat || app.vf == migration.DatetimeFormat {
+		return nil, nil
+	}
+
+	migrations, err := app.source.Select(context.Background(), source.Filter{})
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read existing migrations")
+	}
+
+	versions := make([]migration.Version, len(migrations))
+	for i := range migrations {
+		versions[i] = migrations[i].Version
+	}
+
+	return versions, nil
 }
 
 func (app *App) Migrate(ctx context.Context, steps int, versions []string) error {
@@ -87,6 +292,14 @@ func (app *App) Migrate(ctx context.Context, steps int, versions []string) error
 		return err
 	}
 
+	if app.allowDestructive {
+		configurators = append(configurators, tern.AllowDestructive())
+	}
+
+	if app.seedAfterMigrate {
+		configurators = append(configurators, app.seeding())
+	}
+
 	if _, migrateErr := app.migrator.Migrate(ctx, configurators...); migrateErr != nil {
 		return migrateErr
 	}
@@ -100,6 +313,10 @@ func (app *App) Rollback(ctx context.Context, steps int, versions []string) erro
 		return err
 	}
 
+	if app.allowIrreversible {
+		configurators = append(configurators, tern.AllowIrreversible())
+	}
+
 	if _, rollbackErr := app.migrator.Rollback(ctx, configurators...); rollbackErr != nil {
 		return rollbackErr
 	}
@@ -113,6 +330,14 @@ func (app *App) Refresh(ctx context.Context, steps int, versions []string) error
 		return err
 	}
 
+	if app.allowDestructive {
+		configurators = append(configurators, tern.AllowDestructive())
+	}
+
+	if app.allowIrreversible {
+		configurators = append(configurators, tern.AllowIrreversible())
+	}
+
 	if _, _, refreshErr := app.migrator.Refresh(ctx, configurators...); refreshErr != nil {
 		return refreshErr
 	}
@@ -120,6 +345,53 @@ func (app *App) Refresh(ctx context.Context, steps int, versions []string) error
 	return nil
 }
 
+// Reset - rolls back all migrations and migrates them again
+func (app *App) Reset(ctx context.Context) (migration.Migrations, migration.Migrations, error) {
+	var configurators []tern.ActionConfigurator
+	if app.allowDestructive {
+		configurators = append(configurators, tern.AllowDestructive())
+	}
+
+	if app.allowIrreversible {
+		configurators = append(configurators, tern.AllowIrreversible())
+	}
+
+	return app.migrator.Reset(ctx, configurators...)
+}
+
+// Fresh - drops all tables and migrates all migrations from scratch
+func (app *App) Fresh(ctx context.Context) ([]string, migration.Migrations, error) {
+	var configurators []tern.ActionConfigurator
+	if app.allowDestructive {
+		configurators = append(configurators, tern.AllowDestructive())
+	}
+
+	if app.seedAfterMigrate {
+		configurators = append(configurators, app.seeding())
+	}
+
+	return app.migrator.Fresh(ctx, configurators...)
+}
+
+// ForceClean - clears the dirty flag of the migration version
+func (app *App) ForceClean(ctx context.Context, version string) error {
+	return app.migrator.ForceClean(ctx, version)
+}
+
+// History - latest entries of the history table, newest first
+func (app *App) History(ctx context.Context, limit int) ([]tern.HistoryEntry, error) {
+	return app.migrator.History(ctx, limit)
+}
+
+// Seed - applies the seeds of the environment that have not been applied yet
+func (app *App) Seed(ctx context.Context) (migration.Migrations, error) {
+	return app.migrator.Seed(ctx, tern.WithLabels(app.seedLabels...))
+}
+
+func (app *App) seeding() tern.ActionConfigurator {
+	return tern.WithSeeding(tern.WithLabels(app.seedLabels...))
+}
+
 func InitCfg(path string) error {
 	f, err := os.Create(path)
 	if err != nil {
@@ -147,4 +419,4 @@ func FileExists(path string) bool {
 		return false
 	}
 	return !info.IsDir()
-}
\ No newline at end of file
+}
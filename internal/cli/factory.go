@@ -2,6 +2,7 @@ package cli
 
 import (
 	"github.com/denismitr/tern/v2"
+	"github.com/denismitr/tern/v2/internal/database/sqlgateway"
 	"github.com/denismitr/tern/v2/migration"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
@@ -12,26 +13,60 @@ import (
 	"log"
 	"os"
 	"strings"
+	"time"
 )
 
 type (
-	migratorFactory    func(cfg Config) (*tern.Migrator, tern.CloserFunc, error)
+	migratorFactory    func(cfg Config, extra ...tern.OptionFunc) (*tern.Migrator, tern.CloserFunc, error)
 	migratorFactoryMap map[string]migratorFactory
 
+	archive struct {
+		Path   string `yaml:"path"`
+		Folder string `yaml:"folder"`
+	}
+
+	analyzer struct {
+		Enabled                 bool     `yaml:"enabled"`
+		RequireAllowDestructive bool     `yaml:"require_allow_destructive"`
+		DisabledRules           []string `yaml:"disabled_rules"`
+	}
+
 	migrations struct {
-		LocalFolder   string `yaml:"local_folder"`
-		DatabaseURL   string `yaml:"database_url"`
-		VersionFormat string `yaml:"version_format"`
+		LocalFolder   string  `yaml:"local_folder"`
+		DatabaseURL   string  `yaml:"database_url"`
+		VersionFormat string  `yaml:"version_format"`
+		Recursive     bool    `yaml:"recursive"`
+		CreatePath    string  `yaml:"create_path"`
+		SingleFile    bool    `yaml:"single_file"`
+		Archive       archive `yaml:"archive"`
 	}
 
-	configFile struct {
-		Version    string     `yaml:"version"`
-		Migrations migrations `yaml:"migrations"`
+	seeds struct {
+		LocalFolder     string   `yaml:"local_folder"`
+		Labels          []string `yaml:"labels"`
+		RunAfterMigrate bool     `yaml:"run_after_migrate"`
+	}
+
+	metricsConfig struct {
+		Textfile string `yaml:"textfile"`
+		KeyLabel bool   `yaml:"key_label"`
+	}
+
+	session struct {
+		Init             []string `yaml:"init"`
+		StatementTimeout string   `yaml:"statement_timeout"`
+		MigrationTimeout string   `yaml:"migration_timeout"`
 	}
-)
 
-var (
-	allowedVersionFormats = []migration.VersionFormat{migration.TimestampFormat, migration.DatetimeFormat}
+	configFile struct {
+		Version     string        `yaml:"version"`
+		Environment string        `yaml:"environment"`
+		Migrations  migrations    `yaml:"migrations"`
+		Seeds       seeds         `yaml:"seeds"`
+		Analyzer    analyzer      `yaml:"analyzer"`
+		Metrics     metricsConfig `yaml:"metrics"`
+		Session     session       `yaml:"session"`
+	}
 )
 
 func createConfigFromYaml(path string) (Config, error) {
@@ -73,65 +108,208 @@ func createConfigFromYaml(path string) (Config, error) {
 		return cfg, errors.New("database url was not defined")
 	}
 
-	if cfg.MigrationsFolder == "" {
+	cfg.ArchivePath = cfgFile.Migrations.Archive.Path
+	cfg.ArchiveFolder = cfgFile.Migrations.Archive.Folder
+
+	if cfg.MigrationsFolder == "" && cfg.ArchivePath == "" {
 		return cfg, errors.New("migrations folder was not defined")
 	}
 
-	for _, format := range allowedVersionFormats {
-		if string(format) == cfgFile.Migrations.VersionFormat {
-			cfg.VersionFormat = format
-		}
+	cfg.VersionFormat = migration.VersionFormat(cfgFile.Migrations.VersionFormat)
+	if _, err := migration.LookupFormat(cfg.VersionFormat); err != nil || cfg.VersionFormat == migration.AnyFormat {
+		return cfg, ErrInvalidVersionFormat
 	}
 
-	if cfg.VersionFormat == "" {
-		return cfg, ErrInvalidVersionFormat
+	cfg.Recursive = cfgFile.Migrations.Recursive
+	cfg.CreatePath = cfgFile.Migrations.CreatePath
+	cfg.SingleFile = cfgFile.Migrations.SingleFile
+
+	cfg.Environment = cfgFile.Environment
+	cfg.SeedsFolder = cfgFile.Seeds.LocalFolder
+	cfg.SeedLabels = cfgFile.Seeds.Labels
+	cfg.SeedAfterMigrate = cfgFile.Seeds.RunAfterMigrate
+	cfg.MetricsTextfile = cfgFile.Metrics.Textfile
+	cfg.MetricsKeyLabel = cfgFile.Metrics.KeyLabel
+	cfg.SessionInit = cfgFile.Session.Init
+
+	if cfg.StatementTimeout, err = parseTimeout(cfgFile.Session.StatementTimeout); err != nil {
+		return cfg, errors.Wrap(err, "invalid session statement_timeout")
+	}
+
+	if cfg.MigrationTimeout, err = parseTimeout(cfgFile.Session.MigrationTimeout); err != nil {
+		return cfg, errors.Wrap(err, "invalid session migration_timeout")
 	}
+	cfg.Analyzer = cfgFile.Analyzer.Enabled
+	cfg.RequireAllowDestructive = cfgFile.Analyzer.RequireAllowDestructive
+	cfg.DisabledRules = cfgFile.Analyzer.DisabledRules
 
 	return cfg, nil
 }
 
-func createMySQLMigrator(cfg Config) (*tern.Migrator, tern.CloserFunc, error) {
+func createMySQLMigrator(cfg Config, extra ...tern.OptionFunc) (*tern.Migrator, tern.CloserFunc, error) {
 	db, err := sqlx.Open("mysql", strings.TrimPrefix(cfg.DatabaseUrl, "mysql://"))
 	if err != nil {
 		return nil, nil, err
 	}
 
+	logOpt, err := logOption(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the logger goes first, sources are created with the logger of the migrator
 	var opts []tern.OptionFunc
 	opts = append(
 		opts,
-		tern.UseMySQL(db.DB),
-		tern.UseLocalFolderSource(cfg.MigrationsFolder),
-		tern.UseColorLogger(log.New(os.Stdout, "", 0), true, true),
+		logOpt,
+		tern.UseMySQL(
+			db.DB,
+			tern.WithMySQLSessionInit(cfg.SessionInit...),
+			tern.WithMySQLStatementTimeout(cfg.StatementTimeout),
+			tern.WithMySQLMigrationTimeout(cfg.MigrationTimeout),
+		),
+		sourceOption(cfg),
+		tern.UseEnvironment(cfg.Environment),
 	)
 
+	if cfg.SeedsFolder != "" {
+		opts = append(opts, tern.UseSeedsFolder(cfg.SeedsFolder, tern.WithVersionFormat(cfg.VersionFormat)))
+	}
+
+	if cfg.Analyzer {
+		opts = append(opts, analyzerOption(cfg))
+	}
+
+	opts = append(opts, extra...)
+
 	return tern.NewMigrator(opts...)
 }
 
-func createMigrator(cfg Config) (*tern.Migrator, tern.CloserFunc, error) {
+// logOption - structured logger writing to stdout in the format, color by default,
+// the level defaults to sql so that every executed statement is printed
+func logOption(cfg Config) (tern.OptionFunc, error) {
+	level := tern.LogLevelSQL
+	if cfg.LogLevel != "" {
+		l, err := tern.ParseLogLevel(cfg.LogLevel)
+		if err != nil {
+			return nil, err
+		}
+
+		level = l
+	}
+
+	switch cfg.LogFormat {
+	case "", "color":
+		return tern.UseLogSink(tern.PrinterLogSink(log.New(os.Stdout, "", 0), true), level), nil
+	case "text":
+		return tern.UseLogSink(tern.TextLogSink(os.Stdout), level), nil
+	case "json":
+		return tern.UseLogSink(tern.JSONLogSink(os.Stdout), level), nil
+	}
+
+	return nil, errors.Wrapf(ErrInvalidLogFormat, "[%s]", cfg.LogFormat)
+}
+
+// parseTimeout - empty timeout means no limit
+func parseTimeout(timeout string) (time.Duration, error) {
+	if timeout == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(timeout)
+	if err != nil {
+		return 0, err
+	}
+
+	if d < 0 {
+		return 0, errors.Errorf("timeout [%s] must not be negative", timeout)
+	}
+
+	return d, nil
+}
+
+func sourceOption(cfg Config) tern.OptionFunc {
+	if cfg.ArchivePath != "" {
+		return tern.UseArchiveSource(cfg.ArchivePath, cfg.ArchiveFolder, sourceConfigurators(cfg)...)
+	}
+
+	if cfg.SourceRef != "" {
+		return tern.UseGitSource(cfg.MigrationsFolder, cfg.SourceRef, sourceConfigurators(cfg)...)
+	}
+
+	return tern.UseLocalFolderSource(cfg.MigrationsFolder, sourceConfigurators(cfg)...)
+}
+
+func analyzerOption(cfg Config) tern.OptionFunc {
+	configurators := []tern.AnalyzerConfigurator{tern.WithDisabledRules(cfg.DisabledRules...)}
+	if cfg.RequireAllowDestructive {
+		configurators = append(configurators, tern.WithDestructiveGuard())
+	}
+
+	return tern.UseSQLAnalyzer(configurators...)
+}
+
+func sourceConfigurators(cfg Config) []tern.SourceConfigurator {
+	var configurators []tern.SourceConfigurator
+	if cfg.Recursive {
+		configurators = append(configurators, tern.WithRecursion())
+	}
+
+	if cfg.CreatePath != "" {
+		configurators = append(configurators, tern.WithCreatePath(cfg.CreatePath))
+	}
+
+	if cfg.SingleFile {
+		configurators = append(configurators, tern.WithSingleFile())
+	}
+
+	return configurators
+}
+
+func createMigrator(cfg Config, extra ...tern.OptionFunc) (*tern.Migrator, tern.CloserFunc, error) {
 	factoryMap := make(map[string]migratorFactory)
 	factoryMap["mysql"] = createMySQLMigrator
 
-	var driver string
-	if strings.HasPrefix(cfg.DatabaseUrl, "mysql") {
-		driver = "mysql"
-	} else if strings.HasPrefix(cfg.DatabaseUrl, "sqlite") {
-		driver = "sqlite"
-	} else {
-		return nil, nil, errors.Errorf("unknown database driver [%s]", cfg.DatabaseUrl)
+	driver, err := driverFromURL(cfg.DatabaseUrl)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return createMigratorFrom(driver, factoryMap, cfg, extra...)
+}
+
+func driverFromURL(databaseURL string) (string, error) {
+	if strings.HasPrefix(databaseURL, "mysql") {
+		return "mysql", nil
+	} else if strings.HasPrefix(databaseURL, "sqlite") {
+		return "sqlite", nil
+	}
+
+	return "", errors.Errorf("unknown database driver [%s]", databaseURL)
+}
+
+// nameColumnLength - length of the migration name column in the migrations table of the driver
+func nameColumnLength(driver string) int {
+	switch driver {
+	case "mysql":
+		return sqlgateway.MysqlNameColumnLength
+	case "sqlite":
+		return sqlgateway.SqliteNameColumnLength
 	}
 
-	return createMigratorFrom(driver, factoryMap, cfg)
+	return 0
 }
 
 func createMigratorFrom(
 	driver string,
 	factoryMap migratorFactoryMap,
 	cfg Config,
+	extra ...tern.OptionFunc,
 ) (*tern.Migrator, tern.CloserFunc, error) {
 	factory, ok := factoryMap[driver]
 	if !ok {
 		return nil, nil, errors.Errorf("could not find factory for driver [%s]", driver)
 	}
 
-	return factory(cfg)
+	return factory(cfg, extra...)
 }
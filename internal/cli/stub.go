@@ -1,9 +1,13 @@
 package cli
 
 const configFileStub = `version: "1"
+environment: local
 
 migrations:
   local_folder: "./migrations"
   database_url: "mysql://username:password@(127.0.0.1:3306)/your_db_name?parseTime=true"
   version_format: datetime
+
+seeds:
+  local_folder: "./seeds"
 `
@@ -2,32 +2,99 @@ package database
 
 import (
 	"context"
+	"github.com/denismitr/tern/v2/event"
 	"github.com/denismitr/tern/v2/internal/logger"
+	"github.com/denismitr/tern/v2/metrics"
 	"github.com/denismitr/tern/v2/migration"
+	"github.com/denismitr/tern/v2/trace"
 	"github.com/pkg/errors"
+	"time"
 )
 
 var ErrNoChangesRequired = errors.New("no changes to the database required")
 var ErrMigrationVersionNotSpecified = errors.New("migration version not specified")
+var ErrIrreversibleMigration = errors.New("migration is irreversible")
+var ErrDirtyMigration = errors.New("migration is dirty")
+var ErrLockModeNotSupported = errors.New("migration lock mode is not supported by the database")
 
 var MigratedAtColumn = "migrated_at"
 
+// TernVersion - version of tern recorded in the history table
+const TernVersion = "2.0.0"
+
 const (
 	DefaultMigrationsTable = "migrations"
-
-	OperationRollback = "rollback"
-	OperationMigrate  = "migrate"
-	OperationRefresh  = "refresh"
+	DefaultSeedsTable      = "seeds"
+	DefaultHistoryTable    = "tern_history"
+
+	OperationRollback   = "rollback"
+	OperationMigrate    = "migrate"
+	OperationRefresh    = "refresh"
+	OperationConvert    = "convert"
+	OperationReset      = "reset"
+	OperationForceClean = "force-clean"
 )
 
 type CommonOptions struct {
-	MigrationsTable   string
-	MigratedAtColumn  string
+	MigrationsTable  string
+	MigratedAtColumn string
+	// HistoryTable - append-only table of every migrate and rollback step
+	// of all the migrations tables, tern_history by default
+	HistoryTable string
+	// SessionInit - statements executed on the connection right after it is established,
+	// e.g. SET SESSION lock_wait_timeout=5 or PRAGMA foreign_keys = ON
+	SessionInit []string
+	// StatementTimeout - max duration of every statement of SQL migrations, zero means no limit
+	StatementTimeout time.Duration
+	// MigrationTimeout - max duration of the migrations without their own timeout directive
+	MigrationTimeout time.Duration
+}
+
+// HistoryEntry - migrate or rollback step of a migration kept in the history table
+type HistoryEntry struct {
+	ID          int64
+	Operation   string
+	Table       string
+	Key         string
+	Version     string
+	Origin      string
+	StartedAt   time.Time
+	FinishedAt  time.Time
+	Duration    time.Duration
+	Success     bool
+	Error       string
+	Host        string
+	OSUser      string
+	TernVersion string
 }
 
 type Plan struct {
-	Steps int
+	Steps    int
 	Versions []migration.Version
+
+	// Check is called with the migrations scheduled to be migrated before any of them runs,
+	// an error returned from it cancels the operation
+	Check func(scheduled migration.Migrations) error
+
+	// AllowIrreversible lets irreversible migrations be rolled back, only their versions are removed
+	AllowIrreversible bool
+}
+
+// CheckScheduled - runs the plan check if there is one
+func (p Plan) CheckScheduled(scheduled migration.Migrations) error {
+	if p.Check == nil {
+		return nil
+	}
+
+	return p.Check(scheduled)
+}
+
+func (p Plan) checkReversible(m *migration.Migration) error {
+	if m.Irreversible && !p.AllowIrreversible {
+		return errors.Wrapf(ErrIrreversibleMigration, "%s cannot be rolled back", m.Key)
+	}
+
+	return nil
 }
 
 type versionController interface {
@@ -36,29 +103,50 @@ type versionController interface {
 	ShowTables(ctx context.Context) ([]string, error)
 	DropMigrationsTable(ctx context.Context) error
 	CreateMigrationsTable(ctx context.Context) error
+	// RenameVersions - replaces the migrated versions, renames map old version values to new ones
+	RenameVersions(ctx context.Context, renames map[string]string) error
+	// ForceClean - clears the dirty flag of the migration version left by a failed migration or rollback
+	ForceClean(ctx context.Context, version string) error
+	// ReadHistory - latest entries of the history table, newest first, zero limit reads all of them
+	ReadHistory(ctx context.Context, limit int) ([]HistoryEntry, error)
 }
 
 type Gateway interface {
 	SetLogger(logger.Logger)
+	// SetEventHandler - handler receiving the progress events of the operations
+	SetEventHandler(event.Handler)
+	// SetMetricsCollector - collector receiving the metrics of the operations
+	SetMetricsCollector(metrics.Collector)
 	Migrate(ctx context.Context, migrations migration.Migrations, p Plan) (migration.Migrations, error)
 	Rollback(ctx context.Context, migrations migration.Migrations, p Plan) (migration.Migrations, error)
 	Refresh(ctx context.Context, migrations migration.Migrations, plan Plan) (migration.Migrations, migration.Migrations, error)
-	Connect() error
+	// Reset - rolls back all migrated migrations and migrates all of the migrations again
+	Reset(ctx context.Context, migrations migration.Migrations, plan Plan) (migration.Migrations, migration.Migrations, error)
+	// DropAllTables - drops every view and table of the database with foreign key checks disabled
+	DropAllTables(ctx context.Context) ([]string, error)
+	// ForTable - gateway using the same connection to track versions in another table
+	ForTable(table string) Gateway
+	// Connect - connects to the database once, ctx is the parent of the connect span
+	Connect(ctx context.Context) error
+	// SetTracer - tracer starting the spans of the operations, statements and connection attempts
+	SetTracer(trace.Tracer)
 
 	versionController
 }
 
 type ConnCloser func() error
 
+// ScheduleForRollback - migrated migrations to be rolled back, newest first,
+// fails at the first irreversible migration unless the plan allows it
 func ScheduleForRollback(
 	migrations migration.Migrations,
 	migratedVersions []migration.Version,
 	p Plan,
-) migration.Migrations {
+) (migration.Migrations, error) {
 	var scheduled migration.Migrations
 
 	for i := len(migrations) - 1; i >= 0; i-- {
-		if len(p.Versions) > 0 && ! migration.InVersions(migrations[i].Version, p.Versions) {
+		if len(p.Versions) > 0 && !migration.InVersions(migrations[i].Version, p.Versions) {
 			continue
 		}
 
@@ -67,11 +155,15 @@ func ScheduleForRollback(
 				break
 			}
 
+			if err := p.checkReversible(migrations[i]); err != nil {
+				return nil, err
+			}
+
 			scheduled = append(scheduled, migrations[i])
 		}
 	}
 
-	return scheduled
+	return scheduled, nil
 }
 
 func ScheduleForMigration(
@@ -96,14 +188,16 @@ func ScheduleForMigration(
 	return scheduled
 }
 
+// ScheduleForRefresh - migrated migrations to be rolled back and migrated again, newest first,
+// fails at the first irreversible migration unless the plan allows it
 func ScheduleForRefresh(
 	migrations migration.Migrations,
 	migratedVersions []migration.Version,
 	p Plan,
-) migration.Migrations {
+) (migration.Migrations, error) {
 	var scheduled migration.Migrations
 	for i := len(migrations) - 1; i >= 0; i-- {
-		if len(p.Versions) > 0 && ! migration.InVersions(migrations[i].Version, p.Versions) {
+		if len(p.Versions) > 0 && !migration.InVersions(migrations[i].Version, p.Versions) {
 			continue
 		}
 
@@ -112,8 +206,12 @@ func ScheduleForRefresh(
 				break
 			}
 
+			if err := p.checkReversible(migrations[i]); err != nil {
+				return nil, err
+			}
+
 			scheduled = append(scheduled, migrations[i])
 		}
 	}
-	return scheduled
-}
\ No newline at end of file
+	return scheduled, nil
+}
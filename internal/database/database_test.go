@@ -4,6 +4,7 @@ import (
 	"github.com/denismitr/tern/v2/migration"
 	_ "github.com/go-sql-driver/mysql"
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"testing"
@@ -55,25 +56,28 @@ func TestSchedule(t *testing.T) {
 	require.NoError(t, err)
 
 	t.Run("it will schedule only 1 migration for rollback if steps are limited to one", func(t *testing.T) {
-		scheduled := ScheduleForRollback(migration.Migrations{m1, m2, m3}, []migration.Version{v1, v2, v3}, Plan{Steps: 1})
+		scheduled, err := ScheduleForRollback(migration.Migrations{m1, m2, m3}, []migration.Version{v1, v2, v3}, Plan{Steps: 1})
+		require.NoError(t, err)
 		require.Len(t, scheduled, 1)
 		assert.Equal(t, v3.Value, scheduled[0].Version.Value)
 		assert.Equal(t, "Create baz table", scheduled[0].Name)
 	})
 
 	t.Run("it will schedule 1 specific migration for rollback if steps are limited to one and versions in plan", func(t *testing.T) {
-		scheduled := ScheduleForRollback(
+		scheduled, err := ScheduleForRollback(
 			migration.Migrations{m1, m2, m3},
 			[]migration.Version{v1, v2, v3},
 			Plan{Steps: 1, Versions: []migration.Version{v2}},
 		)
+		require.NoError(t, err)
 		require.Len(t, scheduled, 1)
 		assert.Equal(t, v2.Value, scheduled[0].Version.Value)
 		assert.Equal(t, "Create bar table", scheduled[0].Name)
 	})
 
 	t.Run("it will schedule all migrations for rollback if specific plan not specified", func(t *testing.T) {
-		scheduled := ScheduleForRollback(migration.Migrations{m1, m2, m3}, []migration.Version{v1, v2, v3}, Plan{})
+		scheduled, err := ScheduleForRollback(migration.Migrations{m1, m2, m3}, []migration.Version{v1, v2, v3}, Plan{})
+		require.NoError(t, err)
 		require.Len(t, scheduled, 3)
 
 		assert.Equal(t, v3.Value, scheduled[0].Version.Value)
@@ -151,26 +155,68 @@ func TestSchedule(t *testing.T) {
 		assert.Equal(t, "Create foo table", scheduled[0].Name)
 	})
 
+	t.Run("rollback stops at the first irreversible migration", func(t *testing.T) {
+		irreversible := *m2
+		irreversible.Irreversible = true
+
+		scheduled, err := ScheduleForRollback(
+			migration.Migrations{m1, &irreversible, m3},
+			[]migration.Version{v1, v2, v3},
+			Plan{},
+		)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrIrreversibleMigration))
+		assert.Contains(t, err.Error(), irreversible.Key)
+		assert.Nil(t, scheduled)
+
+		scheduled, err = ScheduleForRollback(
+			migration.Migrations{m1, &irreversible, m3},
+			[]migration.Version{v1, v2, v3},
+			Plan{Steps: 1},
+		)
+		require.NoError(t, err)
+		require.Len(t, scheduled, 1)
+
+		scheduled, err = ScheduleForRefresh(
+			migration.Migrations{m1, &irreversible, m3},
+			[]migration.Version{v1, v2, v3},
+			Plan{},
+		)
+		assert.True(t, errors.Is(err, ErrIrreversibleMigration))
+		assert.Nil(t, scheduled)
+
+		scheduled, err = ScheduleForRollback(
+			migration.Migrations{m1, &irreversible, m3},
+			[]migration.Version{v1, v2, v3},
+			Plan{AllowIrreversible: true},
+		)
+		require.NoError(t, err)
+		require.Len(t, scheduled, 3)
+	})
+
 	t.Run("it will schedule only 1 migration for refresh if steps are limited to one", func(t *testing.T) {
-		scheduled := ScheduleForRefresh(migration.Migrations{m1, m2, m3}, []migration.Version{v1, v2, v3}, Plan{Steps: 1})
+		scheduled, err := ScheduleForRefresh(migration.Migrations{m1, m2, m3}, []migration.Version{v1, v2, v3}, Plan{Steps: 1})
+		require.NoError(t, err)
 		require.Len(t, scheduled, 1)
 		assert.Equal(t, v3.Value, scheduled[0].Version.Value)
 		assert.Equal(t, "Create baz table", scheduled[0].Name)
 	})
 
 	t.Run("it will schedule 1 specific migration for refresh if steps are limited to one and versions in plan", func(t *testing.T) {
-		scheduled := ScheduleForRefresh(
+		scheduled, err := ScheduleForRefresh(
 			migration.Migrations{m1, m2, m3},
 			[]migration.Version{v1, v2, v3},
 			Plan{Steps: 1, Versions: []migration.Version{v2}},
 		)
+		require.NoError(t, err)
 		require.Len(t, scheduled, 1)
 		assert.Equal(t, v2.Value, scheduled[0].Version.Value)
 		assert.Equal(t, "Create bar table", scheduled[0].Name)
 	})
 
 	t.Run("it will schedule all migrations for refresh if specific plan not specified", func(t *testing.T) {
-		scheduled := ScheduleForRefresh(migration.Migrations{m1, m2, m3}, []migration.Version{v1, v2, v3}, Plan{})
+		scheduled, err := ScheduleForRefresh(migration.Migrations{m1, m2, m3}, []migration.Version{v1, v2, v3}, Plan{})
+		require.NoError(t, err)
 		require.Len(t, scheduled, 3)
 
 		assert.Equal(t, v3.Value, scheduled[0].Version.Value)
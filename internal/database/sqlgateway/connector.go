@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"github.com/denismitr/tern/v2/internal/retry"
+	"github.com/denismitr/tern/v2/trace"
 	"github.com/pkg/errors"
 	"time"
 )
@@ -36,8 +37,9 @@ type SQLConnector interface {
 
 type RetryingConnector struct {
 	options *ConnectOptions
-	db *sql.DB
-	conn *sql.Conn
+	db      *sql.DB
+	conn    *sql.Conn
+	tracer  trace.Tracer
 }
 
 func (c RetryingConnector) Timeout() time.Duration {
@@ -45,7 +47,12 @@ func (c RetryingConnector) Timeout() time.Duration {
 }
 
 func MakeRetryingConnector(db *sql.DB, options *ConnectOptions) *RetryingConnector {
-	return &RetryingConnector{db: db, options: options}
+	return &RetryingConnector{db: db, options: options, tracer: trace.NoopTracer{}}
+}
+
+// SetTracer - every connection attempt is traced as a child of the connect span
+func (c *RetryingConnector) SetTracer(t trace.Tracer) {
+	c.tracer = t
 }
 
 func (c *RetryingConnector) Connect(ctx context.Context) (*sql.Conn, error) {
@@ -53,16 +60,26 @@ func (c *RetryingConnector) Connect(ctx context.Context) (*sql.Conn, error) {
 		return c.conn, nil
 	}
 
+	tracer := c.tracer
+	if tracer == nil {
+		tracer = trace.NoopTracer{}
+	}
+
 	result, err := retry.Incremental(ctx, 2*time.Second, c.options.MaxAttempts, func(attempt int) (interface{}, error) {
+		ctx, span := tracer.Start(ctx, trace.SpanConnectTry, trace.Attr("attempt", attempt))
+
 		conn, err := c.db.Conn(ctx)
 		if err != nil {
+			span.End(err)
 			return nil, retry.Error(errors.Wrap(err, "could not establish DB connection"), attempt)
 		}
 
 		if err := conn.PingContext(ctx); err != nil {
+			span.End(err)
 			return nil, errors.Wrap(err, "db ping failed")
 		}
 
+		span.End(nil)
 		return conn, nil
 	})
 
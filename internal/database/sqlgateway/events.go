@@ -0,0 +1,67 @@
+package sqlgateway
+
+import (
+	"github.com/denismitr/tern/v2/event"
+	"github.com/denismitr/tern/v2/internal/database"
+	"github.com/denismitr/tern/v2/migration"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// SetEventHandler - handler receiving the progress events of the operations, nil disables them
+func (g *SQLGateway) SetEventHandler(h event.Handler) {
+	g.events = h
+}
+
+func (g *SQLGateway) emit(e event.Event) {
+	if g.events == nil {
+		return
+	}
+
+	e.Time = time.Now()
+	e.Table = g.schema.tableName()
+	g.events(e)
+}
+
+func (g *SQLGateway) emitPlan(operation string, migrate, rollback migration.Migrations) {
+	g.emit(event.Event{
+		Kind:      event.PlanComputed,
+		Operation: operation,
+		Migrate:   migrate.Keys(),
+		Rollback:  rollback.Keys(),
+	})
+}
+
+func (g *SQLGateway) emitStep(kind event.Kind, operation string, m *migration.Migration, d time.Duration, err error) {
+	g.emit(event.Event{
+		Kind:      kind,
+		Operation: operation,
+		Key:       m.Key,
+		Version:   m.Version.Value,
+		Duration:  d,
+		Err:       err,
+	})
+}
+
+func (g *SQLGateway) emitStatement(operation string, m *migration.Migration, statement string, startedAt time.Time, err error) {
+	g.emit(event.Event{
+		Kind:      event.StatementExecuted,
+		Operation: operation,
+		Key:       m.Key,
+		Version:   m.Version.Value,
+		Statement: statement,
+		Duration:  time.Since(startedAt),
+		Err:       err,
+	})
+}
+
+// emitDone - operation that had nothing to do is finished rather than failed
+func (g *SQLGateway) emitDone(operation string, startedAt time.Time, err error) {
+	e := event.Event{Kind: event.OperationFinished, Operation: operation, Duration: time.Since(startedAt)}
+	if err != nil && !errors.Is(err, database.ErrNoChangesRequired) {
+		e.Kind = event.OperationFailed
+		e.Err = err
+	}
+
+	g.emit(e)
+}
@@ -0,0 +1,183 @@
+package sqlgateway
+
+import (
+	"context"
+	"database/sql"
+	"github.com/denismitr/tern/v2/event"
+	"github.com/denismitr/tern/v2/internal/database"
+	"github.com/denismitr/tern/v2/internal/logger"
+	"github.com/denismitr/tern/v2/migration"
+	"github.com/denismitr/tern/v2/trace"
+	"github.com/pkg/errors"
+	"os"
+	"os/user"
+	"time"
+)
+
+// historyFlushTimeout - history is written after the operation, even if its context is done
+const historyFlushTimeout = 5 * time.Second
+
+// runStep - runs the migrate or rollback step of the migration with the executor
+// of the session and buffers the history entry of the step in the session
+func (g *SQLGateway) runStep(ctx context.Context, s *session, operation string, m *migration.Migration) (err error) {
+	ctx, span := g.tracer.Start(
+		ctx,
+		trace.SpanMigration,
+		trace.Attr("operation", operation),
+		trace.Attr("table", g.schema.tableName()),
+		trace.Attr("key", m.Key),
+		trace.Attr("version", m.Version.Value),
+		trace.Attr("no_transaction", m.NoTransaction),
+	)
+
+	defer func() {
+		span.End(err)
+	}()
+
+	ex, err := s.executor(ctx, m)
+	if err != nil {
+		return err
+	}
+
+	lg := g.lg.With(logger.F("operation", operation), logger.F("key", m.Key), logger.F("version", m.Version.Value))
+	lg.Log(logger.LevelDebug, "running")
+	g.emitStep(event.MigrationStarted, operation, m, 0, nil)
+
+	startedAt := time.Now()
+	done := "migrated"
+	if operation == database.OperationRollback {
+		done = "rolled back"
+		err = g.rollbackOne(ctx, ex, m)
+	} else {
+		err = g.migrateOne(ctx, ex, m)
+	}
+
+	entry := g.historyEntry(operation, m, startedAt, err)
+	s.history = append(s.history, entry)
+	g.observeMigration(operation, m.Key, entry.Duration, err)
+	g.emitStep(event.MigrationFinished, operation, m, entry.Duration, err)
+
+	if err != nil {
+		lg.Log(logger.LevelError, "failed", logger.F("duration", entry.Duration), logger.F("error", err))
+	} else {
+		lg.Log(logger.LevelInfo, done, logger.F("duration", entry.Duration))
+	}
+
+	return err
+}
+
+func (g *SQLGateway) historyEntry(operation string, m *migration.Migration, startedAt time.Time, err error) database.HistoryEntry {
+	finishedAt := time.Now()
+	e := database.HistoryEntry{
+		Operation:   operation,
+		Table:       g.schema.tableName(),
+		Key:         m.Key,
+		Version:     m.Version.Value,
+		Origin:      m.Origin,
+		StartedAt:   startedAt.UTC(),
+		FinishedAt:  finishedAt.UTC(),
+		Duration:    finishedAt.Sub(startedAt),
+		Success:     err == nil,
+		Host:        hostname(),
+		OSUser:      osUser(),
+		TernVersion: database.TernVersion,
+	}
+
+	if err != nil {
+		e.Error = err.Error()
+	}
+
+	return e
+}
+
+// flushHistory - appends the buffered entries to the history table once the transaction
+// of the operation is committed or rolled back, so that failed steps are kept too,
+// a failure to write the history does not fail the operation and is only logged
+func (g *SQLGateway) flushHistory(s *session) {
+	if s == nil || len(s.history) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), historyFlushTimeout)
+	defer cancel()
+
+	for _, e := range s.history {
+		q, args := g.schema.insertHistoryQuery(e)
+		if _, err := g.conn.ExecContext(ctx, q, args...); err != nil {
+			g.lg.Error(errors.Wrapf(err, "could not write history of [%s] %s", e.Operation, e.Key))
+			break
+		}
+	}
+
+	s.history = nil
+}
+
+// CreateHistoryTable - creates the history table if it does not exist
+func (g *SQLGateway) CreateHistoryTable(ctx context.Context) error {
+	if _, err := g.conn.ExecContext(ctx, g.schema.initHistoryQuery()); err != nil {
+		return errors.Wrap(err, "could not create history table")
+	}
+
+	return nil
+}
+
+// ReadHistory - latest entries of the history table, newest first
+func (g *SQLGateway) ReadHistory(ctx context.Context, limit int) ([]database.HistoryEntry, error) {
+	if err := g.CreateHistoryTable(ctx); err != nil {
+		return nil, err
+	}
+
+	rows, err := g.conn.QueryContext(ctx, g.schema.readHistoryQuery(limit))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read history")
+	}
+
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			g.lg.Error(closeErr)
+		}
+	}()
+
+	var result []database.HistoryEntry
+	for rows.Next() {
+		var e database.HistoryEntry
+		var origin, errText, host, osUser sql.NullString
+		var durationMs int64
+
+		if err := rows.Scan(
+			&e.ID, &e.Operation, &e.Table, &e.Key, &e.Version, &origin, &e.StartedAt, &e.FinishedAt,
+			&durationMs, &e.Success, &errText, &host, &osUser, &e.TernVersion,
+		); err != nil {
+			return result, errors.Wrap(err, "could not scan history entry")
+		}
+
+		e.Origin, e.Error, e.Host, e.OSUser = origin.String, errText.String, host.String, osUser.String
+		e.Duration = time.Duration(durationMs) * time.Millisecond
+		result = append(result, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return result, errors.Wrap(err, "history rows error")
+	}
+
+	return result, nil
+}
+
+func hostname() string {
+	host, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+
+	return host
+}
+
+// osUser - name of the user running the operation, USER variable is used
+// when the user cannot be looked up, e.g. in scratch containers
+func osUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+
+	return os.Getenv("USER")
+}
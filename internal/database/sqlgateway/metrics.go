@@ -0,0 +1,58 @@
+package sqlgateway
+
+import (
+	"context"
+	"github.com/denismitr/tern/v2/internal/database"
+	"github.com/denismitr/tern/v2/metrics"
+	"github.com/denismitr/tern/v2/migration"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// SetMetricsCollector - collector receiving the metrics of the operations, nil disables them
+func (g *SQLGateway) SetMetricsCollector(c metrics.Collector) {
+	g.metrics = c
+}
+
+func (g *SQLGateway) observeMigration(operation, key string, d time.Duration, err error) {
+	if g.metrics != nil {
+		g.metrics.ObserveMigration(g.schema.tableName(), operation, key, d, err)
+	}
+}
+
+func (g *SQLGateway) observeLockWait(operation string, d time.Duration) {
+	if g.metrics != nil {
+		g.metrics.ObserveLockWait(operation, d)
+	}
+}
+
+// observeOperation - operation that had nothing to do counts as a successful one,
+// migrations not migrated yet are counted once the lock is released
+func (g *SQLGateway) observeOperation(operation string, migrations migration.Migrations, err error) {
+	if g.metrics == nil {
+		return
+	}
+
+	if errors.Is(err, database.ErrNoChangesRequired) {
+		err = nil
+	}
+
+	g.metrics.ObserveOperation(g.schema.tableName(), operation, err)
+
+	if migrations == nil {
+		return
+	}
+
+	// the context of the operation may be done already, e.g. after a timeout
+	ctx, cancel := context.WithTimeout(context.Background(), historyFlushTimeout)
+	defer cancel()
+
+	versions, readErr := g.ReadVersions(ctx)
+	if readErr != nil {
+		g.lg.Error(errors.Wrap(readErr, "could not count pending migrations"))
+		return
+	}
+
+	pending := database.ScheduleForMigration(migrations, versions, database.Plan{})
+	g.metrics.SetPending(g.schema.tableName(), len(pending))
+}
@@ -2,38 +2,91 @@ package sqlgateway
 
 import (
 	"fmt"
+	"github.com/denismitr/tern/v2/internal/analyzer"
+	"github.com/denismitr/tern/v2/internal/database"
 	"github.com/denismitr/tern/v2/migration"
+	"regexp"
+	"strings"
+)
+
+// MysqlNameColumnLength - max length of a migration name stored in the migrations table
+const MysqlNameColumnLength = 120
+
+var (
+	// mysqlAlterTable - ALTER TABLE statement normalized by the analyzer
+	mysqlAlterTable = regexp.MustCompile(`^ALTER (?:ONLINE |IGNORE )?TABLE `)
+	mysqlLockClause = regexp.MustCompile(`\bLOCK ?=`)
 )
 
 type mysqlSchemaV1 struct {
-	migrationsTable, migratedAtColumn, charset string
+	migrationsTable, migratedAtColumn, historyTableName, charset string
 }
 
 var _ schema = (*mysqlSchemaV1)(nil)
 
-func newMysqlSchemaV1(migrationsTable, migratedAtColumn, charset string) *mysqlSchemaV1 {
-	return &mysqlSchemaV1{migrationsTable: migrationsTable, migratedAtColumn: migratedAtColumn, charset: charset}
+func newMysqlSchemaV1(migrationsTable, migratedAtColumn, historyTable, charset string) *mysqlSchemaV1 {
+	return &mysqlSchemaV1{
+		migrationsTable:  migrationsTable,
+		migratedAtColumn: migratedAtColumn,
+		historyTableName: historyTable,
+		charset:          charset,
+	}
 }
 
 func (s mysqlSchemaV1) initQuery() string {
 	const createSQL = `
 		CREATE TABLE IF NOT EXISTS %s (
 			version VARCHAR(14) PRIMARY KEY,
-			name VARCHAR(120),
-			%s TIMESTAMP default CURRENT_TIMESTAMP
+			name VARCHAR(%d),
+			%s TIMESTAMP default CURRENT_TIMESTAMP,
+			origin VARCHAR(255) NULL,
+			dirty TINYINT(1) NOT NULL DEFAULT 0
 		) ENGINE=InnoDB CHARACTER SET=%s
 	`
 
-	return fmt.Sprintf(createSQL, s.migrationsTable, s.migratedAtColumn, s.charset)
+	return fmt.Sprintf(createSQL, s.migrationsTable, MysqlNameColumnLength, s.migratedAtColumn, s.charset)
 }
 
 func (s mysqlSchemaV1) insertQuery(m *migration.Migration) (string, []interface{}) {
 	const insertSQL = `
-		INSERT INTO %s (version, name) VALUES (?, ?);	
+		INSERT INTO %s (version, name, origin) VALUES (?, ?, ?);	
 	`
 	v := m.Version.Value
 	n := m.Name
-	return fmt.Sprintf(insertSQL, s.migrationsTable), []interface{}{v, n}
+	return fmt.Sprintf(insertSQL, s.migrationsTable), []interface{}{v, n, nullable(m.Origin)}
+}
+
+func (s mysqlSchemaV1) insertDirtyQuery(m *migration.Migration) (string, []interface{}) {
+	const insertSQL = "INSERT INTO %s (version, name, origin, dirty) VALUES (?, ?, ?, 1);"
+	return fmt.Sprintf(insertSQL, s.migrationsTable), []interface{}{m.Version.Value, m.Name, nullable(m.Origin)}
+}
+
+func (s mysqlSchemaV1) setDirtyQuery(version string, dirty bool) (string, []interface{}) {
+	const updateSQL = "UPDATE %s SET `dirty` = ? WHERE `version` = ?;"
+	return fmt.Sprintf(updateSQL, s.migrationsTable), []interface{}{dirty, version}
+}
+
+func (s mysqlSchemaV1) dirtyVersionsQuery() string {
+	const dirtySQL = "SELECT `version`, `name` FROM %s WHERE `dirty` = 1 ORDER BY `version`;"
+	return fmt.Sprintf(dirtySQL, s.migrationsTable)
+}
+
+func (s mysqlSchemaV1) hasColumnQuery(column string) (string, []interface{}) {
+	const hasColumnSQL = `
+		SELECT COUNT(*) FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?;
+	`
+	return hasColumnSQL, []interface{}{s.migrationsTable, column}
+}
+
+// addColumnQuery - columns added to migrations tables created by the older versions
+func (s mysqlSchemaV1) addColumnQuery(column string) string {
+	definitions := map[string]string{
+		"origin": "VARCHAR(255) NULL",
+		"dirty":  "TINYINT(1) NOT NULL DEFAULT 0",
+	}
+
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN `%s` %s;", s.migrationsTable, column, definitions[column])
 }
 
 func (s mysqlSchemaV1) readVersionsQuery(f readVersionsFilter) string {
@@ -58,6 +111,11 @@ func (s mysqlSchemaV1) removeQuery(m *migration.Migration) (string, []interface{
 	return fmt.Sprintf(removeSQL, s.migrationsTable), []interface{}{v}
 }
 
+func (s mysqlSchemaV1) updateVersionQuery(from, to string) (string, []interface{}) {
+	const updateSQL = "UPDATE %s SET `version` = ? WHERE `version` = ?;"
+	return fmt.Sprintf(updateSQL, s.migrationsTable), []interface{}{to, from}
+}
+
 func (s mysqlSchemaV1) dropQuery() string {
 	const dropSQL = `
 		DROP TABLE IF EXISTS %s;
@@ -65,6 +123,95 @@ func (s mysqlSchemaV1) dropQuery() string {
 	return fmt.Sprintf(dropSQL, s.migrationsTable)
 }
 
+func (s mysqlSchemaV1) dropTableQuery(table string) string {
+	return fmt.Sprintf("DROP TABLE IF EXISTS `%s`;", strings.ReplaceAll(table, "`", "``"))
+}
+
+func (s mysqlSchemaV1) dropViewQuery(view string) string {
+	return fmt.Sprintf("DROP VIEW IF EXISTS `%s`;", strings.ReplaceAll(view, "`", "``"))
+}
+
+func (s mysqlSchemaV1) foreignKeyChecksQuery() string {
+	return "SELECT @@FOREIGN_KEY_CHECKS;"
+}
+
+func (s mysqlSchemaV1) setForeignKeyChecksQuery(enabled bool) string {
+	if enabled {
+		return "SET FOREIGN_KEY_CHECKS = 1;"
+	}
+
+	return "SET FOREIGN_KEY_CHECKS = 0;"
+}
+
+func (s mysqlSchemaV1) isSystemTable(string) bool {
+	return false
+}
+
+// lockModeStatements - splits the script into statements and adds the LOCK clause
+// of the online DDL to the ALTER TABLE ones, that do not have it already, comments
+// are ignored and the clause goes before the trailing ones, so it is not commented out
+func (s mysqlSchemaV1) lockModeStatements(script, mode string) ([]string, error) {
+	statements := analyzer.Statements(script)
+	for i, stmt := range statements {
+		code := analyzer.Normalize(stmt)
+		if mysqlAlterTable.MatchString(code) && !mysqlLockClause.MatchString(code) {
+			end := analyzer.CodeEnd(stmt)
+			statements[i] = stmt[:end] + ", LOCK=" + strings.ToUpper(mode) + stmt[end:]
+		}
+	}
+
+	return statements, nil
+}
+
+func (s mysqlSchemaV1) withTable(table string) schema {
+	return newMysqlSchemaV1(table, s.migratedAtColumn, s.historyTableName, s.charset)
+}
+
+func (s mysqlSchemaV1) tableName() string {
+	return s.migrationsTable
+}
+
+func (s mysqlSchemaV1) historyTable() string {
+	return s.historyTableName
+}
+
+func (s mysqlSchemaV1) initHistoryQuery() string {
+	const createSQL = `
+		CREATE TABLE IF NOT EXISTS %s (
+			id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+			operation VARCHAR(32) NOT NULL,
+			table_name VARCHAR(255) NOT NULL,
+			migration_key VARCHAR(%d) NOT NULL,
+			version VARCHAR(14) NOT NULL,
+			origin VARCHAR(255) NULL,
+			started_at DATETIME(6) NOT NULL,
+			finished_at DATETIME(6) NOT NULL,
+			duration_ms BIGINT NOT NULL,
+			success BOOLEAN NOT NULL,
+			error TEXT NULL,
+			host VARCHAR(255) NULL,
+			os_user VARCHAR(255) NULL,
+			tern_version VARCHAR(32) NULL,
+			INDEX (migration_key)
+		) ENGINE=InnoDB CHARACTER SET=%s
+	`
+
+	return fmt.Sprintf(createSQL, s.historyTableName, MysqlNameColumnLength+migration.MaxVersionLen+1, s.charset)
+}
+
+func (s mysqlSchemaV1) insertHistoryQuery(e database.HistoryEntry) (string, []interface{}) {
+	return insertHistoryQuery(s.historyTableName, e)
+}
+
+func (s mysqlSchemaV1) readHistoryQuery(limit int) string {
+	return readHistoryQuery(s.historyTableName, limit)
+}
+
+// showTablesQuery - base tables only, SHOW TABLES lists views as well, they cannot be dropped with DROP TABLE
 func (s mysqlSchemaV1) showTablesQuery() string {
-	return "SHOW TABLES;"
+	return "SHOW FULL TABLES WHERE Table_type = 'BASE TABLE';"
+}
+
+func (s mysqlSchemaV1) showViewsQuery() string {
+	return "SHOW FULL TABLES WHERE Table_type = 'VIEW';"
 }
@@ -1,11 +1,13 @@
 package sqlgateway
 
 import (
+	"fmt"
+	"github.com/denismitr/tern/v2/internal/database"
 	"github.com/denismitr/tern/v2/migration"
 )
 
 const (
-	ASC = "ASC"
+	ASC  = "ASC"
 	DESC = "DESC"
 )
 
@@ -18,9 +20,65 @@ type schema interface {
 	initQuery() string
 	insertQuery(m *migration.Migration) (string, []interface{})
 	removeQuery(m *migration.Migration) (string, []interface{})
+	updateVersionQuery(from, to string) (string, []interface{})
 	dropQuery() string
 	showTablesQuery() string
+	showViewsQuery() string
 	readVersionsQuery(f readVersionsFilter) string
+	insertDirtyQuery(m *migration.Migration) (string, []interface{})
+	setDirtyQuery(version string, dirty bool) (string, []interface{})
+	dirtyVersionsQuery() string
+	hasColumnQuery(column string) (string, []interface{})
+	addColumnQuery(column string) string
+	dropTableQuery(table string) string
+	dropViewQuery(view string) string
+	foreignKeyChecksQuery() string
+	setForeignKeyChecksQuery(enabled bool) string
+	isSystemTable(table string) bool
+	withTable(table string) schema
+	tableName() string
+	historyTable() string
+	initHistoryQuery() string
+	insertHistoryQuery(e database.HistoryEntry) (string, []interface{})
+	readHistoryQuery(limit int) string
+	// lockModeStatements - statements of the script with the lock mode applied to them
+	lockModeStatements(script, mode string) ([]string, error)
 }
 
+// nullable - empty strings are stored as NULL
+func nullable(s string) interface{} {
+	if s == "" {
+		return nil
+	}
 
+	return s
+}
+
+// insertHistoryQuery - history table has the same columns in all of the dialects
+func insertHistoryQuery(table string, e database.HistoryEntry) (string, []interface{}) {
+	const insertSQL = `
+		INSERT INTO %s (
+			operation, table_name, migration_key, version, origin, started_at, finished_at,
+			duration_ms, success, error, host, os_user, tern_version
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);
+	`
+
+	return fmt.Sprintf(insertSQL, table), []interface{}{
+		e.Operation, e.Table, e.Key, e.Version, nullable(e.Origin), e.StartedAt, e.FinishedAt,
+		e.Duration.Milliseconds(), e.Success, nullable(e.Error), nullable(e.Host), nullable(e.OSUser), e.TernVersion,
+	}
+}
+
+func readHistoryQuery(table string, limit int) string {
+	readSQL := `
+		SELECT id, operation, table_name, migration_key, version, origin, started_at, finished_at,
+			duration_ms, success, error, host, os_user, tern_version
+		FROM %s ORDER BY id DESC
+	`
+
+	if limit > 0 {
+		readSQL += fmt.Sprintf(" LIMIT %d", limit)
+	}
+
+	return fmt.Sprintf(readSQL, table)
+}
@@ -0,0 +1,79 @@
+package sqlgateway
+
+import (
+	"context"
+	"database/sql"
+	"github.com/denismitr/tern/v2/internal/database"
+	"github.com/denismitr/tern/v2/migration"
+	"github.com/pkg/errors"
+)
+
+// session - keeps the transaction of the currently running operation and lets
+// migrations marked with no-transaction directive run outside of it. The running
+// transaction is committed before such a migration and a new one is started
+// for the migrations that follow.
+type session struct {
+	conn    *sql.Conn
+	tx      *sql.Tx
+	history []database.HistoryEntry
+}
+
+func newSession(ctx context.Context, conn *sql.Conn) (*session, error) {
+	s := &session{conn: conn}
+	if err := s.begin(ctx); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *session) begin(ctx context.Context) error {
+	tx, err := s.conn.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return err
+	}
+
+	s.tx = tx
+	return nil
+}
+
+// executor - returns executor the migration should be run with
+func (s *session) executor(ctx context.Context, m *migration.Migration) (ctxExecutor, error) {
+	if !m.NoTransaction {
+		if s.tx == nil {
+			if err := s.begin(ctx); err != nil {
+				return nil, errors.Wrapf(err, "could not start transaction after migration")
+			}
+		}
+
+		return s.tx, nil
+	}
+
+	if err := s.commit(); err != nil {
+		return nil, errors.Wrapf(err, "could not commit transaction before migration [%s]", m.Key)
+	}
+
+	return s.conn, nil
+}
+
+func (s *session) commit() error {
+	if s.tx == nil {
+		return nil
+	}
+
+	tx := s.tx
+	s.tx = nil
+
+	return tx.Commit()
+}
+
+func (s *session) rollback() error {
+	if s.tx == nil {
+		return nil
+	}
+
+	tx := s.tx
+	s.tx = nil
+
+	return tx.Rollback()
+}
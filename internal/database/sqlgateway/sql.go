@@ -3,10 +3,15 @@ package sqlgateway
 import (
 	"context"
 	"database/sql"
+	"github.com/denismitr/tern/v2/event"
 	"github.com/denismitr/tern/v2/internal/database"
 	"github.com/denismitr/tern/v2/internal/logger"
+	"github.com/denismitr/tern/v2/metrics"
 	"github.com/denismitr/tern/v2/migration"
+	"github.com/denismitr/tern/v2/trace"
 	"github.com/pkg/errors"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -26,6 +31,13 @@ type SQLGateway struct {
 	conn      *sql.Conn
 	connector SQLConnector
 	schema    schema
+	events    event.Handler
+	metrics   metrics.Collector
+	tracer    trace.Tracer
+
+	sessionInit      []string
+	statementTimeout time.Duration
+	migrationTimeout time.Duration
 }
 
 var _ database.Gateway = (*SQLGateway)(nil)
@@ -36,6 +48,7 @@ func NewMySQLGateway(connector SQLConnector, options *MySQLOptions) (*SQLGateway
 	gateway := SQLGateway{}
 	gateway.connector = connector
 	gateway.locker = newMySQLLocker(options.LockKey, options.LockFor, options.NoLock)
+	gateway.tracer = trace.NoopTracer{}
 
 	if options.MigrationsTable == "" {
 		options.MigrationsTable = database.DefaultMigrationsTable
@@ -45,7 +58,14 @@ func NewMySQLGateway(connector SQLConnector, options *MySQLOptions) (*SQLGateway
 		options.MigratedAtColumn = database.MigratedAtColumn
 	}
 
-	gateway.schema = newMysqlSchemaV1(options.MigrationsTable, options.MigratedAtColumn, "utf8")
+	if options.HistoryTable == "" {
+		options.HistoryTable = database.DefaultHistoryTable
+	}
+
+	gateway.schema = newMysqlSchemaV1(options.MigrationsTable, options.MigratedAtColumn, options.HistoryTable, "utf8")
+	gateway.sessionInit = options.SessionInit
+	gateway.statementTimeout = options.StatementTimeout
+	gateway.migrationTimeout = options.MigrationTimeout
 
 	return &gateway, connector.Close
 }
@@ -55,6 +75,7 @@ func NewSqliteGateway(connector SQLConnector, options *SqliteOptions) (*SQLGatew
 	gateway := SQLGateway{}
 	gateway.connector = connector
 	gateway.locker = &nullLocker{}
+	gateway.tracer = trace.NoopTracer{}
 
 	if options.MigrationsTable == "" {
 		options.MigrationsTable = database.DefaultMigrationsTable
@@ -64,21 +85,53 @@ func NewSqliteGateway(connector SQLConnector, options *SqliteOptions) (*SQLGatew
 		options.MigratedAtColumn = database.MigratedAtColumn
 	}
 
-	gateway.schema = newSqliteSchemaV1(options.MigrationsTable, options.MigratedAtColumn)
+	if options.HistoryTable == "" {
+		options.HistoryTable = database.DefaultHistoryTable
+	}
+
+	gateway.schema = newSqliteSchemaV1(options.MigrationsTable, options.MigratedAtColumn, options.HistoryTable)
+	gateway.sessionInit = options.SessionInit
+	gateway.statementTimeout = options.StatementTimeout
+	gateway.migrationTimeout = options.MigrationTimeout
 
 	return &gateway, connector.Close
 }
 
+// ForTable - gateway sharing the connection and the lock of this one,
+// that keeps applied versions in another table, e.g. seeds
+func (g *SQLGateway) ForTable(table string) database.Gateway {
+	cp := *g
+	cp.schema = g.schema.withTable(table)
+	return &cp
+}
+
 func (g *SQLGateway) SetLogger(lg logger.Logger) {
 	g.lg = lg
 }
 
-func (g *SQLGateway) Connect() error {
+// SetTracer - tracer starting the spans, it is passed on to the connector if it traces connection attempts
+func (g *SQLGateway) SetTracer(t trace.Tracer) {
+	if t == nil {
+		t = trace.NoopTracer{}
+	}
+
+	g.tracer = t
+	if tc, ok := g.connector.(interface{ SetTracer(trace.Tracer) }); ok {
+		tc.SetTracer(t)
+	}
+}
+
+func (g *SQLGateway) Connect(ctx context.Context) (err error) {
 	if g.conn != nil {
 		return nil
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), g.connector.Timeout())
+	ctx, span := g.tracer.Start(ctx, trace.SpanConnect)
+	defer func() {
+		span.End(err)
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, g.connector.Timeout())
 	defer cancel()
 
 	conn, err := g.connector.Connect(ctx)
@@ -86,27 +139,48 @@ func (g *SQLGateway) Connect() error {
 		return err
 	}
 
+	if err := g.initSession(ctx, conn); err != nil {
+		return err
+	}
+
 	g.conn = conn
 	return nil
 }
 
+// initSession - executes the session init statements on the connection tern runs everything with
+func (g *SQLGateway) initSession(ctx context.Context, conn *sql.Conn) error {
+	for _, statement := range g.sessionInit {
+		g.lg.SQL(statement)
+
+		if _, err := conn.ExecContext(ctx, statement); err != nil {
+			return errors.Wrapf(err, "could not execute session init statement [%s]", statement)
+		}
+	}
+
+	return nil
+}
+
 func (g *SQLGateway) Migrate(ctx context.Context, migrations migration.Migrations, p database.Plan) (migration.Migrations, error) {
 	var migrated migration.Migrations
 
-	if err := g.execUnderLock(ctx, database.OperationMigrate, func(tx *sql.Tx, migratedVersions []migration.Version) error {
+	if err := g.execUnderLock(ctx, database.OperationMigrate, migrations, func(ctx context.Context, s *session, migratedVersions []migration.Version) error {
 		scheduled := database.ScheduleForMigration(migrations, migratedVersions, p)
 
 		if len(scheduled) == 0 {
 			return database.ErrNoChangesRequired
 		}
 
+		g.emitPlan(database.OperationMigrate, scheduled, nil)
+
+		if err := p.CheckScheduled(scheduled); err != nil {
+			return err
+		}
+
 		for i := range scheduled {
-			if err := g.migrateOne(ctx, tx, scheduled[i]); err != nil {
+			if err := g.runStep(ctx, s, database.OperationMigrate, scheduled[i]); err != nil {
 				return err
 			}
 
-			g.lg.Successf("migrated: %s", scheduled[i].Key)
-
 			migrated = append(migrated, scheduled[i])
 		}
 
@@ -121,21 +195,23 @@ func (g *SQLGateway) Migrate(ctx context.Context, migrations migration.Migration
 func (g *SQLGateway) Rollback(ctx context.Context, migrations migration.Migrations, p database.Plan) (migration.Migrations, error) {
 	var rolledBack migration.Migrations
 
-	if err := g.execUnderLock(ctx, database.OperationRollback, func(tx *sql.Tx, migratedVersions []migration.Version) error {
-		scheduled := database.ScheduleForRollback(migrations, migratedVersions, p)
+	if err := g.execUnderLock(ctx, database.OperationRollback, migrations, func(ctx context.Context, s *session, migratedVersions []migration.Version) error {
+		scheduled, err := database.ScheduleForRollback(migrations, migratedVersions, p)
+		if err != nil {
+			return err
+		}
 
 		if len(scheduled) == 0 {
 			return database.ErrNoChangesRequired
 		}
 
+		g.emitPlan(database.OperationRollback, nil, scheduled)
+
 		for i := range scheduled {
-			g.lg.Debugf("rolling back: %s", scheduled[i].Key)
-			if err := g.rollbackOne(ctx, tx, scheduled[i]); err != nil {
+			if err := g.runStep(ctx, s, database.OperationRollback, scheduled[i]); err != nil {
 				return err
 			}
 
-			g.lg.Successf("rolled back: %s", scheduled[i].Key)
-
 			rolledBack = append(rolledBack, scheduled[i])
 		}
 
@@ -155,31 +231,41 @@ func (g *SQLGateway) Refresh(
 	var rolledBack migration.Migrations
 	var migrated migration.Migrations
 
-	if err := g.execUnderLock(ctx, database.OperationRefresh, func(tx *sql.Tx, migratedVersions []migration.Version) error {
-		scheduled := database.ScheduleForRefresh(migrations, migratedVersions, p)
+	if err := g.execUnderLock(ctx, database.OperationRefresh, migrations, func(ctx context.Context, s *session, migratedVersions []migration.Version) error {
+		scheduled, err := database.ScheduleForRefresh(migrations, migratedVersions, p)
+		if err != nil {
+			return err
+		}
 
 		if len(scheduled) == 0 {
 			return database.ErrNoChangesRequired
 		}
 
+		reversed := make(migration.Migrations, 0, len(scheduled))
+		for i := len(scheduled) - 1; i >= 0; i-- {
+			reversed = append(reversed, scheduled[i])
+		}
+
+		g.emitPlan(database.OperationRefresh, reversed, scheduled)
+
+		if err := p.CheckScheduled(scheduled); err != nil {
+			return err
+		}
+
 		for i := range scheduled {
-			g.lg.Debugf("rolling back: %s", scheduled[i].Key)
-			if err := g.rollbackOne(ctx, tx, scheduled[i]); err != nil {
+			if err := g.runStep(ctx, s, database.OperationRollback, scheduled[i]); err != nil {
 				return err
 			}
 
 			rolledBack = append(rolledBack, scheduled[i])
-			g.lg.Successf("rolled back: %s", scheduled[i].Key)
 		}
 
 		for i := len(scheduled) - 1; i >= 0; i-- {
-			g.lg.Debugf("migrating: %s", scheduled[i].Key)
-			if err := g.migrateOne(ctx, tx, scheduled[i]); err != nil {
+			if err := g.runStep(ctx, s, database.OperationMigrate, scheduled[i]); err != nil {
 				return err
 			}
 
 			migrated = append(migrated, scheduled[i])
-			g.lg.Debugf("migrated: %s", scheduled[i].Key)
 		}
 
 		return nil
@@ -190,6 +276,141 @@ func (g *SQLGateway) Refresh(
 	return rolledBack, migrated, nil
 }
 
+// Reset - rolls back every migrated migration, newest first, and then migrates
+// all of the migrations including the ones that were not migrated before
+func (g *SQLGateway) Reset(
+	ctx context.Context,
+	migrations migration.Migrations,
+	p database.Plan,
+) (migration.Migrations, migration.Migrations, error) {
+	var rolledBack migration.Migrations
+	var migrated migration.Migrations
+
+	if err := g.execUnderLock(ctx, database.OperationReset, migrations, func(ctx context.Context, s *session, migratedVersions []migration.Version) error {
+		scheduledForRollback, err := database.ScheduleForRollback(
+			migrations,
+			migratedVersions,
+			database.Plan{AllowIrreversible: p.AllowIrreversible},
+		)
+		if err != nil {
+			return err
+		}
+
+		scheduledForMigration := database.ScheduleForMigration(migrations, nil, database.Plan{})
+
+		if len(scheduledForRollback) == 0 && len(scheduledForMigration) == 0 {
+			return database.ErrNoChangesRequired
+		}
+
+		g.emitPlan(database.OperationReset, scheduledForMigration, scheduledForRollback)
+
+		if err := p.CheckScheduled(scheduledForMigration); err != nil {
+			return err
+		}
+
+		for i := range scheduledForRollback {
+			if err := g.runStep(ctx, s, database.OperationRollback, scheduledForRollback[i]); err != nil {
+				return err
+			}
+
+			rolledBack = append(rolledBack, scheduledForRollback[i])
+		}
+
+		for i := range scheduledForMigration {
+			if err := g.runStep(ctx, s, database.OperationMigrate, scheduledForMigration[i]); err != nil {
+				return err
+			}
+
+			migrated = append(migrated, scheduledForMigration[i])
+		}
+
+		return nil
+	}); err != nil {
+		return rolledBack, migrated, err
+	}
+
+	return rolledBack, migrated, nil
+}
+
+// DropAllTables - drops all tables listed by ShowTables including the migrations table
+// but not the history table, foreign key checks are disabled while the tables are dropped and restored afterwards
+func (g *SQLGateway) DropAllTables(ctx context.Context) ([]string, error) {
+	if err := g.locker.lock(ctx, g.conn); err != nil {
+		return nil, errors.Wrap(err, "database lock failed")
+	}
+
+	dropped, err := g.dropAllTables(ctx)
+	if unlockErr := g.locker.unlock(ctx, g.conn); unlockErr != nil {
+		if err != nil {
+			return dropped, errors.Wrapf(err, unlockErr.Error())
+		}
+
+		return dropped, unlockErr
+	}
+
+	return dropped, err
+}
+
+func (g *SQLGateway) dropAllTables(ctx context.Context) (dropped []string, err error) {
+	tables, err := g.ShowTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var checks int
+	if err := g.conn.QueryRowContext(ctx, g.schema.foreignKeyChecksQuery()).Scan(&checks); err != nil {
+		return nil, errors.Wrap(err, "could not read foreign key checks setting")
+	}
+
+	if _, err := g.conn.ExecContext(ctx, g.schema.setForeignKeyChecksQuery(false)); err != nil {
+		return nil, errors.Wrap(err, "could not disable foreign key checks")
+	}
+
+	defer func() {
+		if checks == 0 {
+			return
+		}
+
+		if _, restoreErr := g.conn.ExecContext(ctx, g.schema.setForeignKeyChecksQuery(true)); restoreErr != nil && err == nil {
+			err = errors.Wrap(restoreErr, "could not enable foreign key checks")
+		}
+	}()
+
+	// views are dropped first, since DROP TABLE does not drop them
+	views, err := g.queryNames(ctx, g.schema.showViewsQuery())
+	if err != nil {
+		return nil, errors.Wrap(err, "could not list all views")
+	}
+
+	for _, view := range views {
+		q := g.schema.dropViewQuery(view)
+		g.lg.SQL(q)
+
+		if _, err := g.conn.ExecContext(ctx, q); err != nil {
+			return dropped, errors.Wrapf(err, "could not drop view [%s]", view)
+		}
+
+		dropped = append(dropped, view)
+	}
+
+	for _, table := range tables {
+		if g.schema.isSystemTable(table) {
+			continue
+		}
+
+		q := g.schema.dropTableQuery(table)
+		g.lg.SQL(q)
+
+		if _, err := g.conn.ExecContext(ctx, q); err != nil {
+			return dropped, errors.Wrapf(err, "could not drop table [%s]", table)
+		}
+
+		dropped = append(dropped, table)
+	}
+
+	return dropped, nil
+}
+
 func (g *SQLGateway) ReadVersions(ctx context.Context) ([]migration.Version, error) {
 	tx, err := g.conn.BeginTx(ctx, &sql.TxOptions{})
 	if err != nil {
@@ -209,11 +430,37 @@ func (g *SQLGateway) ReadVersions(ctx context.Context) ([]migration.Version, err
 	return result, nil
 }
 
+// CreateMigrationsTable - creates the migrations table and the history table if they do not exist
 func (g *SQLGateway) CreateMigrationsTable(ctx context.Context) error {
 	if _, err := g.conn.ExecContext(ctx, g.schema.initQuery()); err != nil {
 		return err
 	}
 
+	if err := g.ensureColumns(ctx, "origin", "dirty"); err != nil {
+		return err
+	}
+
+	return g.CreateHistoryTable(ctx)
+}
+
+// ensureColumns - adds the columns missing in migrations tables created by the older versions
+func (g *SQLGateway) ensureColumns(ctx context.Context, columns ...string) error {
+	for _, column := range columns {
+		var count int
+		q, args := g.schema.hasColumnQuery(column)
+		if err := g.conn.QueryRowContext(ctx, q, args...).Scan(&count); err != nil {
+			return errors.Wrapf(err, "could not check migrations table %s column", column)
+		}
+
+		if count > 0 {
+			continue
+		}
+
+		if _, err := g.conn.ExecContext(ctx, g.schema.addColumnQuery(column)); err != nil {
+			return errors.Wrapf(err, "could not add %s column to migrations table", column)
+		}
+	}
+
 	return nil
 }
 
@@ -252,14 +499,53 @@ func (g *SQLGateway) WriteVersions(ctx context.Context, migrations migration.Mig
 	return nil
 }
 
+// RenameVersions - updates the migrated versions in a single transaction under lock,
+// versions that were not migrated are skipped
+func (g *SQLGateway) RenameVersions(ctx context.Context, renames map[string]string) error {
+	return g.execUnderLock(ctx, database.OperationConvert, nil, func(ctx context.Context, s *session, migratedVersions []migration.Version) error {
+		for _, v := range migratedVersions {
+			to, ok := renames[v.Value]
+			if !ok {
+				continue
+			}
+
+			q, args := g.schema.updateVersionQuery(v.Value, to)
+			g.lg.SQL(q, args...)
+
+			if _, err := s.tx.ExecContext(ctx, q, args...); err != nil {
+				return errors.Wrapf(err, "could not rename migration version [%s] to [%s]", v.Value, to)
+			}
+		}
+
+		return nil
+	})
+}
+
 func (g *SQLGateway) ShowTables(ctx context.Context) ([]string, error) {
-	rows, err := g.conn.QueryContext(ctx, g.schema.showTablesQuery())
+	tables, err := g.queryNames(ctx, g.schema.showTablesQuery())
 	if err != nil {
 		return nil, errors.Wrap(err, "could not list all tables")
 	}
 
-	if rowsErr := rows.Err(); rowsErr != nil {
-		return nil, errors.Wrap(err, "show tables rows error")
+	var result []string
+	for _, table := range tables {
+		// the history table is append-only and survives dropping of all tables
+		if table == g.schema.historyTable() {
+			continue
+		}
+
+		result = append(result, table)
+	}
+
+	return result, nil
+}
+
+// queryNames - reads the first column of every row, e.g. names of the tables,
+// the other columns, like the table type of MySQL SHOW FULL TABLES, are skipped
+func (g *SQLGateway) queryNames(ctx context.Context, query string) ([]string, error) {
+	rows, err := g.conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
 	}
 
 	defer func() {
@@ -268,42 +554,77 @@ func (g *SQLGateway) ShowTables(ctx context.Context) ([]string, error) {
 		}
 	}()
 
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
 	var result []string
 	for rows.Next() {
-		if errRows := rows.Err(); errRows != nil {
-			if errRows != sql.ErrNoRows {
-				return nil, errors.Wrap(errRows, "migration table iteration error")
-			} else {
-				break
-			}
+		var name string
+		dest := make([]interface{}, len(columns))
+		dest[0] = &name
+		for i := 1; i < len(dest); i++ {
+			dest[i] = new(sql.RawBytes)
 		}
 
-		var table string
-		if errScan := rows.Scan(&table); errScan != nil {
-			return result, errScan
+		if err := rows.Scan(dest...); err != nil {
+			return result, err
 		}
 
-		result = append(result, table)
+		result = append(result, name)
 	}
 
-	return result, err
+	return result, rows.Err()
 }
 
-func (g *SQLGateway) execUnderLock(ctx context.Context, operation string, f func(*sql.Tx, []migration.Version) error) error {
-	if err := g.locker.lock(ctx, g.conn); err != nil {
+// execUnderLock - runs the operation in a session under lock, migrations of the operation
+// are used to count the pending ones, they are nil for operations that do not migrate
+func (g *SQLGateway) execUnderLock(
+	ctx context.Context,
+	operation string,
+	migrations migration.Migrations,
+	f func(context.Context, *session, []migration.Version) error,
+) (err error) {
+	startedAt := time.Now()
+	ctx, span := g.tracer.Start(
+		ctx,
+		trace.SpanOperation,
+		trace.Attr("operation", operation),
+		trace.Attr("table", g.schema.tableName()),
+	)
+
+	defer func() {
+		g.observeOperation(operation, migrations, err)
+		g.emitDone(operation, startedAt, err)
+
+		if errors.Is(err, database.ErrNoChangesRequired) {
+			span.End(nil)
+		} else {
+			span.End(err)
+		}
+	}()
+
+	g.emit(event.Event{Kind: event.LockWaiting, Operation: operation})
+	if err := g.lock(ctx); err != nil {
 		return errors.Wrap(err, "database lock failed")
 	}
 
-	handleError := func(err error, tx *sql.Tx) error {
+	g.observeLockWait(operation, time.Since(startedAt))
+	g.emit(event.Event{Kind: event.LockAcquired, Operation: operation, Duration: time.Since(startedAt)})
+
+	handleError := func(err error, s *session) error {
 		var rollbackErr error
 		var unlockErr error
 		var result = err
 
-		if tx != nil {
-			rollbackErr = tx.Rollback()
+		if s != nil {
+			rollbackErr = s.rollback()
 			if rollbackErr != nil {
 				result = errors.Wrapf(result, rollbackErr.Error())
 			}
+
+			g.flushHistory(s)
 		}
 
 		unlockErr = g.locker.unlock(ctx, g.conn)
@@ -318,62 +639,130 @@ func (g *SQLGateway) execUnderLock(ctx context.Context, operation string, f func
 		return handleError(err, nil)
 	}
 
-	tx, err := g.conn.BeginTx(ctx, &sql.TxOptions{})
+	s, err := newSession(ctx, g.conn)
 	if err != nil {
 		return handleError(errors.Wrapf(err, "could not start transaction to execute [%s] operation", operation), nil)
 	}
 
-	availableVersions, err := g.readVersionsUnderTx(tx, readVersionsFilter{Sort: ASC})
+	availableVersions, err := g.schedule(ctx, s, operation)
 	if err != nil {
-		if errors.Is(err, database.ErrNoChangesRequired) {
-			return handleError(err, tx)
+		if errors.Is(err, database.ErrNoChangesRequired) || errors.Is(err, database.ErrDirtyMigration) {
+			return handleError(err, s)
 		}
 
-		return handleError(errors.Wrapf(err, "operation [%s] failed", operation), tx)
+		return handleError(errors.Wrapf(err, "operation [%s] failed", operation), s)
 	}
 
-	if err := f(tx, availableVersions); err != nil {
+	if err := f(ctx, s, availableVersions); err != nil {
 		if errors.Is(err, database.ErrNoChangesRequired) {
-			return handleError(err, tx)
+			return handleError(err, s)
 		}
 
-		return handleError(errors.Wrapf(err, "operation [%s] failed", operation), tx)
+		return handleError(errors.Wrapf(err, "operation [%s] failed", operation), s)
 	}
 
-	if err := tx.Commit(); err != nil {
-		return handleError(errors.Wrapf(err, "could not commit [%s] operation, rolled back", operation), tx)
+	if err := s.commit(); err != nil {
+		return handleError(errors.Wrapf(err, "could not commit [%s] operation, rolled back", operation), s)
 	}
 
+	g.flushHistory(s)
+
 	return g.locker.unlock(ctx, g.conn)
 }
 
+// lock - acquires the database lock in a span showing how long the operation waited for it
+func (g *SQLGateway) lock(ctx context.Context) error {
+	ctx, span := g.tracer.Start(ctx, trace.SpanLock)
+	err := g.locker.lock(ctx, g.conn)
+	span.End(err)
+
+	return err
+}
+
+// schedule - reads the migrated versions and makes sure none of them is dirty,
+// unless the dirty flag is being cleared
+func (g *SQLGateway) schedule(ctx context.Context, s *session, operation string) (versions []migration.Version, err error) {
+	ctx, span := g.tracer.Start(ctx, trace.SpanSchedule)
+	defer func() {
+		span.SetAttributes(trace.Attr("migrated", len(versions)))
+		span.End(err)
+	}()
+
+	versions, err = g.readVersionsUnderTx(s.tx, readVersionsFilter{Sort: ASC})
+	if err != nil {
+		return nil, err
+	}
+
+	if operation != database.OperationForceClean {
+		if err := g.checkNotDirty(ctx, s.tx); err != nil {
+			return nil, err
+		}
+	}
+
+	return versions, nil
+}
+
+// execStatement - executes the statement of the migration in its own span,
+// limited by the statement timeout if one is set
+func (g *SQLGateway) execStatement(ctx context.Context, ex ctxExecutor, operation string, m *migration.Migration, statement string) error {
+	g.lg.SQL(statement)
+
+	ctx, span := g.tracer.Start(ctx, trace.SpanStatement, trace.Attr("db.statement", statement))
+	ctx, cancel := g.statementContext(ctx)
+	defer cancel()
+
+	startedAt := time.Now()
+	_, err := ex.ExecContext(ctx, statement)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = errors.Wrapf(err, "statement timed out after %s", time.Since(startedAt).Round(time.Millisecond))
+	}
+
+	span.End(err)
+
+	g.emitStatement(operation, m, statement, startedAt, err)
+
+	return err
+}
+
 func (g *SQLGateway) migrateOne(ctx context.Context, ex ctxExecutor, m *migration.Migration) error {
 	if m.Version.Value == "" {
 		return database.ErrMigrationVersionNotSpecified
 	}
 
-	insertQuery, args := g.schema.insertQuery(m)
+	scripts, err := g.lockModeScripts(m, m.Migrate)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := g.migrationContext(ctx, m)
+	defer cancel()
+
+	dirtyQuery, args := g.schema.insertDirtyQuery(m)
+	g.lg.SQL(dirtyQuery, args...)
 
-	if len(m.Migrate) > 0 {
-		for _, script := range m.Migrate {
-			g.lg.SQL(script)
-			if _, err := ex.ExecContext(ctx, script); err != nil {
+	if _, err := ex.ExecContext(ctx, dirtyQuery, args...); err != nil {
+		return errors.Wrapf(err, "could not insert migration version [%s]", m.Version.Value)
+	}
+
+	if len(scripts) > 0 {
+		for _, script := range scripts {
+			if strings.TrimSpace(script) == "" {
+				continue
+			}
+
+			if err := g.execStatement(ctx, ex, database.OperationMigrate, m, script); err != nil {
 				return errors.Wrapf(err, "could not migrate script [%s], migration [%s]", script, m.Key)
 			}
 		}
 	}
 
-	g.lg.SQL(insertQuery, m.Version.Value, m.Name)
-
-	if _, err := ex.ExecContext(ctx, insertQuery, args...); err != nil {
-		return errors.Wrapf(
-			err,
-			"could not insert migration version [%s]",
-			m.Version.Value,
-		)
+	if m.MigrateFunc != nil {
+		if err := m.MigrateFunc(ctx, ex); err != nil {
+			return errors.Wrapf(err, "could not migrate Go migration [%s]", m.Key)
+		}
 	}
 
-	return nil
+	return g.setDirty(ctx, ex, m.Version.Value, false)
 }
 
 func (g *SQLGateway) rollbackOne(ctx context.Context, ex ctxExecutor, m *migration.Migration) error {
@@ -381,17 +770,42 @@ func (g *SQLGateway) rollbackOne(ctx context.Context, ex ctxExecutor, m *migrati
 		return database.ErrMigrationVersionNotSpecified
 	}
 
+	// only the version of an irreversible migration is removed, its rollback is never run
+	var scripts []string
+	if !m.Irreversible {
+		var err error
+		if scripts, err = g.lockModeScripts(m, m.Rollback); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := g.migrationContext(ctx, m)
+	defer cancel()
+
 	removeVersionQuery, args := g.schema.removeQuery(m)
 
-	if len(m.Rollback) > 0 {
-		for _, script := range m.Rollback {
-			g.lg.SQL(script)
-			if _, err := ex.ExecContext(ctx, script); err != nil {
+	if err := g.setDirty(ctx, ex, m.Version.Value, true); err != nil {
+		return err
+	}
+
+	if len(scripts) > 0 {
+		for _, script := range scripts {
+			if strings.TrimSpace(script) == "" {
+				continue
+			}
+
+			if err := g.execStatement(ctx, ex, database.OperationRollback, m, script); err != nil {
 				return errors.Wrapf(err, "could not rollback script [%s], migration [%s]", script, m.Key)
 			}
 		}
 	}
 
+	if m.RollbackFunc != nil && !m.Irreversible {
+		if err := m.RollbackFunc(ctx, ex); err != nil {
+			return errors.Wrapf(err, "could not rollback Go migration [%s]", m.Key)
+		}
+	}
+
 	g.lg.SQL(removeVersionQuery, m.Version.Value)
 
 	if _, err := ex.ExecContext(ctx, removeVersionQuery, args...); err != nil {
@@ -405,6 +819,136 @@ func (g *SQLGateway) rollbackOne(ctx context.Context, ex ctxExecutor, m *migrati
 	return nil
 }
 
+// setDirty - the version is marked dirty before the migration or the rollback runs
+// with the same executor, so the mark stays when a no-transaction migration or
+// a statement with implicit commit, e.g. MySQL DDL, fails half way through
+func (g *SQLGateway) setDirty(ctx context.Context, ex ctxExecutor, version string, dirty bool) error {
+	q, args := g.schema.setDirtyQuery(version, dirty)
+	g.lg.SQL(q, args...)
+
+	if _, err := ex.ExecContext(ctx, q, args...); err != nil {
+		return errors.Wrapf(err, "could not set dirty flag of migration version [%s]", version)
+	}
+
+	return nil
+}
+
+// checkNotDirty - fails with the keys of the dirty migrations if there are any
+func (g *SQLGateway) checkNotDirty(ctx context.Context, tx *sql.Tx) error {
+	dirty, err := g.readDirtyUnderTx(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	if len(dirty) == 0 {
+		return nil
+	}
+
+	return errors.Wrapf(
+		database.ErrDirtyMigration,
+		"%s failed half way through, fix the database and force clean the version",
+		strings.Join(dirty.Keys(), ", "),
+	)
+}
+
+func (g *SQLGateway) readDirtyUnderTx(ctx context.Context, tx *sql.Tx) (migration.Migrations, error) {
+	rows, err := tx.QueryContext(ctx, g.schema.dirtyVersionsQuery())
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read dirty migrations")
+	}
+
+	defer func() {
+		if closeErr := rows.Close(); closeErr != nil {
+			g.lg.Error(closeErr)
+		}
+	}()
+
+	var result migration.Migrations
+	for rows.Next() {
+		var version string
+		var name sql.NullString
+		if err := rows.Scan(&version, &name); err != nil {
+			return nil, errors.Wrap(err, "could not scan dirty migration")
+		}
+
+		result = append(result, &migration.Migration{
+			Key:     migration.CreateKeyFromVersionAndName(version, name.String),
+			Name:    name.String,
+			Version: migration.Version{Value: version},
+		})
+	}
+
+	return result, rows.Err()
+}
+
+// ForceClean - clears the dirty flag of the version once the database has been fixed by hand,
+// the version stays migrated, the operation is kept in the history
+func (g *SQLGateway) ForceClean(ctx context.Context, version string) error {
+	return g.execUnderLock(ctx, database.OperationForceClean, nil, func(ctx context.Context, s *session, _ []migration.Version) error {
+		dirty, err := g.readDirtyUnderTx(ctx, s.tx)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range dirty {
+			if !migration.SameVersion(m.Version, migration.Version{Value: version}) {
+				continue
+			}
+
+			startedAt := time.Now()
+			err := g.setDirty(ctx, s.tx, m.Version.Value, false)
+			s.history = append(s.history, g.historyEntry(database.OperationForceClean, m, startedAt, err))
+
+			return err
+		}
+
+		return errors.Wrapf(database.ErrNoChangesRequired, "migration version [%s] is not dirty", version)
+	})
+}
+
+// lockModeScripts - scripts of the migration with the lock directive applied to them,
+// they are returned as is when the migration has no lock mode
+func (g *SQLGateway) lockModeScripts(m *migration.Migration, scripts []string) ([]string, error) {
+	if m.LockMode == "" {
+		return scripts, nil
+	}
+
+	var result []string
+	for _, script := range scripts {
+		statements, err := g.schema.lockModeStatements(script, m.LockMode)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not apply lock mode to migration [%s]", m.Key)
+		}
+
+		result = append(result, statements...)
+	}
+
+	return result, nil
+}
+
+// migrationContext - derives a context limited by the timeout directive of the migration
+// or by the default migration timeout of the gateway if one is set
+func (g *SQLGateway) migrationContext(ctx context.Context, m *migration.Migration) (context.Context, context.CancelFunc) {
+	if m.Timeout > 0 {
+		return context.WithTimeout(ctx, m.Timeout)
+	}
+
+	if g.migrationTimeout > 0 {
+		return context.WithTimeout(ctx, g.migrationTimeout)
+	}
+
+	return context.WithCancel(ctx)
+}
+
+// statementContext - derives a context limited by the statement timeout if one is set
+func (g *SQLGateway) statementContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if g.statementTimeout > 0 {
+		return context.WithTimeout(ctx, g.statementTimeout)
+	}
+
+	return context.WithCancel(ctx)
+}
+
 func (g *SQLGateway) readVersionsUnderTx(tx *sql.Tx, f readVersionsFilter) ([]migration.Version, error) {
 	q := g.schema.readVersionsQuery(f)
 	rows, err := tx.Query(q)
@@ -443,8 +987,27 @@ func (g *SQLGateway) readVersionsUnderTx(tx *sql.Tx, f readVersionsFilter) ([]mi
 			return result, err
 		}
 
-		result = append(result, migration.Version{Value: timestamp, MigratedAt: migratedAt})
+		v := migration.Version{Value: timestamp, MigratedAt: migratedAt}
+		if parsed, err := migration.VersionFromString(timestamp); err == nil {
+			v.Format = parsed.Format
+		}
+
+		result = append(result, v)
 	}
 
+	sortVersions(result, f.Sort)
+
 	return result, nil
 }
+
+// sortVersions - orders versions with the rules of their formats, since the database
+// compares them as strings and e.g. 10 digit timestamps go after 14 digit datetimes
+func sortVersions(versions []migration.Version, order string) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		if order == DESC {
+			return migration.CompareVersions(versions[j], versions[i]) < 0
+		}
+
+		return migration.CompareVersions(versions[i], versions[j]) < 0
+	})
+}
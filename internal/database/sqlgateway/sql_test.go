@@ -2,6 +2,7 @@ package sqlgateway
 
 import (
 	"github.com/denismitr/tern/v2/internal/database"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"testing"
@@ -20,13 +21,14 @@ func TestNewSqliteGateway(t *testing.T) {
 
 		assert.Equal(t, "migrations", s.migrationsTable)
 		assert.Equal(t, "migrated_at", s.migratedAtColumn)
+		assert.Equal(t, "tern_history", s.historyTableName)
 	})
 
 	t.Run("custom options", func(t *testing.T) {
 		connector := RetryingConnector{}
 		g, closer := NewSqliteGateway(&connector, &SqliteOptions{
 			database.CommonOptions{
-				MigrationsTable: "foo",
+				MigrationsTable:  "foo",
 				MigratedAtColumn: "created_at",
 			},
 		})
@@ -39,6 +41,7 @@ func TestNewSqliteGateway(t *testing.T) {
 
 		assert.Equal(t, "foo", s.migrationsTable)
 		assert.Equal(t, "created_at", s.migratedAtColumn)
+		assert.Equal(t, "tern_history", s.historyTableName)
 	})
 }
 
@@ -55,14 +58,16 @@ func TestNewMySQLGateway(t *testing.T) {
 
 		assert.Equal(t, "migrations", s.migrationsTable)
 		assert.Equal(t, "migrated_at", s.migratedAtColumn)
+		assert.Equal(t, "tern_history", s.historyTableName)
 	})
 
 	t.Run("custom options", func(t *testing.T) {
 		connector := RetryingConnector{}
 		g, closer := NewMySQLGateway(&connector, &MySQLOptions{
 			CommonOptions: database.CommonOptions{
-				MigrationsTable: "foo",
+				MigrationsTable:  "foo",
 				MigratedAtColumn: "created_at",
+				HistoryTable:     "foo_audit",
 			},
 			LockKey: "foobar",
 			LockFor: 2,
@@ -76,5 +81,55 @@ func TestNewMySQLGateway(t *testing.T) {
 
 		assert.Equal(t, "foo", s.migrationsTable)
 		assert.Equal(t, "created_at", s.migratedAtColumn)
+		assert.Equal(t, "foo_audit", s.historyTableName)
+	})
+}
+
+func TestLockModeStatements(t *testing.T) {
+	t.Run("mysql adds the lock clause to alter table statements", func(t *testing.T) {
+		s := newMysqlSchemaV1("migrations", "migrated_at", "tern_history", "utf8")
+
+		statements, err := s.lockModeStatements(
+			"-- adds a column\nALTER TABLE foo ADD bar INT;\n"+
+				"alter online table foo ADD INDEX idx_bar (bar);\n"+
+				"ALTER TABLE foo ADD baz INT, LOCK = SHARED;\n"+
+				"INSERT INTO foo (bar) VALUES ('ALTER TABLE;');",
+			"none",
+		)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"-- adds a column\nALTER TABLE foo ADD bar INT, LOCK=NONE",
+			"alter online table foo ADD INDEX idx_bar (bar), LOCK=NONE",
+			"ALTER TABLE foo ADD baz INT, LOCK = SHARED",
+			"INSERT INTO foo (bar) VALUES ('ALTER TABLE;')",
+		}, statements)
+	})
+
+	t.Run("mysql ignores comments of alter table statements", func(t *testing.T) {
+		s := newMysqlSchemaV1("migrations", "migrated_at", "tern_history", "utf8")
+
+		statements, err := s.lockModeStatements(
+			"ALTER TABLE foo ADD COLUMN x INT -- note\n;\n"+
+				"ALTER TABLE foo ADD COLUMN y INT /* LOCK=SHARED */;\n"+
+				"ALTER TABLE foo ADD COLUMN z VARCHAR(8) DEFAULT '--' # note;",
+			"exclusive",
+		)
+
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"ALTER TABLE foo ADD COLUMN x INT, LOCK=EXCLUSIVE -- note",
+			"ALTER TABLE foo ADD COLUMN y INT, LOCK=EXCLUSIVE /* LOCK=SHARED */",
+			"ALTER TABLE foo ADD COLUMN z VARCHAR(8) DEFAULT '--', LOCK=EXCLUSIVE # note;",
+		}, statements)
+	})
+
+	t.Run("sqlite does not support lock modes", func(t *testing.T) {
+		s := newSqliteSchemaV1("migrations", "migrated_at", "tern_history")
+
+		_, err := s.lockModeStatements("ALTER TABLE foo ADD bar INT;", "none")
+
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, database.ErrLockModeNotSupported))
 	})
 }
@@ -4,28 +4,66 @@ import (
 	"fmt"
 	"github.com/denismitr/tern/v2/internal/database"
 	"github.com/denismitr/tern/v2/migration"
+	"github.com/pkg/errors"
+	"strings"
 )
 
+// SqliteNameColumnLength - max length of a migration name stored in the migrations table
+const SqliteNameColumnLength = 255
+
 type sqliteSchemaV1 struct {
-	migrationsTable, migratedAtColumn string
+	migrationsTable, migratedAtColumn, historyTableName string
 }
 
 func (s sqliteSchemaV1) initQuery() string {
 	const sqliteCreateMigrationsSchema = `
 		CREATE TABLE IF NOT EXISTS %s (
 			version VARCHAR(13) PRIMARY KEY,
-			name VARCHAR(255),
-			%s TIMESTAMP default CURRENT_TIMESTAMP
+			name VARCHAR(%d),
+			%s TIMESTAMP default CURRENT_TIMESTAMP,
+			origin VARCHAR(255),
+			dirty BOOLEAN NOT NULL DEFAULT 0
 		);	
 	`
 
-	return fmt.Sprintf(sqliteCreateMigrationsSchema, s.migrationsTable, s.migratedAtColumn)
+	return fmt.Sprintf(sqliteCreateMigrationsSchema, s.migrationsTable, SqliteNameColumnLength, s.migratedAtColumn)
 }
 
 func (s sqliteSchemaV1) insertQuery(m *migration.Migration) (string, []interface{}) {
-	const sqliteInsertVersionQuery   = "INSERT INTO %s (version, name) VALUES (?, ?);"
+	const sqliteInsertVersionQuery = "INSERT INTO %s (version, name, origin) VALUES (?, ?, ?);"
 	q := fmt.Sprintf(sqliteInsertVersionQuery, s.migrationsTable)
-	return q, []interface{}{m.Version.Value, m.Name}
+	return q, []interface{}{m.Version.Value, m.Name, nullable(m.Origin)}
+}
+
+func (s sqliteSchemaV1) insertDirtyQuery(m *migration.Migration) (string, []interface{}) {
+	const sqliteInsertDirtyVersionQuery = "INSERT INTO %s (version, name, origin, dirty) VALUES (?, ?, ?, 1);"
+	q := fmt.Sprintf(sqliteInsertDirtyVersionQuery, s.migrationsTable)
+	return q, []interface{}{m.Version.Value, m.Name, nullable(m.Origin)}
+}
+
+func (s sqliteSchemaV1) setDirtyQuery(version string, dirty bool) (string, []interface{}) {
+	const sqliteSetDirtyQuery = "UPDATE %s SET dirty = ? WHERE version = ?;"
+	return fmt.Sprintf(sqliteSetDirtyQuery, s.migrationsTable), []interface{}{dirty, version}
+}
+
+func (s sqliteSchemaV1) dirtyVersionsQuery() string {
+	const sqliteDirtyVersionsQuery = "SELECT version, name FROM %s WHERE dirty = 1 ORDER BY version;"
+	return fmt.Sprintf(sqliteDirtyVersionsQuery, s.migrationsTable)
+}
+
+func (s sqliteSchemaV1) hasColumnQuery(column string) (string, []interface{}) {
+	const sqliteHasColumnQuery = "SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?;"
+	return sqliteHasColumnQuery, []interface{}{s.migrationsTable, column}
+}
+
+// addColumnQuery - columns added to migrations tables created by the older versions
+func (s sqliteSchemaV1) addColumnQuery(column string) string {
+	definitions := map[string]string{
+		"origin": "VARCHAR(255)",
+		"dirty":  "BOOLEAN NOT NULL DEFAULT 0",
+	}
+
+	return fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", s.migrationsTable, column, definitions[column])
 }
 
 func (s sqliteSchemaV1) removeQuery(m *migration.Migration) (string, []interface{}) {
@@ -34,16 +72,101 @@ func (s sqliteSchemaV1) removeQuery(m *migration.Migration) (string, []interface
 	return q, []interface{}{m.Version.Value}
 }
 
+func (s sqliteSchemaV1) updateVersionQuery(from, to string) (string, []interface{}) {
+	const sqliteUpdateVersionQuery = "UPDATE %s SET version = ? WHERE version = ?;"
+	q := fmt.Sprintf(sqliteUpdateVersionQuery, s.migrationsTable)
+	return q, []interface{}{to, from}
+}
+
 func (s sqliteSchemaV1) dropQuery() string {
 	const sqliteDropMigrationsQuery = "DROP TABLE IF EXISTS %s;"
 	q := fmt.Sprintf(sqliteDropMigrationsQuery, s.migrationsTable)
 	return q
 }
 
+func (s sqliteSchemaV1) dropTableQuery(table string) string {
+	return fmt.Sprintf("DROP TABLE IF EXISTS \"%s\";", strings.ReplaceAll(table, `"`, `""`))
+}
+
+func (s sqliteSchemaV1) dropViewQuery(view string) string {
+	return fmt.Sprintf("DROP VIEW IF EXISTS \"%s\";", strings.ReplaceAll(view, `"`, `""`))
+}
+
+func (s sqliteSchemaV1) foreignKeyChecksQuery() string {
+	return "PRAGMA foreign_keys;"
+}
+
+// setForeignKeyChecksQuery - the pragma has no effect inside of a transaction
+func (s sqliteSchemaV1) setForeignKeyChecksQuery(enabled bool) string {
+	if enabled {
+		return "PRAGMA foreign_keys = ON;"
+	}
+
+	return "PRAGMA foreign_keys = OFF;"
+}
+
+// isSystemTable - tables prefixed with sqlite_ are internal and cannot be dropped
+func (s sqliteSchemaV1) isSystemTable(table string) bool {
+	return strings.HasPrefix(strings.ToLower(table), "sqlite_")
+}
+
+// lockModeStatements - sqlite locks the whole database for writes, so there are no lock levels to choose from
+func (s sqliteSchemaV1) lockModeStatements(_, mode string) ([]string, error) {
+	return nil, errors.Wrapf(database.ErrLockModeNotSupported, "sqlite does not support lock mode [%s]", mode)
+}
+
+func (s sqliteSchemaV1) withTable(table string) schema {
+	return newSqliteSchemaV1(table, s.migratedAtColumn, s.historyTableName)
+}
+
+func (s sqliteSchemaV1) tableName() string {
+	return s.migrationsTable
+}
+
+func (s sqliteSchemaV1) historyTable() string {
+	return s.historyTableName
+}
+
+// initHistoryQuery - integer primary key is an alias of rowid and needs no sqlite_sequence table
+func (s sqliteSchemaV1) initHistoryQuery() string {
+	const sqliteCreateHistorySchema = `
+		CREATE TABLE IF NOT EXISTS %s (
+			id INTEGER PRIMARY KEY,
+			operation VARCHAR(32) NOT NULL,
+			table_name VARCHAR(255) NOT NULL,
+			migration_key VARCHAR(%d) NOT NULL,
+			version VARCHAR(14) NOT NULL,
+			origin VARCHAR(255),
+			started_at TIMESTAMP NOT NULL,
+			finished_at TIMESTAMP NOT NULL,
+			duration_ms INTEGER NOT NULL,
+			success BOOLEAN NOT NULL,
+			error TEXT,
+			host VARCHAR(255),
+			os_user VARCHAR(255),
+			tern_version VARCHAR(32)
+		);
+	`
+
+	return fmt.Sprintf(sqliteCreateHistorySchema, s.historyTableName, SqliteNameColumnLength+migration.MaxVersionLen+1)
+}
+
+func (s sqliteSchemaV1) insertHistoryQuery(e database.HistoryEntry) (string, []interface{}) {
+	return insertHistoryQuery(s.historyTableName, e)
+}
+
+func (s sqliteSchemaV1) readHistoryQuery(limit int) string {
+	return readHistoryQuery(s.historyTableName, limit)
+}
+
 func (s sqliteSchemaV1) showTablesQuery() string {
 	return "SELECT name FROM sqlite_master WHERE type='table' ORDER BY name;"
 }
 
+func (s sqliteSchemaV1) showViewsQuery() string {
+	return "SELECT name FROM sqlite_master WHERE type='view' ORDER BY name;"
+}
+
 func (s sqliteSchemaV1) readVersionsQuery(f readVersionsFilter) string {
 	var readSQL = "SELECT `version`, `%s` FROM %s"
 
@@ -62,8 +185,12 @@ func (s sqliteSchemaV1) readVersionsQuery(f readVersionsFilter) string {
 
 var _ schema = (*sqliteSchemaV1)(nil)
 
-func newSqliteSchemaV1(migrationsTable, migratedAtColumn string) *sqliteSchemaV1 {
-	return &sqliteSchemaV1{migrationsTable: migrationsTable, migratedAtColumn: migratedAtColumn}
+func newSqliteSchemaV1(migrationsTable, migratedAtColumn, historyTable string) *sqliteSchemaV1 {
+	return &sqliteSchemaV1{
+		migrationsTable:  migrationsTable,
+		migratedAtColumn: migratedAtColumn,
+		historyTableName: historyTable,
+	}
 }
 
 type SqliteOptions struct {
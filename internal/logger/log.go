@@ -1,121 +1,176 @@
 package logger
 
 import (
-	"bytes"
 	"fmt"
-	"github.com/logrusorgru/aurora/v3"
+	"github.com/pkg/errors"
+	"strings"
+	"sync"
+	"time"
 )
 
-type Printer interface {
-	Output(calldepth int, s string) error
-}
+var ErrUnknownLevel = errors.New("unknown log level")
 
-type Logger interface {
-	Successf(format string, args ...interface{})
-	Debugf(format string, args ...interface{})
-	Error(err error)
-	SQL(query string, args ...interface{})
-}
+const (
+	// LevelSQL - executed statements and their arguments, the most verbose level
+	LevelSQL Level = iota
+	LevelDebug
+	LevelInfo
+	LevelWarn
+	LevelError
+)
 
-type ColoredLogger struct {
-	printer Printer
-	debug   bool
-	sql     bool
-}
+type (
+	// Level - severity of the log entry, entries below the logger level are dropped
+	Level int
 
-type BWLogger struct {
-	printer Printer
-	debug   bool
-	sql     bool
-}
+	// Field - key value pair attached to the log entry, e.g. migration key or duration
+	Field struct {
+		Key   string
+		Value interface{}
+	}
+
+	// Entry - single structured log record passed to a Sink
+	Entry struct {
+		Time    time.Time
+		Level   Level
+		Message string
+		Fields  []Field
+	}
 
-var _ Logger = (*ColoredLogger)(nil)
-var _ Logger = (*BWLogger)(nil)
+	// Sink - destination of the log entries, e.g. JSON lines for a log pipeline
+	Sink interface {
+		Write(e Entry) error
+	}
+
+	Printer interface {
+		Output(calldepth int, s string) error
+	}
 
-func NewColorLogger(p Printer, sql, debug bool) *ColoredLogger {
-	return &ColoredLogger{
-		printer: p,
-		debug: debug,
-		sql: sql,
+	Logger interface {
+		Successf(format string, args ...interface{})
+		Debugf(format string, args ...interface{})
+		Error(err error)
+		SQL(query string, args ...interface{})
+		// Log - writes a structured entry with the fields of the logger and the given ones
+		Log(level Level, msg string, fields ...Field)
+		// With - logger adding the fields to every entry
+		With(fields ...Field) Logger
 	}
+)
+
+// F - creates a log field
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
 }
 
-func NewBWLogger(p Printer, sql, debug bool) *ColoredLogger {
-	return &ColoredLogger{
-		printer: p,
-		debug: debug,
-		sql: sql,
+func (l Level) String() string {
+	switch l {
+	case LevelSQL:
+		return "sql"
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
 	}
+
+	return fmt.Sprintf("level(%d)", int(l))
 }
 
-func (cl *ColoredLogger) Debugf(format string, args ...interface{}) {
-	if cl.debug {
-		msg := fmt.Sprintf("\nTern debug: "+format, args...)
-		_ = cl.printer.Output(2, aurora.Yellow(msg).String())
+// ParseLevel - level by its name: sql, debug, info, warn or error
+func ParseLevel(s string) (Level, error) {
+	for l := LevelSQL; l <= LevelError; l++ {
+		if strings.EqualFold(strings.TrimSpace(s), l.String()) {
+			return l, nil
+		}
 	}
+
+	return LevelInfo, errors.Wrapf(ErrUnknownLevel, "[%s], use sql, debug, info, warn or error", s)
 }
 
-func (cl *ColoredLogger) Successf(format string, args ...interface{}) {
-	msg := fmt.Sprintf("\nTern: "+format, args...)
-	_ = cl.printer.Output(2, aurora.Green(msg).String())
+// StructuredLogger - levelled logger writing entries with key value fields to a sink
+type StructuredLogger struct {
+	mu     *sync.Mutex
+	sink   Sink
+	level  Level
+	fields []Field
+	now    func() time.Time
 }
 
-func (cl *ColoredLogger) Error(err error) {
-	msg := fmt.Sprintf("\nTern error: %s", err.Error())
-	_ = cl.printer.Output(2, aurora.Red(msg).String())
+var _ Logger = (*StructuredLogger)(nil)
+
+// New - creates a structured logger writing entries of the level and above to the sink
+func New(sink Sink, level Level) *StructuredLogger {
+	return &StructuredLogger{mu: &sync.Mutex{}, sink: sink, level: level, now: time.Now}
 }
 
-func (cl *ColoredLogger) SQL(query string, args ...interface{}) {
-	if cl.sql {
-		var buf bytes.Buffer
-		buf.WriteString("\nTern running sql: ")
-		buf.WriteString(query)
-		buf.WriteString("\nquery parameters: ")
-
-		for i := range args {
-			if i+1 < len(args) {
-				buf.WriteString(fmt.Sprintf("{%#v}, ", args[i]))
-			} else {
-				buf.WriteString(fmt.Sprintf("{%#v}", args[i]))
-			}
-		}
+// NewColorLogger - colored text logger printing with the printer, e.g. log.Logger,
+// sql enables statements and debug enables debug messages
+func NewColorLogger(p Printer, sql, debug bool) *StructuredLogger {
+	return New(NewPrinterSink(p, true), levelOf(sql, debug))
+}
+
+// NewBWLogger - plain text logger printing with the printer, e.g. log.Logger
+func NewBWLogger(p Printer, sql, debug bool) *StructuredLogger {
+	return New(NewPrinterSink(p, false), levelOf(sql, debug))
+}
 
-		_ = cl.printer.Output(2, aurora.Gray(15, buf.String()).String())
+func levelOf(sql, debug bool) Level {
+	switch {
+	case sql:
+		return LevelSQL
+	case debug:
+		return LevelDebug
 	}
+
+	return LevelInfo
 }
 
-func (bwl *BWLogger) Debugf(format string, args ...interface{}) {
-	if bwl.debug {
-		msg := fmt.Sprintf("\nTern debug: "+format, args...)
-		_ = bwl.printer.Output(2, msg)
+func (l *StructuredLogger) Log(level Level, msg string, fields ...Field) {
+	if level < l.level {
+		return
 	}
+
+	e := Entry{Time: l.now(), Level: level, Message: msg}
+	e.Fields = append(e.Fields, l.fields...)
+	e.Fields = append(e.Fields, fields...)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	_ = l.sink.Write(e)
 }
 
-func (bwl *BWLogger) Successf(format string, args ...interface{}) {
-	msg := fmt.Sprintf("\nTern: "+format, args...)
-	_ = bwl.printer.Output(2, msg)
+func (l *StructuredLogger) With(fields ...Field) Logger {
+	cp := *l
+	cp.fields = append(append([]Field(nil), l.fields...), fields...)
+	return &cp
 }
 
-func (bwl *BWLogger) Error(err error) {
-	msg := fmt.Sprintf("\nTern error: %s", err.Error())
-	_ = bwl.printer.Output(2, msg)
+func (l *StructuredLogger) Successf(format string, args ...interface{}) {
+	l.Log(LevelInfo, fmt.Sprintf(format, args...))
 }
 
-func (bwl *BWLogger) SQL(query string, args ...interface{}) {
-	if bwl.sql {
-		var buf bytes.Buffer
-		buf.WriteString("\nTern running sql: ")
-		buf.WriteString(query)
-		buf.WriteString("\nquery parameters: ")
-
-		for i := range args {
-			if i+1 < len(args) {
-				buf.WriteString(fmt.Sprintf("{%#v}, ", args[i]))
-			} else {
-				buf.WriteString(fmt.Sprintf("{%#v}", args[i]))
-			}
-		}
+func (l *StructuredLogger) Debugf(format string, args ...interface{}) {
+	l.Log(LevelDebug, fmt.Sprintf(format, args...))
+}
+
+func (l *StructuredLogger) Error(err error) {
+	l.Log(LevelError, err.Error())
+}
+
+func (l *StructuredLogger) SQL(query string, args ...interface{}) {
+	if LevelSQL < l.level {
+		return
+	}
 
-		_ = bwl.printer.Output(2, buf.String())
+	fields := []Field{F("query", strings.TrimSpace(query))}
+	if len(args) > 0 {
+		fields = append(fields, F("args", args))
 	}
+
+	l.Log(LevelSQL, "running sql", fields...)
 }
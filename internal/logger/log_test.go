@@ -0,0 +1,103 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+type printerStub struct {
+	lines []string
+}
+
+func (p *printerStub) Output(_ int, s string) error {
+	p.lines = append(p.lines, s)
+	return nil
+}
+
+func TestStructuredLogger(t *testing.T) {
+	t.Parallel()
+
+	t.Run("json entries with fields of the logger and the entry", func(t *testing.T) {
+		var buf bytes.Buffer
+		lg := New(NewJSONSink(&buf), LevelInfo)
+		lg.now = func() time.Time { return time.Date(2021, 6, 18, 16, 34, 57, 0, time.UTC) }
+
+		lg.With(F("operation", "migrate")).Log(
+			LevelInfo,
+			"migrated",
+			F("key", "0001_create_foo"),
+			F("duration", 1500*time.Millisecond),
+			F("error", errors.New("boom")),
+		)
+
+		assert.Equal(
+			t,
+			`{"time":"2021-06-18T16:34:57Z","level":"info","msg":"migrated","operation":"migrate",`+
+				`"key":"0001_create_foo","duration":"1.5s","error":"boom"}`+"\n",
+			buf.String(),
+		)
+
+		var decoded map[string]interface{}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	})
+
+	t.Run("entries below the level are dropped", func(t *testing.T) {
+		var buf bytes.Buffer
+		lg := New(NewTextSink(&buf), LevelWarn)
+
+		lg.SQL("SELECT 1")
+		lg.Debugf("debug %d", 1)
+		lg.Successf("done")
+		lg.Error(errors.New("failed"))
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 1)
+		assert.Contains(t, lines[0], "ERROR failed")
+	})
+
+	t.Run("plain text printer has no colors and no leading new lines", func(t *testing.T) {
+		p := &printerStub{}
+		lg := NewBWLogger(p, true, false)
+
+		lg.SQL("INSERT INTO foo VALUES (?)", 1)
+		lg.Successf("migrated: %s", "0001_create_foo")
+
+		assert.Equal(t, []string{
+			`SQL   running sql query="INSERT INTO foo VALUES (?)" args=[1]`,
+			"INFO  migrated: 0001_create_foo",
+		}, p.lines)
+	})
+
+	t.Run("standard log adapter writes to the logger", func(t *testing.T) {
+		var entries []Entry
+		lg := New(SinkFunc(func(e Entry) error {
+			entries = append(entries, e)
+			return nil
+		}), LevelDebug)
+
+		NewStdLog(lg, LevelWarn).Printf("connection %d lost", 2)
+
+		require.Len(t, entries, 1)
+		assert.Equal(t, LevelWarn, entries[0].Level)
+		assert.Equal(t, "connection 2 lost", entries[0].Message)
+	})
+}
+
+func TestParseLevel(t *testing.T) {
+	t.Parallel()
+
+	for _, l := range []Level{LevelSQL, LevelDebug, LevelInfo, LevelWarn, LevelError} {
+		parsed, err := ParseLevel(strings.ToUpper(l.String()))
+		require.NoError(t, err)
+		assert.Equal(t, l, parsed)
+	}
+
+	_, err := ParseLevel("verbose")
+	assert.True(t, errors.Is(err, ErrUnknownLevel))
+}
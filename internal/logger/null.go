@@ -1,6 +1,6 @@
 package logger
 
-type NullLogger struct {}
+type NullLogger struct{}
 
 var _ Logger = (*NullLogger)(nil)
 
@@ -13,3 +13,9 @@ func (l NullLogger) SQL(_ string, _ ...interface{}) {}
 func (NullLogger) Error(_ error) {
 
 }
+
+func (NullLogger) Log(_ Level, _ string, _ ...Field) {}
+
+func (l NullLogger) With(_ ...Field) Logger {
+	return l
+}
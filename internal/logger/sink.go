@@ -0,0 +1,171 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/logrusorgru/aurora/v3"
+	"io"
+	"strings"
+	"time"
+)
+
+type (
+	// JSONSink - writes every entry as a single line JSON object with time, level, msg and the fields
+	JSONSink struct {
+		w io.Writer
+	}
+
+	// TextSink - writes every entry as a single line of text with key=value fields
+	TextSink struct {
+		w     io.Writer
+		color bool
+	}
+
+	// PrinterSink - adapter printing entries with the standard log package or any other Printer,
+	// the time is left to the printer
+	PrinterSink struct {
+		p     Printer
+		color bool
+	}
+
+	// SinkFunc - adapter allowing an ordinary function to be used as a Sink
+	SinkFunc func(e Entry) error
+)
+
+var _ Sink = (*JSONSink)(nil)
+var _ Sink = (*TextSink)(nil)
+var _ Sink = (*PrinterSink)(nil)
+var _ Sink = SinkFunc(nil)
+
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{w: w}
+}
+
+func NewTextSink(w io.Writer) *TextSink {
+	return &TextSink{w: w}
+}
+
+// NewColorSink - text sink with the levels and messages colored for a terminal
+func NewColorSink(w io.Writer) *TextSink {
+	return &TextSink{w: w, color: true}
+}
+
+func NewPrinterSink(p Printer, color bool) *PrinterSink {
+	return &PrinterSink{p: p, color: color}
+}
+
+func (f SinkFunc) Write(e Entry) error {
+	return f(e)
+}
+
+func (s *JSONSink) Write(e Entry) error {
+	var buf bytes.Buffer
+	buf.WriteString(`{"time":`)
+	writeJSON(&buf, e.Time.UTC().Format(time.RFC3339Nano))
+	buf.WriteString(`,"level":`)
+	writeJSON(&buf, e.Level.String())
+	buf.WriteString(`,"msg":`)
+	writeJSON(&buf, e.Message)
+
+	for _, f := range e.Fields {
+		buf.WriteByte(',')
+		writeJSON(&buf, f.Key)
+		buf.WriteByte(':')
+		writeJSON(&buf, jsonValue(f.Value))
+	}
+
+	buf.WriteString("}\n")
+
+	_, err := s.w.Write(buf.Bytes())
+	return err
+}
+
+func (s *TextSink) Write(e Entry) error {
+	line := e.Time.UTC().Format(time.RFC3339) + " " + formatText(e, s.color) + "\n"
+	_, err := io.WriteString(s.w, line)
+	return err
+}
+
+func (s *PrinterSink) Write(e Entry) error {
+	return s.p.Output(2, formatText(e, s.color))
+}
+
+// formatText - level, message and key=value fields of the entry
+func formatText(e Entry, color bool) string {
+	var b strings.Builder
+	level := fmt.Sprintf("%-5s", strings.ToUpper(e.Level.String()))
+	if color {
+		level = colorize(e.Level, level)
+	}
+
+	b.WriteString(level)
+	b.WriteString(" ")
+	b.WriteString(e.Message)
+
+	for _, f := range e.Fields {
+		b.WriteString(" ")
+		b.WriteString(f.Key)
+		b.WriteString("=")
+		b.WriteString(textValue(f.Value))
+	}
+
+	return b.String()
+}
+
+func colorize(l Level, s string) string {
+	switch l {
+	case LevelSQL:
+		return aurora.Gray(15, s).String()
+	case LevelDebug:
+		return aurora.Yellow(s).String()
+	case LevelInfo:
+		return aurora.Green(s).String()
+	case LevelWarn:
+		return aurora.Magenta(s).String()
+	}
+
+	return aurora.Red(s).String()
+}
+
+// textValue - values with spaces or quotes are quoted
+func textValue(v interface{}) string {
+	var s string
+	switch tv := v.(type) {
+	case error:
+		s = tv.Error()
+	case fmt.Stringer:
+		s = tv.String()
+	case string:
+		s = tv
+	default:
+		s = fmt.Sprintf("%v", tv)
+	}
+
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return fmt.Sprintf("%q", s)
+	}
+
+	return s
+}
+
+// jsonValue - errors and stringers, e.g. durations, are encoded as strings
+func jsonValue(v interface{}) interface{} {
+	switch tv := v.(type) {
+	case error:
+		return tv.Error()
+	case fmt.Stringer:
+		return tv.String()
+	}
+
+	return v
+}
+
+func writeJSON(buf *bytes.Buffer, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(fmt.Sprintf("%v", v))
+	}
+
+	buf.Write(b)
+}
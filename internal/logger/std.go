@@ -0,0 +1,23 @@
+package logger
+
+import (
+	"bytes"
+	"log"
+)
+
+// stdWriter - io.Writer writing every line to the logger at the level
+type stdWriter struct {
+	lg    Logger
+	level Level
+}
+
+func (w stdWriter) Write(p []byte) (int, error) {
+	w.lg.Log(w.level, string(bytes.TrimRight(p, "\n")))
+	return len(p), nil
+}
+
+// NewStdLog - standard library logger writing to the logger at the level,
+// for code that accepts only *log.Logger
+func NewStdLog(lg Logger, level Level) *log.Logger {
+	return log.New(stdWriter{lg: lg, level: level}, "", 0)
+}
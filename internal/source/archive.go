@@ -0,0 +1,97 @@
+package source
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"github.com/denismitr/tern/v2/internal/logger"
+	"github.com/denismitr/tern/v2/migration"
+	"github.com/pkg/errors"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"strings"
+)
+
+var ErrUnsupportedArchive = errors.New("unsupported archive type, only .zip, .tar.gz and .tgz are supported")
+
+// ArchiveSource reads migrations from a folder inside of a .zip or .tar.gz archive
+// following the same naming rules as the LocalFileSource
+type ArchiveSource struct {
+	*FSSource
+	archive string
+}
+
+// NewArchiveSource loads the archive into memory and creates a source reading
+// migrations from the dir folder inside of it, empty dir means the archive root
+func NewArchiveSource(
+	archive string,
+	dir string,
+	lg logger.Logger,
+	vf migration.VersionFormat,
+	opts ...Option,
+) (*ArchiveSource, error) {
+	contents, err := ioutil.ReadFile(archive)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read archive %s", archive)
+	}
+
+	fsys, err := archiveFS(archive, contents)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not open archive %s", archive)
+	}
+
+	fsSource, err := NewFSSource(fsys, dir, lg, vf, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	fsSource.location = archive + ":" + fsSource.dir
+
+	return &ArchiveSource{FSSource: fsSource, archive: archive}, nil
+}
+
+func archiveFS(name string, contents []byte) (fs.FS, error) {
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return zip.NewReader(bytes.NewReader(contents), int64(len(contents)))
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		gz, err := gzip.NewReader(bytes.NewReader(contents))
+		if err != nil {
+			return nil, err
+		}
+
+		return tarFS(gz)
+	default:
+		return nil, ErrUnsupportedArchive
+	}
+}
+
+// tarFS reads all regular files of the tar stream into memory
+func tarFS(r io.Reader) (fs.FS, error) {
+	fsys := newMemFS()
+	tr := tar.NewReader(r)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return fsys, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !hdr.FileInfo().Mode().IsRegular() {
+			continue
+		}
+
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read %s", hdr.Name)
+		}
+
+		fsys.add(strings.TrimPrefix(hdr.Name, "/"), data)
+	}
+}
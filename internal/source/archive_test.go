@@ -0,0 +1,112 @@
+package source
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"github.com/denismitr/tern/v2/internal/logger"
+	"github.com/denismitr/tern/v2/migration"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var archiveStubs = map[string]string{
+	"bundle/migrations/1596897167_create_foo_table.migrate.sql":  "CREATE TABLE foo (id INT);",
+	"bundle/migrations/1596897167_create_foo_table.rollback.sql": "DROP TABLE foo;",
+	"bundle/migrations/1596897188_create_bar_table.migrate.sql":  "CREATE TABLE bar (id INT);",
+	"bundle/README.md": "release notes",
+}
+
+func TestArchiveSource(t *testing.T) {
+	t.Parallel()
+
+	folder := t.TempDir()
+	zipPath := filepath.Join(folder, "bundle.zip")
+	tarPath := filepath.Join(folder, "bundle.tar.gz")
+
+	writeZipStub(t, zipPath)
+	writeTarGzStub(t, tarPath)
+
+	for _, archive := range []string{zipPath, tarPath} {
+		archive := archive
+
+		t.Run(filepath.Base(archive), func(t *testing.T) {
+			s, err := NewArchiveSource(archive, "bundle/migrations", &logger.NullLogger{}, migration.TimestampFormat)
+			require.NoError(t, err)
+
+			migrations, err := s.Select(context.Background(), Filter{})
+			require.NoError(t, err)
+			require.Len(t, migrations, 2)
+
+			assert.Equal(t, "1596897167_create_foo_table", migrations[0].Key)
+			assert.Equal(t, []string{"CREATE TABLE foo (id INT);"}, migrations[0].Migrate)
+			assert.Equal(t, []string{"DROP TABLE foo;"}, migrations[0].Rollback)
+			assert.Equal(t, "1596897188_create_bar_table", migrations[1].Key)
+		})
+
+		t.Run(filepath.Base(archive)+" recursive from the root", func(t *testing.T) {
+			s, err := NewArchiveSource(archive, "bundle", &logger.NullLogger{}, migration.TimestampFormat, WithRecursion())
+			require.NoError(t, err)
+
+			_, err = s.Select(context.Background(), Filter{})
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, ErrNotAMigrationFile), "README.md is not a migration")
+		})
+	}
+
+	t.Run("unsupported archive", func(t *testing.T) {
+		path := filepath.Join(folder, "bundle.rar")
+		require.NoError(t, os.WriteFile(path, []byte("foo"), 0644))
+
+		_, err := NewArchiveSource(path, "", &logger.NullLogger{}, migration.TimestampFormat)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrUnsupportedArchive))
+	})
+}
+
+func writeZipStub(t *testing.T, path string) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	for name, contents := range archiveStubs {
+		fw, err := w.Create(name)
+		require.NoError(t, err)
+		_, err = fw.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Close())
+}
+
+func writeTarGzStub(t *testing.T, path string) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	w := tar.NewWriter(gz)
+	for name, contents := range archiveStubs {
+		require.NoError(t, w.WriteHeader(&tar.Header{
+			Name:     "./" + name,
+			Mode:     0644,
+			Size:     int64(len(contents)),
+			Typeflag: tar.TypeReg,
+		}))
+		_, err := w.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, w.Close())
+	require.NoError(t, gz.Close())
+}
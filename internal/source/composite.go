@@ -0,0 +1,73 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"github.com/denismitr/tern/v2/migration"
+	"github.com/pkg/errors"
+	"sort"
+)
+
+// CompositeSource merges migrations of several selectors into a single ordered set,
+// e.g. core migrations of an application and migrations of its plugins
+type CompositeSource struct {
+	selectors []Selector
+}
+
+var _ Selector = (*CompositeSource)(nil)
+
+// NewCompositeSource creates a source merging migrations of all the given selectors
+func NewCompositeSource(selectors ...Selector) (*CompositeSource, error) {
+	if len(selectors) == 0 {
+		return nil, errors.Wrap(ErrNoMigrations, "composite source requires at least one source")
+	}
+
+	return &CompositeSource{selectors: selectors}, nil
+}
+
+// Select migrations from every source, each migration is a copy marked with the origin
+// of the source it came from, same version in two sources is an error
+func (s *CompositeSource) Select(ctx context.Context, f Filter) (migration.Migrations, error) {
+	var result migration.Migrations
+
+	for i, selector := range s.selectors {
+		migrations, err := selector.Select(ctx, f)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not select migrations from %s", originOf(selector, i))
+		}
+
+		for _, selected := range migrations {
+			// migrations may be owned by the source, e.g. the in memory one, so they are not modified
+			m := *selected
+			if m.Origin == "" {
+				m.Origin = originOf(selector, i)
+			}
+
+			for _, existing := range result {
+				if migration.SameVersion(existing.Version, m.Version) {
+					return nil, errors.Wrapf(
+						ErrDuplicateVersion,
+						"version %s of %s (%s) conflicts with %s (%s)",
+						m.Version.Value, m.Key, m.Origin, existing.Key, existing.Origin,
+					)
+				}
+			}
+
+			result = append(result, &m)
+		}
+	}
+
+	sort.Sort(result)
+
+	return result, nil
+}
+
+// originOf - describes the selector by its string representation
+// or by its position when it does not have one
+func originOf(selector Selector, i int) string {
+	if s, ok := selector.(fmt.Stringer); ok {
+		return s.String()
+	}
+
+	return fmt.Sprintf("source #%d", i+1)
+}
@@ -0,0 +1,108 @@
+package source
+
+import (
+	"context"
+	"github.com/denismitr/tern/v2/internal/logger"
+	"github.com/denismitr/tern/v2/migration"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCompositeSource_Select(t *testing.T) {
+	core := fstest.MapFS{
+		"core/1596897167_create_foo_table.migrate.sql":  {Data: []byte("CREATE TABLE foo (id INT);")},
+		"core/1596897167_create_foo_table.rollback.sql": {Data: []byte("DROP TABLE foo;")},
+		"core/1597897177_create_baz_table.migrate.sql":  {Data: []byte("CREATE TABLE baz (id INT);")},
+	}
+
+	plugin := fstest.MapFS{
+		"plugin/1596897188_create_bar_table.migrate.sql": {Data: []byte("CREATE TABLE bar (id INT);")},
+	}
+
+	newFSSource := func(fsys fstest.MapFS, dir string) *FSSource {
+		s, err := NewFSSource(fsys, dir, &logger.NullLogger{}, migration.TimestampFormat)
+		require.NoError(t, err)
+		return s
+	}
+
+	t.Run("migrations of all sources are merged in order", func(t *testing.T) {
+		s, err := NewCompositeSource(newFSSource(core, "core"), newFSSource(plugin, "plugin"))
+		require.NoError(t, err)
+
+		migrations, err := s.Select(context.Background(), Filter{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"1596897167_create_foo_table",
+			"1596897188_create_bar_table",
+			"1597897177_create_baz_table",
+		}, migrations.Keys())
+
+		assert.Equal(t, "core", migrations[0].Origin)
+		assert.Equal(t, "plugin", migrations[1].Origin)
+		assert.Equal(t, "core", migrations[2].Origin)
+	})
+
+	t.Run("duplicate versions across sources are rejected", func(t *testing.T) {
+		conflicting := fstest.MapFS{
+			"other/1596897167_create_qux_table.migrate.sql": {Data: []byte("CREATE TABLE qux (id INT);")},
+		}
+
+		s, err := NewCompositeSource(newFSSource(core, "core"), newFSSource(conflicting, "other"))
+		require.NoError(t, err)
+
+		_, err = s.Select(context.Background(), Filter{})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrDuplicateVersion))
+		assert.Contains(t, err.Error(), "1596897167_create_qux_table (other)")
+		assert.Contains(t, err.Error(), "1596897167_create_foo_table (core)")
+	})
+
+	t.Run("same versions in different notation are rejected", func(t *testing.T) {
+		numbered := fstest.MapFS{
+			"numbered/1_create_qux_table.migrate.sql": {Data: []byte("CREATE TABLE qux (id INT);")},
+		}
+
+		fsSource, err := NewFSSource(numbered, "numbered", &logger.NullLogger{}, migration.NumberFormat)
+		require.NoError(t, err)
+
+		inMemory, err := NewInMemorySource(
+			migration.New(migration.Number(1), "Create foo table", []string{"CREATE TABLE foo (id INT);"}, nil),
+		)
+		require.NoError(t, err)
+
+		s, err := NewCompositeSource(inMemory, fsSource)
+		require.NoError(t, err)
+
+		_, err = s.Select(context.Background(), Filter{})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrDuplicateVersion))
+	})
+
+	t.Run("migrations of the sources are not modified", func(t *testing.T) {
+		inMemory, err := NewInMemorySource(
+			migration.New(migration.Timestamp("1596897199"), "Create qux table", []string{"CREATE TABLE qux (id INT);"}, nil),
+		)
+		require.NoError(t, err)
+
+		s, err := NewCompositeSource(newFSSource(plugin, "plugin"), inMemory)
+		require.NoError(t, err)
+
+		migrations, err := s.Select(context.Background(), Filter{})
+		require.NoError(t, err)
+		require.Len(t, migrations, 2)
+		assert.Equal(t, "memory", migrations[1].Origin)
+
+		own, err := inMemory.Select(context.Background(), Filter{})
+		require.NoError(t, err)
+		require.Len(t, own, 1)
+		assert.Equal(t, "", own[0].Origin)
+	})
+
+	t.Run("at least one source is required", func(t *testing.T) {
+		_, err := NewCompositeSource()
+		assert.Error(t, err)
+	})
+}
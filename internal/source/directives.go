@@ -0,0 +1,131 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/denismitr/tern/v2/migration"
+	"github.com/pkg/errors"
+	"strings"
+	"time"
+)
+
+const (
+	directivePrefix = "-- tern:"
+
+	noTransactionDirective = "no-transaction"
+	timeoutDirective       = "timeout"
+	labelsDirective        = "labels"
+	irreversibleDirective  = "irreversible"
+	envDirective           = "env"
+	lockDirective          = "lock"
+)
+
+var ErrInvalidDirective = errors.New("invalid migration directive")
+
+// directives - per migration settings read from the header of a migration file
+//
+//	-- tern:no-transaction
+//	-- tern:timeout=10m
+//	-- tern:labels=slow,billing
+//	-- tern:irreversible
+//	-- tern:env=local,staging
+//	-- tern:lock=none
+type directives struct {
+	noTransaction bool
+	timeout       time.Duration
+	labels        []string
+	irreversible  bool
+	environments  []string
+	lockMode      string
+}
+
+// parseDirectives reads directives from the leading comment lines of the migration file,
+// parsing stops at the first line that is neither a comment nor blank
+func parseDirectives(contents []byte) (directives, error) {
+	var d directives
+
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(contents)+1)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+
+		if !strings.HasPrefix(line, directivePrefix) {
+			continue
+		}
+
+		if err := d.apply(strings.TrimSpace(strings.TrimPrefix(line, directivePrefix))); err != nil {
+			return d, err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return d, err
+	}
+
+	return d, nil
+}
+
+func (d *directives) apply(directive string) error {
+	name, value := directive, ""
+	if i := strings.Index(directive, "="); i >= 0 {
+		name, value = strings.TrimSpace(directive[:i]), strings.TrimSpace(directive[i+1:])
+	}
+
+	switch name {
+	case noTransactionDirective:
+		d.noTransaction = true
+	case irreversibleDirective:
+		d.irreversible = true
+	case timeoutDirective:
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 {
+			return errors.Wrapf(ErrInvalidDirective, "timeout [%s] must be a positive duration", value)
+		}
+
+		d.timeout = timeout
+	case labelsDirective:
+		d.labels = append(d.labels, splitList(value)...)
+	case envDirective:
+		d.environments = append(d.environments, splitList(value)...)
+	case lockDirective:
+		mode := strings.ToLower(value)
+		if mode != "none" && mode != "shared" && mode != "exclusive" && mode != "default" {
+			return errors.Wrapf(ErrInvalidDirective, "lock [%s] must be none, shared, exclusive or default", value)
+		}
+
+		d.lockMode = mode
+	default:
+		return errors.Wrapf(ErrInvalidDirective, "unknown directive [%s]", name)
+	}
+
+	return nil
+}
+
+func (d directives) applyTo(m *migration.Migration) {
+	m.NoTransaction = d.noTransaction
+	m.Timeout = d.timeout
+	m.Labels = d.labels
+	m.Irreversible = d.irreversible
+	m.Environments = d.environments
+	m.LockMode = d.lockMode
+}
+
+// splitList - non empty trimmed items of a comma separated list
+func splitList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+
+	return result
+}
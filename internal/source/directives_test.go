@@ -0,0 +1,90 @@
+package source
+
+import (
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func Test_ParseDirectives(t *testing.T) {
+	t.Parallel()
+
+	valid := []struct {
+		name          string
+		in            string
+		noTransaction bool
+		timeout       time.Duration
+		labels        []string
+		irreversible  bool
+		environments  []string
+		lockMode      string
+	}{
+		{
+			name: "no directives",
+			in:   "CREATE TABLE foo (id INT);",
+		},
+		{
+			name:          "all directives",
+			in:            "-- tern:no-transaction\n-- tern:timeout=10m\n-- tern:labels=slow, billing\nALTER TABLE foo ADD bar INT;",
+			noTransaction: true,
+			timeout:       10 * time.Minute,
+			labels:        []string{"slow", "billing"},
+		},
+		{
+			name:    "directives between comments and blank lines",
+			in:      "-- adds a column\n\n-- tern:timeout=30s\n-- +tern migrate\nALTER TABLE foo ADD bar INT;",
+			timeout: 30 * time.Second,
+		},
+		{
+			name:         "irreversible",
+			in:           "-- tern:irreversible\nALTER TABLE foo DROP COLUMN bar;",
+			irreversible: true,
+		},
+		{
+			name:         "environments",
+			in:           "-- tern:env=local, staging\nINSERT INTO foo VALUES (1);",
+			environments: []string{"local", "staging"},
+		},
+		{
+			name:     "lock mode",
+			in:       "-- tern:lock=NONE\nALTER TABLE foo ADD bar INT;",
+			lockMode: "none",
+		},
+		{
+			name: "directives after the first statement are ignored",
+			in:   "ALTER TABLE foo ADD bar INT;\n-- tern:no-transaction",
+		},
+	}
+
+	for _, tc := range valid {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			d, err := parseDirectives([]byte(tc.in))
+			require.NoError(t, err)
+			assert.Equal(t, tc.noTransaction, d.noTransaction)
+			assert.Equal(t, tc.timeout, d.timeout)
+			assert.Equal(t, tc.labels, d.labels)
+			assert.Equal(t, tc.irreversible, d.irreversible)
+			assert.Equal(t, tc.environments, d.environments)
+			assert.Equal(t, tc.lockMode, d.lockMode)
+		})
+	}
+
+	invalid := []string{
+		"-- tern:timeout=foo",
+		"-- tern:timeout=-1s",
+		"-- tern:no-transactions",
+		"-- tern:lock=table",
+	}
+
+	for _, in := range invalid {
+		in := in
+		t.Run(in, func(t *testing.T) {
+			_, err := parseDirectives([]byte(in))
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, ErrInvalidDirective))
+		})
+	}
+}
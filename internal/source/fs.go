@@ -0,0 +1,288 @@
+package source
+
+import (
+	"context"
+	"github.com/denismitr/tern/v2/internal/logger"
+	"github.com/denismitr/tern/v2/migration"
+	"github.com/pkg/errors"
+	"io/fs"
+	"path"
+	"sort"
+	"sync"
+)
+
+var ErrInvalidSourceDir = errors.New("invalid source directory")
+var ErrDuplicateVersion = errors.New("duplicate migration version")
+
+// FSSource reads migrations from any fs.FS implementation,
+// so that embed.FS, os.DirFS or fstest.MapFS can be used as a migrations source
+type FSSource struct {
+	fsys     fs.FS
+	dir      string
+	location string
+	lg       logger.Logger
+	opts     Options
+	keyParser
+}
+
+// migrationFiles - files found for a single migration key
+type migrationFiles struct {
+	dir    string
+	count  int
+	single bool
+}
+
+var _ Selector = (*FSSource)(nil)
+
+// NewFSSource creates a migrations source that reads migration files
+// from the dir folder of the fsys file system
+func NewFSSource(
+	fsys fs.FS,
+	dir string,
+	lg logger.Logger,
+	vf migration.VersionFormat,
+	opts ...Option,
+) (*FSSource, error) {
+	dir = normalizeFSDir(dir)
+	if !fs.ValidPath(dir) {
+		return nil, errors.Wrapf(ErrInvalidSourceDir, "%s", dir)
+	}
+
+	parser, err := newKeyParser(vf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FSSource{
+		fsys:      fsys,
+		dir:       dir,
+		location:  dir,
+		lg:        lg,
+		opts:      newOptions(opts),
+		keyParser: parser,
+	}, nil
+}
+
+func (s *FSSource) Select(ctx context.Context, f Filter) (migration.Migrations, error) {
+	keys, err := s.getAllVersionsFromFolder(f)
+	if err != nil {
+		return nil, err
+	}
+
+	// buffered so that the readers never block and exit even if Select
+	// returns early on the first error or on a cancelled context
+	migrationsCh := make(chan *migration.Migration, len(keys))
+	errorsCh := make(chan error, len(keys))
+	var wg sync.WaitGroup
+
+	for k, files := range keys {
+		wg.Add(1)
+		go func(key string, files *migrationFiles) {
+			defer wg.Done()
+			var m *migration.Migration
+			var err error
+			if files.single {
+				m, err = s.readSingleFrom(files.dir, key)
+			} else {
+				m, err = s.readFrom(files.dir, key)
+			}
+
+			if err != nil {
+				mErr := errors.Wrapf(err, "with key %s", key)
+				s.lg.Error(mErr)
+				errorsCh <- mErr
+				return
+			}
+
+			migrationsCh <- m
+		}(k, files)
+	}
+
+	go func() {
+		wg.Wait()
+		close(migrationsCh)
+		close(errorsCh)
+	}()
+
+	var result migration.Migrations
+
+	for {
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case m, ok := <-migrationsCh:
+			if ok {
+				result = append(result, m)
+			} else {
+				sort.Sort(result)
+				return filterMigrations(result, f), nil
+			}
+		case err, ok := <-errorsCh:
+			if ok {
+				return nil, err
+			}
+		}
+	}
+}
+
+// String - location of the migrations
+func (s *FSSource) String() string {
+	return s.location
+}
+
+func (s *FSSource) getAllVersionsFromFolder(f Filter) (map[string]*migrationFiles, error) {
+	var onlyVersions []string
+
+	for _, v := range f.Versions {
+		onlyVersions = append(onlyVersions, v.Value)
+	}
+
+	keys := make(map[string]*migrationFiles)
+	versions := make(map[string]string)
+
+	err := s.walk(func(dir string, d fs.DirEntry) error {
+		single := false
+		key, err := convertLocalFilePathToKey(d.Name())
+		if err != nil {
+			key, err = convertSingleFilePathToKey(d.Name())
+			if err != nil {
+				return errors.Wrapf(err, "file %s is not a valid migration name", path.Join(dir, d.Name())) // fixme
+			}
+
+			single = true
+		}
+
+		if len(onlyVersions) > 0 && !keyContainsOfVersions(key, onlyVersions) {
+			return nil
+		}
+
+		if files, ok := keys[key]; ok {
+			if files.dir != dir {
+				return errors.Wrapf(ErrDuplicateVersion, "key %s found in %s and %s", key, files.dir, dir)
+			}
+
+			if files.single || single {
+				return errors.Wrapf(ErrTooManyFilesForKey, "%s has both single file and migrate/rollback files", key)
+			}
+
+			files.count++
+			if files.count > 2 {
+				return errors.Wrapf(ErrTooManyFilesForKey, "%s", key)
+			}
+
+			return nil
+		}
+
+		version, err := s.extractVersionFromKey(key)
+		if err != nil {
+			return errors.Wrapf(err, "file %s", path.Join(dir, d.Name()))
+		}
+
+		if otherKey, ok := versions[version.Value]; ok {
+			return errors.Wrapf(
+				ErrDuplicateVersion,
+				"version %s found in %s and %s",
+				version.Value, path.Join(keys[otherKey].dir, otherKey), path.Join(dir, key),
+			)
+		}
+
+		versions[version.Value] = key
+		keys[key] = &migrationFiles{dir: dir, count: 1, single: single}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// walk calls fn for every file in the source folder and, if the source is
+// recursive, in all of its sub folders, Go migration files are compiled into
+// the binary and are not read by the source
+func (s *FSSource) walk(fn func(dir string, d fs.DirEntry) error) error {
+	if !s.opts.Recursive {
+		files, err := fs.ReadDir(s.fsys, s.dir)
+		if err != nil {
+			return errors.Wrapf(err, "could not read keys from folder %s", s.location)
+		}
+
+		for i := range files {
+			if files[i].IsDir() || path.Ext(files[i].Name()) == goFileExtension {
+				continue
+			}
+
+			if err := fn(s.dir, files[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	return fs.WalkDir(s.fsys, s.dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return errors.Wrapf(err, "could not read keys from folder %s", s.location)
+		}
+
+		if d.IsDir() || path.Ext(d.Name()) == goFileExtension {
+			return nil
+		}
+
+		return fn(path.Dir(p), d)
+	})
+}
+
+func (s *FSSource) readOne(key string) (*migration.Migration, error) {
+	return s.readFrom(s.dir, key)
+}
+
+func (s *FSSource) readFrom(dir, key string) (*migration.Migration, error) {
+	up := path.Join(dir, key+defaultMigrateFileFullExtension)
+	down := path.Join(dir, key+defaultRollbackFileFullExtension)
+
+	migrateContents, err := fs.ReadFile(s.fsys, up)
+	if err != nil {
+		return nil, err
+	}
+
+	rollbackContents, err := fs.ReadFile(s.fsys, down)
+	if err != nil {
+		// rollback version may not be present
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+
+		rollbackContents = nil
+	} else if rollbackContents == nil {
+		rollbackContents = []byte{}
+	}
+
+	return s.createMigration(key, migrateContents, migrateContents, rollbackContents)
+}
+
+func (s *FSSource) readSingleFrom(dir, key string) (*migration.Migration, error) {
+	contents, err := fs.ReadFile(s.fsys, path.Join(dir, key+singleFileFullExtension))
+	if err != nil {
+		return nil, err
+	}
+
+	migrateContents, rollbackContents, err := splitSingleFile(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.createMigration(key, contents, migrateContents, rollbackContents)
+}
+
+// normalizeFSDir converts an os-like relative path e.g. ./migrations/
+// to the form accepted by fs.FS implementations
+func normalizeFSDir(dir string) string {
+	if dir == "" {
+		return "."
+	}
+
+	return path.Clean(dir)
+}
@@ -0,0 +1,354 @@
+package source
+
+import (
+	"context"
+	"github.com/denismitr/tern/v2/internal/logger"
+	"github.com/denismitr/tern/v2/migration"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestFSSource_Select(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"migrations/1596897167_create_foo_table.migrate.sql":  {Data: []byte("CREATE TABLE foo (id INT);")},
+		"migrations/1596897167_create_foo_table.rollback.sql": {Data: []byte("DROP TABLE foo;")},
+		"migrations/1596897188_create_bar_table.migrate.sql":  {Data: []byte("CREATE TABLE bar (id INT);")},
+	}
+
+	t.Run("all migrations can be read from map fs", func(t *testing.T) {
+		s, err := NewFSSource(fsys, "./migrations/", &logger.NullLogger{}, migration.TimestampFormat)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+
+		migrations, err := s.Select(ctx, Filter{})
+		require.NoError(t, err)
+		require.Len(t, migrations, 2)
+
+		assert.Equal(t, "1596897167_create_foo_table", migrations[0].Key)
+		assert.Equal(t, "Create foo table", migrations[0].Name)
+		assert.Equal(t, []string{"CREATE TABLE foo (id INT);"}, migrations[0].Migrate)
+		assert.Equal(t, []string{"DROP TABLE foo;"}, migrations[0].Rollback)
+
+		assert.Equal(t, "1596897188_create_bar_table", migrations[1].Key)
+		assert.Equal(t, "Create bar table", migrations[1].Name)
+		assert.Equal(t, []string{"CREATE TABLE bar (id INT);"}, migrations[1].Migrate)
+		assert.Equal(t, []string{""}, migrations[1].Rollback)
+	})
+
+	t.Run("migrations can be read from os dir fs", func(t *testing.T) {
+		s, err := NewFSSource(os.DirFS("."), defaultMysqlStubs, &logger.NullLogger{}, migration.TimestampFormat)
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+		defer cancel()
+
+		migrations, err := s.Select(ctx, Filter{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"1596897167_create_foo_table",
+			"1596897188_create_bar_table",
+			"1597897177_create_baz_table",
+		}, migrations.Keys())
+	})
+
+	t.Run("number based versions can be read", func(t *testing.T) {
+		numbers := fstest.MapFS{
+			"migrations/0001_create_users.migrate.sql":  {Data: []byte("CREATE TABLE users (id INT);")},
+			"migrations/0001_create_users.rollback.sql": {Data: []byte("DROP TABLE users;")},
+			"migrations/0002_create_posts.sql":          {Data: []byte("-- +tern migrate\nCREATE TABLE posts (id INT);\n")},
+		}
+
+		s, err := NewFSSource(numbers, "migrations", &logger.NullLogger{}, migration.NumberFormat)
+		require.NoError(t, err)
+
+		migrations, err := s.Select(context.Background(), Filter{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"0001_create_users", "0002_create_posts"}, migrations.Keys())
+		assert.Equal(t, "0002", migrations[1].Version.Value)
+		assert.Equal(t, "Create posts", migrations[1].Name)
+	})
+
+	t.Run("missing folder is reported", func(t *testing.T) {
+		s, err := NewFSSource(fsys, "foo", &logger.NullLogger{}, migration.TimestampFormat)
+		require.NoError(t, err)
+
+		_, err = s.Select(context.Background(), Filter{})
+		require.Error(t, err)
+	})
+
+	t.Run("absolute dir is not a valid fs path", func(t *testing.T) {
+		_, err := NewFSSource(fsys, "/migrations", &logger.NullLogger{}, migration.TimestampFormat)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrInvalidSourceDir))
+	})
+}
+
+// not parallel, so that the number of goroutines is not affected by other tests
+func TestFSSource_SelectDoesNotLeakReaders(t *testing.T) {
+	fsys := fstest.MapFS{}
+	for i := 1; i <= 50; i++ {
+		fsys["migrations/"+strconv.Itoa(i)+"_create_table.migrate.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+	}
+
+	s, err := NewFSSource(fsys, "migrations", &logger.NullLogger{}, migration.NumberFormat)
+	require.NoError(t, err)
+
+	before := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for i := 0; i < 10; i++ {
+		_, _ = s.Select(ctx, Filter{})
+	}
+
+	// assert.Eventually runs the condition in goroutines of its own
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+}
+
+func TestFSSource_Recursive(t *testing.T) {
+	t.Parallel()
+
+	t.Run("migrations can be read from nested folders", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"migrations/2020/08/1596897167_create_foo_table.migrate.sql":  {Data: []byte("CREATE TABLE foo (id INT);")},
+			"migrations/2020/08/1596897167_create_foo_table.rollback.sql": {Data: []byte("DROP TABLE foo;")},
+			"migrations/2020/08/1596897188_create_bar_table.migrate.sql":  {Data: []byte("CREATE TABLE bar (id INT);")},
+			"migrations/2020/07/1595897188_create_baz_table.migrate.sql":  {Data: []byte("CREATE TABLE baz (id INT);")},
+			"migrations/1594897188_initial.migrate.sql":                   {Data: []byte("CREATE TABLE qux (id INT);")},
+		}
+
+		s, err := NewFSSource(fsys, "migrations", &logger.NullLogger{}, migration.TimestampFormat, WithRecursion())
+		require.NoError(t, err)
+
+		migrations, err := s.Select(context.Background(), Filter{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"1594897188_initial",
+			"1595897188_create_baz_table",
+			"1596897167_create_foo_table",
+			"1596897188_create_bar_table",
+		}, migrations.Keys())
+		assert.Equal(t, []string{"DROP TABLE foo;"}, migrations[2].Rollback)
+	})
+
+	t.Run("nested folders are ignored without recursion", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"migrations/2020/1596897167_create_foo_table.migrate.sql": {Data: []byte("CREATE TABLE foo (id INT);")},
+			"migrations/1594897188_initial.migrate.sql":               {Data: []byte("CREATE TABLE qux (id INT);")},
+		}
+
+		s, err := NewFSSource(fsys, "migrations", &logger.NullLogger{}, migration.TimestampFormat)
+		require.NoError(t, err)
+
+		migrations, err := s.Select(context.Background(), Filter{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1594897188_initial"}, migrations.Keys())
+	})
+
+	t.Run("same version in two folders is reported", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"migrations/core/1596897167_create_foo_table.migrate.sql": {Data: []byte("CREATE TABLE foo (id INT);")},
+			"migrations/blog/1596897167_create_foo_table.migrate.sql": {Data: []byte("CREATE TABLE foo (id INT);")},
+		}
+
+		s, err := NewFSSource(fsys, "migrations", &logger.NullLogger{}, migration.TimestampFormat, WithRecursion())
+		require.NoError(t, err)
+
+		_, err = s.Select(context.Background(), Filter{})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrDuplicateVersion))
+	})
+
+	t.Run("same version with different names is reported", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"migrations/core/1596897167_create_foo_table.migrate.sql": {Data: []byte("CREATE TABLE foo (id INT);")},
+			"migrations/blog/1596897167_create_posts.migrate.sql":     {Data: []byte("CREATE TABLE posts (id INT);")},
+		}
+
+		s, err := NewFSSource(fsys, "migrations", &logger.NullLogger{}, migration.TimestampFormat, WithRecursion())
+		require.NoError(t, err)
+
+		_, err = s.Select(context.Background(), Filter{})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrDuplicateVersion))
+	})
+}
+
+func TestFSSource_SingleFile(t *testing.T) {
+	t.Parallel()
+
+	t.Run("single file migrations can be mixed with two file migrations", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"migrations/1596897167_create_foo_table.migrate.sql":  {Data: []byte("CREATE TABLE foo (id INT);")},
+			"migrations/1596897167_create_foo_table.rollback.sql": {Data: []byte("DROP TABLE foo;")},
+			"migrations/1596897188_create_bar_table.sql": {Data: []byte(
+				"-- +tern migrate\nCREATE TABLE bar (id INT);\n\n-- +tern rollback\nDROP TABLE bar;\n",
+			)},
+			"migrations/1596897199_create_baz_table.sql": {Data: []byte(
+				"-- some comment\n-- +tern migrate\nCREATE TABLE baz (id INT);\n",
+			)},
+		}
+
+		s, err := NewFSSource(fsys, "migrations", &logger.NullLogger{}, migration.TimestampFormat)
+		require.NoError(t, err)
+
+		migrations, err := s.Select(context.Background(), Filter{})
+		require.NoError(t, err)
+		require.Len(t, migrations, 3)
+
+		assert.Equal(t, "1596897188_create_bar_table", migrations[1].Key)
+		assert.Equal(t, "Create bar table", migrations[1].Name)
+		assert.Equal(t, []string{"CREATE TABLE bar (id INT);"}, migrations[1].Migrate)
+		assert.Equal(t, []string{"DROP TABLE bar;"}, migrations[1].Rollback)
+
+		assert.Equal(t, "1596897199_create_baz_table", migrations[2].Key)
+		assert.Equal(t, []string{"CREATE TABLE baz (id INT);"}, migrations[2].Migrate)
+		assert.Equal(t, []string{""}, migrations[2].Rollback)
+	})
+
+	t.Run("single file without migrate section is reported", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"migrations/1596897188_create_bar_table.sql": {Data: []byte("CREATE TABLE bar (id INT);")},
+		}
+
+		s, err := NewFSSource(fsys, "migrations", &logger.NullLogger{}, migration.TimestampFormat)
+		require.NoError(t, err)
+
+		_, err = s.Select(context.Background(), Filter{})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrMissingMigrateSection))
+	})
+
+	t.Run("key cannot have both layouts", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"migrations/1596897188_create_bar_table.migrate.sql": {Data: []byte("CREATE TABLE bar (id INT);")},
+			"migrations/1596897188_create_bar_table.sql":         {Data: []byte("-- +tern migrate\nCREATE TABLE bar (id INT);")},
+		}
+
+		s, err := NewFSSource(fsys, "migrations", &logger.NullLogger{}, migration.TimestampFormat)
+		require.NoError(t, err)
+
+		_, err = s.Select(context.Background(), Filter{})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrTooManyFilesForKey))
+	})
+}
+
+// dottedFormat - custom version format with dots e.g. 1.4.2
+type dottedFormat struct{}
+
+func (dottedFormat) Name() migration.VersionFormat { return "dotted" }
+
+func (dottedFormat) Pattern() string { return `\d+\.\d+\.\d+` }
+
+func (dottedFormat) Parse(s string) (migration.Version, error) {
+	return migration.Version{Format: "dotted", Value: s}, nil
+}
+
+func (dottedFormat) Compare(a, b string) int {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range pa {
+		na, _ := strconv.Atoi(pa[i])
+		nb, _ := strconv.Atoi(pb[i])
+		if na != nb {
+			return na - nb
+		}
+	}
+
+	return 0
+}
+
+func (dottedFormat) Next(_ migration.ClockFunc, _ []migration.Version) (migration.Version, error) {
+	return migration.Version{}, migration.ErrInvalidVersionFormat
+}
+
+func TestFSSource_Irreversible(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"migrations/1596897167_create_foo_table.migrate.sql":  {Data: []byte("CREATE TABLE foo (id INT);")},
+		"migrations/1596897167_create_foo_table.rollback.sql": {Data: []byte("DROP TABLE foo;")},
+		"migrations/1596897177_seed_foo_table.migrate.sql":    {Data: []byte("INSERT INTO foo VALUES (1);")},
+		"migrations/1596897177_seed_foo_table.rollback.sql":   {Data: []byte("")},
+		"migrations/1596897188_drop_bar_column.migrate.sql":   {Data: []byte("ALTER TABLE foo DROP COLUMN bar;")},
+		"migrations/1596897199_drop_baz_column.migrate.sql": {Data: []byte(
+			"-- tern:irreversible\nALTER TABLE foo DROP COLUMN baz;",
+		)},
+		"migrations/1596897199_drop_baz_column.rollback.sql": {Data: []byte("")},
+		"migrations/1596897211_create_bar_table.sql":         {Data: []byte("-- +tern migrate\nCREATE TABLE bar (id INT);\n")},
+		"migrations/1596897222_create_baz_table.sql": {Data: []byte(
+			"-- +tern migrate\nCREATE TABLE baz (id INT);\n-- +tern rollback\n",
+		)},
+	}
+
+	s, err := NewFSSource(fsys, "migrations", &logger.NullLogger{}, migration.TimestampFormat)
+	require.NoError(t, err)
+
+	migrations, err := s.Select(context.Background(), Filter{})
+	require.NoError(t, err)
+	require.Len(t, migrations, 6)
+
+	irreversible := make(map[string]bool)
+	for _, m := range migrations {
+		irreversible[m.Key] = m.Irreversible
+	}
+
+	assert.Equal(t, map[string]bool{
+		"1596897167_create_foo_table": false,
+		"1596897177_seed_foo_table":   false,
+		"1596897188_drop_bar_column":  true,
+		"1596897199_drop_baz_column":  true,
+		"1596897211_create_bar_table": true,
+		"1596897222_create_baz_table": false,
+	}, irreversible)
+}
+
+func TestFSSource_CustomVersionFormat(t *testing.T) {
+	require.NoError(t, migration.RegisterFormat(dottedFormat{}))
+
+	fsys := fstest.MapFS{
+		"migrations/1.10.0_add_index.migrate.sql": {Data: []byte("CREATE INDEX idx ON foo (id);")},
+		"migrations/1.4.2_create_foo_table.sql":   {Data: []byte("-- +tern migrate\nCREATE TABLE foo (id INT);\n")},
+	}
+
+	t.Run("versions with dots are read and ordered by the format", func(t *testing.T) {
+		s, err := NewFSSource(fsys, "migrations", &logger.NullLogger{}, "dotted")
+		require.NoError(t, err)
+
+		migrations, err := s.Select(context.Background(), Filter{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1.4.2_create_foo_table", "1.10.0_add_index"}, migrations.Keys())
+		assert.Equal(t, "Add index", migrations[1].Name)
+	})
+
+	t.Run("hidden files are not migrations", func(t *testing.T) {
+		_, err := convertLocalFilePathToKey(".1.4.3_create_bar_table.migrate.sql")
+		assert.True(t, errors.Is(err, ErrNotAMigrationFile))
+
+		_, err = convertSingleFilePathToKey(".sql")
+		assert.True(t, errors.Is(err, ErrNotAMigrationFile))
+	})
+
+	t.Run("unknown format is rejected", func(t *testing.T) {
+		_, err := NewFSSource(fsys, "migrations", &logger.NullLogger{}, "calver")
+		assert.True(t, errors.Is(err, migration.ErrUnknownVersionFormat))
+	})
+}
@@ -0,0 +1,146 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+
+	"github.com/denismitr/tern/v2/internal/logger"
+	"github.com/denismitr/tern/v2/migration"
+	"github.com/pkg/errors"
+)
+
+var ErrGitCommandFailed = errors.New("git command failed")
+
+// GitSource reads migrations folder as it exists at the given git revision
+// (commit, branch or tag) of the local repository, without checking it out
+type GitSource struct {
+	folder string
+	ref    string
+	repo   string
+	prefix string
+	lg     logger.Logger
+	vf     migration.VersionFormat
+	opts   []Option
+}
+
+var _ Selector = (*GitSource)(nil)
+var _ Linter = (*GitSource)(nil)
+
+// NewGitSource creates a source for the folder of the local git repository at ref revision
+func NewGitSource(
+	folder string,
+	ref string,
+	lg logger.Logger,
+	vf migration.VersionFormat,
+	opts ...Option,
+) (*GitSource, error) {
+	ctx := context.Background()
+
+	// validate version format early, the same way other sources do
+	if _, err := newKeyParser(vf); err != nil {
+		return nil, err
+	}
+
+	repo, err := gitString(ctx, folder, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, errors.Wrapf(err, "folder %s is not inside of a git repository", folder)
+	}
+
+	prefix, err := gitString(ctx, folder, "rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := runGit(ctx, repo, "rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+		return nil, errors.Wrapf(err, "revision %s not found", ref)
+	}
+
+	return &GitSource{
+		folder: folder,
+		ref:    ref,
+		repo:   repo,
+		prefix: normalizeFSDir(prefix),
+		lg:     lg,
+		vf:     vf,
+		opts:   opts,
+	}, nil
+}
+
+func (s *GitSource) Select(ctx context.Context, f Filter) (migration.Migrations, error) {
+	fsSource, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return fsSource.Select(ctx, f)
+}
+
+// Lint - checks migrations folder as it exists at the source revision
+func (s *GitSource) Lint(ctx context.Context, rules LintRules) (*LintReport, error) {
+	fsSource, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return fsSource.Lint(ctx, rules)
+}
+
+// String - migrations folder and the revision it is read at
+func (s *GitSource) String() string {
+	return s.folder + "@" + s.ref
+}
+
+// load - reads the folder tree at the revision with git archive
+func (s *GitSource) load(ctx context.Context) (*FSSource, error) {
+	args := []string{"archive", "--format=tar", s.ref}
+	if s.prefix != "." {
+		args = append(args, "--", s.prefix)
+	}
+
+	archive, err := runGit(ctx, s.repo, args...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read %s at revision %s", s.folder, s.ref)
+	}
+
+	fsys, err := tarFS(bytes.NewReader(archive))
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read %s at revision %s", s.folder, s.ref)
+	}
+
+	fsSource, err := NewFSSource(fsys, s.prefix, s.lg, s.vf, s.opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	fsSource.location = s.String()
+
+	return fsSource, nil
+}
+
+func runGit(ctx context.Context, dir string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, errors.Wrapf(
+			ErrGitCommandFailed,
+			"git %s: %s: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()),
+		)
+	}
+
+	return stdout.Bytes(), nil
+}
+
+func gitString(ctx context.Context, dir string, args ...string) (string, error) {
+	out, err := runGit(ctx, dir, args...)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
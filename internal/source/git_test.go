@@ -0,0 +1,80 @@
+package source
+
+import (
+	"context"
+	"github.com/denismitr/tern/v2/internal/logger"
+	"github.com/denismitr/tern/v2/migration"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestGitSource_Select(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	repo := t.TempDir()
+	folder := filepath.Join(repo, "db", "migrations")
+	require.NoError(t, os.MkdirAll(folder, 0755))
+
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", repo}, args...)...)
+		cmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=tern", "GIT_AUTHOR_EMAIL=tern@example.com",
+			"GIT_COMMITTER_NAME=tern", "GIT_COMMITTER_EMAIL=tern@example.com")
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+
+	write := func(name, contents string) {
+		t.Helper()
+		require.NoError(t, os.WriteFile(filepath.Join(folder, name), []byte(contents), 0644))
+	}
+
+	git("init", "-q")
+	write("1596897167_create_foo_table.migrate.sql", "CREATE TABLE foo (id INT);")
+	write("1596897167_create_foo_table.rollback.sql", "DROP TABLE foo;")
+	git("add", ".")
+	git("commit", "-q", "-m", "foo")
+	git("tag", "v1.0.0")
+
+	write("1596897167_create_foo_table.migrate.sql", "CREATE TABLE foo (id BIGINT);")
+	write("1596897188_create_bar_table.migrate.sql", "CREATE TABLE bar (id INT);")
+	git("add", ".")
+	git("commit", "-q", "-m", "bar")
+
+	write("1597897177_create_baz_table.migrate.sql", "CREATE TABLE baz (id INT);")
+
+	t.Run("migrations are read at the tag", func(t *testing.T) {
+		s, err := NewGitSource(folder, "v1.0.0", &logger.NullLogger{}, migration.TimestampFormat)
+		require.NoError(t, err)
+
+		migrations, err := s.Select(context.Background(), Filter{})
+		require.NoError(t, err)
+		require.Len(t, migrations, 1)
+		assert.Equal(t, "1596897167_create_foo_table", migrations[0].Key)
+		assert.Equal(t, []string{"CREATE TABLE foo (id INT);"}, migrations[0].Migrate)
+		assert.Equal(t, []string{"DROP TABLE foo;"}, migrations[0].Rollback)
+	})
+
+	t.Run("uncommitted files are ignored", func(t *testing.T) {
+		s, err := NewGitSource(folder, "HEAD", &logger.NullLogger{}, migration.TimestampFormat)
+		require.NoError(t, err)
+
+		migrations, err := s.Select(context.Background(), Filter{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1596897167_create_foo_table", "1596897188_create_bar_table"}, migrations.Keys())
+		assert.Equal(t, []string{"CREATE TABLE foo (id BIGINT);"}, migrations[0].Migrate)
+	})
+
+	t.Run("unknown revision is reported", func(t *testing.T) {
+		_, err := NewGitSource(folder, "v2.0.0", &logger.NullLogger{}, migration.TimestampFormat)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrGitCommandFailed))
+	})
+}
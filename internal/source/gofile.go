@@ -0,0 +1,138 @@
+package source
+
+import (
+	"fmt"
+	"github.com/denismitr/tern/v2/migration"
+	"github.com/pkg/errors"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	goFileExtension      = ".go"
+	defaultGoPackageName = "migrations"
+)
+
+var ErrInvalidGoMigrationName = errors.New("invalid Go migration name")
+
+// GoCreator - source able to create Go migration files registering themselves with migration.Register
+type GoCreator interface {
+	CreateGo(dt, name string, withRollback bool) (*migration.Migration, error)
+}
+
+var _ GoCreator = (*LocalFileSource)(nil)
+
+const goFileTemplate = `package %s
+
+import (
+	"context"
+
+	"github.com/denismitr/tern/v2/migration"
+)
+
+func init() {
+	migration.Register(%q, %q, %s, %s)
+}
+
+func %s(ctx context.Context, ex migration.Executor) error {
+	return nil
+}
+`
+
+const goRollbackFuncTemplate = `
+func %s(ctx context.Context, ex migration.Executor) error {
+	return nil
+}
+`
+
+// CreateGo - creates <version>_<name>.go file with empty migrate and rollback functions
+// registered in the init function, the folder becomes a Go package that has to be
+// imported by the binary running the migrations
+func (lfs *LocalFileSource) CreateGo(dt, name string, withRollback bool) (*migration.Migration, error) {
+	key := migration.CreateKeyFromVersionAndName(dt, name)
+	if strings.HasSuffix(key, "_test") {
+		return nil, errors.Wrapf(ErrInvalidGoMigrationName, "%s would be compiled as a test file", key)
+	}
+
+	if lfs.AlreadyExists(dt, name) {
+		return nil, errors.Wrapf(ErrMigrationAlreadyExists, "migration %s with key already exists", key)
+	}
+
+	folder := lfs.createFolder(dt)
+	if folder != lfs.folder {
+		if err := os.MkdirAll(folder, 0755); err != nil {
+			return nil, errors.Wrapf(err, "could not create folder [%s]", folder)
+		}
+	}
+
+	contents, err := goFileStub(goPackageName(folder), dt, name, withRollback)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := createFile(filepath.Join(folder, key+goFileExtension), contents); err != nil {
+		return nil, err
+	}
+
+	return &migration.Migration{
+		Key:  key,
+		Name: name,
+		Version: migration.Version{
+			Value:  dt,
+			Format: lfs.versionFormat,
+		},
+	}, nil
+}
+
+// goFileStub - contents of a new Go migration, function names include the version
+// so that migrations of the same package never clash
+func goFileStub(pkg, version, name string, withRollback bool) (string, error) {
+	suffix := version + goIdentifier(name)
+	migrateFunc, rollbackFunc := "migrate"+suffix, "nil"
+	if withRollback {
+		rollbackFunc = "rollback" + suffix
+	}
+
+	contents := fmt.Sprintf(goFileTemplate, pkg, version, name, migrateFunc, rollbackFunc, migrateFunc)
+	if withRollback {
+		contents += fmt.Sprintf(goRollbackFuncTemplate, rollbackFunc)
+	}
+
+	formatted, err := format.Source([]byte(contents))
+	if err != nil {
+		return "", errors.Wrapf(ErrInvalidGoMigrationName, "%s: %s", name, err)
+	}
+
+	return string(formatted), nil
+}
+
+// goPackageName - package name derived from the folder name, e.g. migrations or db_migrations
+func goPackageName(folder string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(filepath.Base(folder)) {
+		switch {
+		case r >= 'a' && r <= 'z', r == '_', r >= '0' && r <= '9' && b.Len() > 0:
+			b.WriteRune(r)
+		case r == '-' || r == ' ':
+			b.WriteRune('_')
+		}
+	}
+
+	if b.Len() == 0 || strings.Trim(b.String(), "_") == "" {
+		return defaultGoPackageName
+	}
+
+	return b.String()
+}
+
+// goIdentifier - CamelCase form of the migration name
+func goIdentifier(name string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' || r == ' ' }) {
+		b.WriteString(ucFirst(part))
+	}
+
+	return b.String()
+}
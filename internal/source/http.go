@@ -0,0 +1,312 @@
+package source
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/denismitr/tern/v2/internal/logger"
+	"github.com/denismitr/tern/v2/migration"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	etagCacheExtension = ".etag"
+	bodyCacheExtension = ".body"
+)
+
+var ErrUnexpectedHTTPStatus = errors.New("unexpected http response status")
+var ErrChecksumMismatch = errors.New("migration file checksum mismatch")
+
+type (
+	// HTTPOptions - configure the way the HTTP source fetches migration files
+	HTTPOptions struct {
+		// BearerToken is sent in Authorization header if not empty, only with the requests
+		// to the scheme and host of the manifest url, so it does not leak to other servers
+		BearerToken string
+
+		// CacheDir enables local caching of the fetched files, validated with ETag
+		CacheDir string
+
+		// Client is used to make the requests, http.DefaultClient is used if nil
+		Client *http.Client
+	}
+
+	// Manifest - lists all migrations available on a remote server
+	//  {
+	//    "migrations": [{
+	//      "key": "1596897167_create_foo_table",
+	//      "migrate": {"url": "1596897167_create_foo_table.migrate.sql", "size": 27, "sha256": "..."},
+	//      "rollback": {"url": "1596897167_create_foo_table.rollback.sql", "size": 16, "sha256": "..."}
+	//    }]
+	//  }
+	// file urls can be relative to the manifest url, size and sha256 are optional
+	Manifest struct {
+		Migrations []ManifestEntry `json:"migrations"`
+	}
+
+	ManifestEntry struct {
+		Key      string        `json:"key"`
+		Migrate  ManifestFile  `json:"migrate"`
+		Rollback *ManifestFile `json:"rollback,omitempty"`
+	}
+
+	ManifestFile struct {
+		URL    string `json:"url"`
+		Size   int64  `json:"size,omitempty"`
+		SHA256 string `json:"sha256,omitempty"`
+	}
+)
+
+// HTTPSource fetches migrations from a remote server using a manifest
+// that lists migration keys and urls of their migrate and rollback files
+type HTTPSource struct {
+	manifestURL *url.URL
+	lg          logger.Logger
+	opts        HTTPOptions
+	keyParser
+}
+
+var _ Selector = (*HTTPSource)(nil)
+
+func NewHTTPSource(
+	manifestURL string,
+	lg logger.Logger,
+	vf migration.VersionFormat,
+	opts HTTPOptions,
+) (*HTTPSource, error) {
+	u, err := url.Parse(manifestURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid manifest url %s", manifestURL)
+	}
+
+	parser, err := newKeyParser(vf)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+
+	return &HTTPSource{
+		manifestURL: u,
+		lg:          lg,
+		opts:        opts,
+		keyParser:   parser,
+	}, nil
+}
+
+func (s *HTTPSource) Select(ctx context.Context, f Filter) (migration.Migrations, error) {
+	manifest, err := s.fetchManifest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var onlyVersions []string
+	for _, v := range f.Versions {
+		onlyVersions = append(onlyVersions, v.Value)
+	}
+
+	if err := s.checkVersions(manifest); err != nil {
+		return nil, err
+	}
+
+	var result migration.Migrations
+
+	for _, entry := range manifest.Migrations {
+		if len(onlyVersions) > 0 && !keyContainsOfVersions(entry.Key, onlyVersions) {
+			continue
+		}
+
+		m, err := s.readOne(ctx, entry)
+		if err != nil {
+			mErr := errors.Wrapf(err, "with key %s", entry.Key)
+			s.lg.Error(mErr)
+			return nil, mErr
+		}
+
+		result = append(result, m)
+	}
+
+	sort.Sort(result)
+
+	return filterMigrations(result, f), nil
+}
+
+// checkVersions - every version can be listed in the manifest only once, same as in a folder
+func (s *HTTPSource) checkVersions(manifest *Manifest) error {
+	versions := make(map[string]string)
+	for _, entry := range manifest.Migrations {
+		version, err := s.extractVersionFromKey(entry.Key)
+		if err != nil {
+			return errors.Wrapf(err, "manifest key %s", entry.Key)
+		}
+
+		if otherKey, ok := versions[version.Value]; ok {
+			return errors.Wrapf(
+				ErrDuplicateVersion,
+				"version %s is listed in manifest by %s and %s", version.Value, otherKey, entry.Key,
+			)
+		}
+
+		versions[version.Value] = entry.Key
+	}
+
+	return nil
+}
+
+// String - url of the migrations manifest
+func (s *HTTPSource) String() string {
+	return s.manifestURL.String()
+}
+
+func (s *HTTPSource) readOne(ctx context.Context, entry ManifestEntry) (*migration.Migration, error) {
+	migrateContents, err := s.fetchFile(ctx, entry.Migrate)
+	if err != nil {
+		return nil, err
+	}
+
+	var rollbackContents []byte
+	if entry.Rollback != nil {
+		rollbackContents, err = s.fetchFile(ctx, *entry.Rollback)
+		if err != nil {
+			return nil, err
+		}
+
+		if rollbackContents == nil {
+			rollbackContents = []byte{}
+		}
+	}
+
+	return s.createMigration(entry.Key, migrateContents, migrateContents, rollbackContents)
+}
+
+func (s *HTTPSource) fetchManifest(ctx context.Context) (*Manifest, error) {
+	body, err := s.fetch(ctx, s.manifestURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not fetch migrations manifest")
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return nil, errors.Wrap(err, "could not parse migrations manifest")
+	}
+
+	return &manifest, nil
+}
+
+func (s *HTTPSource) fetchFile(ctx context.Context, file ManifestFile) ([]byte, error) {
+	u, err := s.manifestURL.Parse(file.URL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid migration file url %s", file.URL)
+	}
+
+	body, err := s.fetch(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	if file.Size > 0 && int64(len(body)) != file.Size {
+		return nil, errors.Wrapf(ErrChecksumMismatch, "%s size is %d, expected %d", u, len(body), file.Size)
+	}
+
+	if file.SHA256 != "" {
+		sum := sha256.Sum256(body)
+		if hex.EncodeToString(sum[:]) != file.SHA256 {
+			return nil, errors.Wrapf(ErrChecksumMismatch, "%s sha256 does not match", u)
+		}
+	}
+
+	return body, nil
+}
+
+// sameOrigin - the url points to the server of the manifest
+func (s *HTTPSource) sameOrigin(u *url.URL) bool {
+	return strings.EqualFold(u.Scheme, s.manifestURL.Scheme) && strings.EqualFold(u.Host, s.manifestURL.Host)
+}
+
+// fetch - performs GET request, when cache dir is configured the ETag of
+// the previous response is sent and cached body is used if server responds with 304
+func (s *HTTPSource) fetch(ctx context.Context, u *url.URL) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.opts.BearerToken != "" && s.sameOrigin(u) {
+		req.Header.Set("Authorization", "Bearer "+s.opts.BearerToken)
+	}
+
+	cacheKey := s.cacheKey(u)
+	if cacheKey != "" {
+		if etag, err := ioutil.ReadFile(cacheKey + etagCacheExtension); err == nil {
+			req.Header.Set("If-None-Match", string(etag))
+		}
+	}
+
+	resp, err := s.opts.Client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "GET %s failed", u)
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			s.lg.Error(err)
+		}
+	}()
+
+	if resp.StatusCode == http.StatusNotModified && cacheKey != "" {
+		s.lg.Debugf("using cached %s", u)
+		return ioutil.ReadFile(cacheKey + bodyCacheExtension)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Wrapf(ErrUnexpectedHTTPStatus, "GET %s responded with %d", u, resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read response of %s", u)
+	}
+
+	if etag := resp.Header.Get("ETag"); cacheKey != "" && etag != "" {
+		if err := s.store(cacheKey, etag, body); err != nil {
+			s.lg.Error(err)
+		}
+	}
+
+	return body, nil
+}
+
+func (s *HTTPSource) cacheKey(u *url.URL) string {
+	if s.opts.CacheDir == "" {
+		return ""
+	}
+
+	sum := sha256.Sum256([]byte(u.String()))
+	return filepath.Join(s.opts.CacheDir, hex.EncodeToString(sum[:]))
+}
+
+func (s *HTTPSource) store(cacheKey, etag string, body []byte) error {
+	if err := os.MkdirAll(s.opts.CacheDir, 0755); err != nil {
+		return errors.Wrapf(err, "could not create cache dir %s", s.opts.CacheDir)
+	}
+
+	if err := ioutil.WriteFile(cacheKey+bodyCacheExtension, body, 0644); err != nil {
+		return errors.Wrap(err, "could not cache response body")
+	}
+
+	if err := ioutil.WriteFile(cacheKey+etagCacheExtension, []byte(etag), 0644); err != nil {
+		return errors.Wrap(err, "could not cache response etag")
+	}
+
+	return nil
+}
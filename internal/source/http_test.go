@@ -0,0 +1,234 @@
+package source
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/denismitr/tern/v2/internal/logger"
+	"github.com/denismitr/tern/v2/migration"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+type remoteStub struct {
+	files        map[string]string
+	manifest     Manifest
+	token        string
+	fullResponse int32
+}
+
+func (rs *remoteStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if rs.token != "" && r.Header.Get("Authorization") != "Bearer "+rs.token {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var body []byte
+	if r.URL.Path == "/manifest.json" {
+		b, err := json.Marshal(rs.manifest)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		body = b
+	} else if contents, ok := rs.files[r.URL.Path]; ok {
+		body = []byte(contents)
+	} else {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	etag := `"` + checksum(string(body)) + `"`
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	atomic.AddInt32(&rs.fullResponse, 1)
+	w.Header().Set("ETag", etag)
+	_, _ = w.Write(body)
+}
+
+func checksum(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func newRemoteStub() *remoteStub {
+	files := map[string]string{
+		"/files/1596897167_create_foo_table.migrate.sql":  "CREATE TABLE foo (id INT);",
+		"/files/1596897167_create_foo_table.rollback.sql": "DROP TABLE foo;",
+		"/files/1596897188_create_bar_table.migrate.sql":  "-- tern:labels=bar\nCREATE TABLE bar (id INT);",
+	}
+
+	return &remoteStub{
+		files: files,
+		token: "secret",
+		manifest: Manifest{
+			Migrations: []ManifestEntry{
+				{
+					Key: "1596897188_create_bar_table",
+					Migrate: ManifestFile{
+						URL:    "files/1596897188_create_bar_table.migrate.sql",
+						Size:   int64(len(files["/files/1596897188_create_bar_table.migrate.sql"])),
+						SHA256: checksum(files["/files/1596897188_create_bar_table.migrate.sql"]),
+					},
+				},
+				{
+					Key:     "1596897167_create_foo_table",
+					Migrate: ManifestFile{URL: "/files/1596897167_create_foo_table.migrate.sql"},
+					Rollback: &ManifestFile{
+						URL:    "files/1596897167_create_foo_table.rollback.sql",
+						SHA256: checksum(files["/files/1596897167_create_foo_table.rollback.sql"]),
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestHTTPSource_Select(t *testing.T) {
+	t.Parallel()
+
+	t.Run("migrations are fetched and sorted", func(t *testing.T) {
+		stub := newRemoteStub()
+		srv := httptest.NewServer(stub)
+		defer srv.Close()
+
+		s, err := NewHTTPSource(srv.URL+"/manifest.json", &logger.NullLogger{}, migration.TimestampFormat, HTTPOptions{
+			BearerToken: "secret",
+		})
+		require.NoError(t, err)
+
+		migrations, err := s.Select(context.Background(), Filter{})
+		require.NoError(t, err)
+		require.Len(t, migrations, 2)
+
+		assert.Equal(t, "1596897167_create_foo_table", migrations[0].Key)
+		assert.Equal(t, "Create foo table", migrations[0].Name)
+		assert.Equal(t, []string{"CREATE TABLE foo (id INT);"}, migrations[0].Migrate)
+		assert.Equal(t, []string{"DROP TABLE foo;"}, migrations[0].Rollback)
+
+		assert.Equal(t, "1596897188_create_bar_table", migrations[1].Key)
+		assert.Equal(t, []string{"bar"}, migrations[1].Labels)
+	})
+
+	t.Run("versions filter is applied", func(t *testing.T) {
+		stub := newRemoteStub()
+		srv := httptest.NewServer(stub)
+		defer srv.Close()
+
+		s, err := NewHTTPSource(srv.URL+"/manifest.json", &logger.NullLogger{}, migration.TimestampFormat, HTTPOptions{
+			BearerToken: "secret",
+		})
+		require.NoError(t, err)
+
+		migrations, err := s.Select(context.Background(), Filter{Versions: []migration.Version{{Value: "1596897188"}}})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1596897188_create_bar_table"}, migrations.Keys())
+	})
+
+	t.Run("unauthorized request fails", func(t *testing.T) {
+		stub := newRemoteStub()
+		srv := httptest.NewServer(stub)
+		defer srv.Close()
+
+		s, err := NewHTTPSource(srv.URL+"/manifest.json", &logger.NullLogger{}, migration.TimestampFormat, HTTPOptions{})
+		require.NoError(t, err)
+
+		_, err = s.Select(context.Background(), Filter{})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrUnexpectedHTTPStatus))
+	})
+
+	t.Run("checksum mismatch fails", func(t *testing.T) {
+		stub := newRemoteStub()
+		stub.files["/files/1596897188_create_bar_table.migrate.sql"] = "-- tern:labels=baz\nCREATE TABLE bar (id INT);"
+		srv := httptest.NewServer(stub)
+		defer srv.Close()
+
+		s, err := NewHTTPSource(srv.URL+"/manifest.json", &logger.NullLogger{}, migration.TimestampFormat, HTTPOptions{
+			BearerToken: "secret",
+		})
+		require.NoError(t, err)
+
+		_, err = s.Select(context.Background(), Filter{})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrChecksumMismatch))
+	})
+
+	t.Run("token is not sent to other hosts", func(t *testing.T) {
+		var authorization atomic.Value
+		authorization.Store("")
+		files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authorization.Store(r.Header.Get("Authorization"))
+			_, _ = w.Write([]byte("CREATE TABLE baz (id INT);"))
+		}))
+		defer files.Close()
+
+		stub := newRemoteStub()
+		stub.manifest.Migrations = append(stub.manifest.Migrations, ManifestEntry{
+			Key:     "1596897199_create_baz_table",
+			Migrate: ManifestFile{URL: files.URL + "/1596897199_create_baz_table.migrate.sql"},
+		})
+		srv := httptest.NewServer(stub)
+		defer srv.Close()
+
+		s, err := NewHTTPSource(srv.URL+"/manifest.json", &logger.NullLogger{}, migration.TimestampFormat, HTTPOptions{
+			BearerToken: "secret",
+		})
+		require.NoError(t, err)
+
+		migrations, err := s.Select(context.Background(), Filter{})
+		require.NoError(t, err)
+		require.Len(t, migrations, 3)
+		assert.Equal(t, []string{"CREATE TABLE baz (id INT);"}, migrations[2].Migrate)
+		assert.Equal(t, "", authorization.Load())
+	})
+
+	t.Run("duplicate versions are rejected", func(t *testing.T) {
+		stub := newRemoteStub()
+		stub.manifest.Migrations = append(stub.manifest.Migrations, ManifestEntry{
+			Key:     "1596897167_create_baz_table",
+			Migrate: ManifestFile{URL: "files/1596897167_create_foo_table.migrate.sql"},
+		})
+		srv := httptest.NewServer(stub)
+		defer srv.Close()
+
+		s, err := NewHTTPSource(srv.URL+"/manifest.json", &logger.NullLogger{}, migration.TimestampFormat, HTTPOptions{
+			BearerToken: "secret",
+		})
+		require.NoError(t, err)
+
+		_, err = s.Select(context.Background(), Filter{Versions: []migration.Version{{Value: "1596897188"}}})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrDuplicateVersion))
+	})
+
+	t.Run("cached files are validated with etag", func(t *testing.T) {
+		stub := newRemoteStub()
+		srv := httptest.NewServer(stub)
+		defer srv.Close()
+
+		s, err := NewHTTPSource(srv.URL+"/manifest.json", &logger.NullLogger{}, migration.TimestampFormat, HTTPOptions{
+			BearerToken: "secret",
+			CacheDir:    t.TempDir(),
+		})
+		require.NoError(t, err)
+
+		first, err := s.Select(context.Background(), Filter{})
+		require.NoError(t, err)
+		assert.Equal(t, int32(4), atomic.LoadInt32(&stub.fullResponse))
+
+		second, err := s.Select(context.Background(), Filter{})
+		require.NoError(t, err)
+		assert.Equal(t, int32(4), atomic.LoadInt32(&stub.fullResponse), "not modified responses expected")
+		assert.Equal(t, first, second)
+	})
+}
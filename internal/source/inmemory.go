@@ -4,28 +4,112 @@ import (
 	"context"
 	"github.com/denismitr/tern/v2/migration"
 	"github.com/pkg/errors"
+	"sort"
+	"sync"
 )
 
 var ErrNoMigrations = errors.New("no migrations")
 
+// InMemorySource keeps migrations defined in code, new migrations created
+// with Create live only as long as the source itself
 type InMemorySource struct {
+	mu         sync.RWMutex
 	migrations migration.Migrations
 }
 
+var _ Source = (*InMemorySource)(nil)
+
 func (c *InMemorySource) Select(ctx context.Context, f Filter) (migration.Migrations, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
 	if c.migrations == nil {
 		return nil, ErrNoMigrations
 	}
 
-	return c.migrations, nil
+	result := make(migration.Migrations, len(c.migrations))
+	copy(result, c.migrations)
+
+	return filterMigrations(result, f), nil
+}
+
+// IsValid - in memory source is always valid
+func (c *InMemorySource) IsValid() bool {
+	return true
+}
+
+// AlreadyExists - checks if a migration with the dt version is already in the source
+func (c *InMemorySource) AlreadyExists(dt, name string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.indexOf(dt) != -1
+}
+
+// Create - adds a new empty migration to the source
+func (c *InMemorySource) Create(dt, name string, withRollback bool) (*migration.Migration, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := migration.CreateKeyFromVersionAndName(dt, name)
+	if c.indexOf(dt) != -1 {
+		return nil, errors.Wrapf(ErrMigrationAlreadyExists, "migration %s with key already exists", key)
+	}
+
+	m := &migration.Migration{
+		Key:          key,
+		Name:         name,
+		Version:      migration.Version{Value: dt},
+		Irreversible: !withRollback,
+	}
+
+	if err := migration.SetVersionFormat(m); err != nil {
+		return nil, err
+	}
+
+	c.migrations = append(c.migrations, m)
+	sort.Sort(c.migrations)
+
+	return m, nil
+}
+
+// String - in memory source has no location
+func (c *InMemorySource) String() string {
+	return "memory"
+}
+
+// indexOf - position of the migration with the same version, e.g. 0001 and 1 are the same
+func (c *InMemorySource) indexOf(version string) int {
+	for i := range c.migrations {
+		if migration.SameVersion(c.migrations[i].Version, migration.Version{Value: version}) {
+			return i
+		}
+	}
+
+	return -1
 }
 
+// NewInMemorySource creates a source of migrations created by the factories,
+// migrations are sorted by version and the same version can be used only once
 func NewInMemorySource(factories ...migration.Factory) (*InMemorySource, error) {
 	m, err := migration.NewMigrations(factories...)
 	if err != nil {
 		return nil, err
 	}
 
+	for i := range m {
+		for j := 0; j < i; j++ {
+			if migration.SameVersion(m[j].Version, m[i].Version) {
+				return nil, errors.Wrapf(
+					ErrDuplicateVersion,
+					"version %s is used by both %s and %s", m[i].Version.Value, m[j].Key, m[i].Key,
+				)
+			}
+		}
+	}
+
+	sort.Sort(m)
+
 	return &InMemorySource{
 		migrations: m,
 	}, nil
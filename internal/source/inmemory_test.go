@@ -0,0 +1,97 @@
+package source
+
+import (
+	"context"
+	"github.com/denismitr/tern/v2/migration"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestInMemorySource(t *testing.T) {
+	newSource := func(t *testing.T) *InMemorySource {
+		s, err := NewInMemorySource(
+			migration.New(migration.Timestamp("1597897177"), "Create baz table", []string{"CREATE TABLE baz (id INT);"}, nil),
+			migration.New(migration.Timestamp("1596897167"), "Create foo table", []string{"CREATE TABLE foo (id INT);"}, nil),
+			migration.New(migration.Timestamp("1596897188"), "Create bar table", []string{"CREATE TABLE bar (id INT);"}, nil),
+		)
+		require.NoError(t, err)
+		return s
+	}
+
+	t.Run("migrations are sorted by version", func(t *testing.T) {
+		migrations, err := newSource(t).Select(context.Background(), Filter{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"1596897167_create_foo_table",
+			"1596897188_create_bar_table",
+			"1597897177_create_baz_table",
+		}, migrations.Keys())
+	})
+
+	t.Run("version filter is applied", func(t *testing.T) {
+		migrations, err := newSource(t).Select(context.Background(), Filter{
+			Versions: []migration.Version{{Value: "1597897177"}, {Value: "1596897167"}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1596897167_create_foo_table", "1597897177_create_baz_table"}, migrations.Keys())
+	})
+
+	t.Run("duplicate versions are rejected", func(t *testing.T) {
+		_, err := NewInMemorySource(
+			migration.New(migration.Timestamp("1596897167"), "Create foo table", []string{"CREATE TABLE foo (id INT);"}, nil),
+			migration.New(migration.Timestamp("1596897167"), "Create bar table", []string{"CREATE TABLE bar (id INT);"}, nil),
+		)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrDuplicateVersion))
+	})
+
+	t.Run("same versions in different notation are rejected", func(t *testing.T) {
+		_, err := NewInMemorySource(
+			migration.New(migration.Number(1), "Create foo table", []string{"CREATE TABLE foo (id INT);"}, nil),
+			migration.New(func() (migration.Version, error) {
+				return migration.Version{Value: "1", Format: migration.NumberFormat}, nil
+			}, "Create bar table", []string{"CREATE TABLE bar (id INT);"}, nil),
+		)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrDuplicateVersion))
+	})
+
+	t.Run("migrations can be created", func(t *testing.T) {
+		s := newSource(t)
+		assert.True(t, s.IsValid())
+		assert.False(t, s.AlreadyExists("1596897177", "create_qux_table"))
+
+		m, err := s.Create("1596897177", "create_qux_table", true)
+		require.NoError(t, err)
+		assert.Equal(t, "1596897177_create_qux_table", m.Key)
+		assert.Equal(t, migration.TimestampFormat, m.Version.Format)
+		assert.True(t, s.AlreadyExists("1596897177", "create_qux_table"))
+
+		_, err = s.Create("1596897177", "create_qux_table", true)
+		assert.True(t, errors.Is(err, ErrMigrationAlreadyExists))
+
+		migrations, err := s.Select(context.Background(), Filter{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"1596897167_create_foo_table",
+			"1596897177_create_qux_table",
+			"1596897188_create_bar_table",
+			"1597897177_create_baz_table",
+		}, migrations.Keys())
+		assert.False(t, m.Irreversible)
+	})
+
+	t.Run("migration created without rollback is irreversible", func(t *testing.T) {
+		s, err := NewInMemorySource(
+			migration.New(migration.Number(1), "Create foo table", []string{"CREATE TABLE foo (id INT);"}, nil),
+		)
+		require.NoError(t, err)
+		assert.True(t, s.AlreadyExists("1", "create_foo_table"))
+
+		m, err := s.Create("2", "create_bar_table", false)
+		require.NoError(t, err)
+		assert.True(t, m.Irreversible)
+	})
+}
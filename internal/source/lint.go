@@ -0,0 +1,311 @@
+package source
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/denismitr/tern/v2/migration"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+type LintSeverity string
+
+const (
+	LintError   LintSeverity = "error"
+	LintWarning LintSeverity = "warning"
+)
+
+const (
+	LintRuleNaming           = "naming"
+	LintRuleStrayFile        = "stray-file"
+	LintRuleMissingMigrate   = "missing-migrate"
+	LintRuleMissingRollback  = "missing-rollback"
+	LintRuleDuplicateVersion = "duplicate-version"
+	LintRuleMixedFormats     = "mixed-formats"
+	LintRuleNameTooLong      = "name-too-long"
+	LintRuleEmptyScript      = "empty-script"
+	LintRuleTooManyFiles     = "too-many-files"
+	LintRuleInvalidDirective = "invalid-directive"
+)
+
+type (
+	// LintRules - limits the linter checks the migrations against
+	LintRules struct {
+		// MaxNameLength is the length of the name column of the migrations table,
+		// zero means the name length is not checked
+		MaxNameLength int
+	}
+
+	// LintIssue - a single problem found in the migrations folder
+	LintIssue struct {
+		Severity LintSeverity `json:"severity"`
+		Rule     string       `json:"rule"`
+		Path     string       `json:"path"`
+		Message  string       `json:"message"`
+	}
+
+	// LintReport - all problems found in the migrations folder
+	LintReport struct {
+		Location string      `json:"location"`
+		Issues   []LintIssue `json:"issues"`
+	}
+
+	// Linter - source able to check its migrations without running them
+	Linter interface {
+		Lint(ctx context.Context, rules LintRules) (*LintReport, error)
+	}
+
+	// lintFiles - files found for a single migration key in a single folder
+	lintFiles struct {
+		dir      string
+		key      string
+		migrate  string
+		rollback string
+		single   string
+	}
+)
+
+var _ Linter = (*FSSource)(nil)
+
+// HasErrors - true when at least one issue is an error, warnings alone are not
+func (r *LintReport) HasErrors() bool {
+	return r.Count(LintError) > 0
+}
+
+// Count - number of issues of the severity
+func (r *LintReport) Count(severity LintSeverity) int {
+	var n int
+	for i := range r.Issues {
+		if r.Issues[i].Severity == severity {
+			n++
+		}
+	}
+
+	return n
+}
+
+func (r *LintReport) add(severity LintSeverity, rule, p, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, LintIssue{
+		Severity: severity,
+		Rule:     rule,
+		Path:     p,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Lint checks every file of the source folder in one pass, problems found in the files
+// are returned in the report, error is returned only if the folder cannot be read
+func (s *FSSource) Lint(ctx context.Context, rules LintRules) (*LintReport, error) {
+	report := &LintReport{Location: s.location}
+	found := make(map[string]*lintFiles)
+
+	err := s.walk(func(dir string, d fs.DirEntry) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		s.lintFile(report, found, dir, d.Name())
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(found))
+	for k := range found {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	versions := make(map[string][]string)
+	formats := make(map[migration.VersionFormat][]string)
+
+	for _, k := range keys {
+		files := found[k]
+		s.lintMigration(report, files, rules)
+
+		version, err := s.extractVersionFromKey(files.key)
+		if err != nil {
+			continue
+		}
+
+		versions[version.Value] = append(versions[version.Value], path.Join(files.dir, files.key))
+
+		if v, err := migration.VersionFromString(version.Value); err == nil {
+			formats[v.Format] = append(formats[v.Format], path.Join(files.dir, files.key))
+		}
+	}
+
+	for version, paths := range versions {
+		if len(paths) > 1 {
+			report.add(LintError, LintRuleDuplicateVersion, paths[0],
+				"version %s is used by %s", version, strings.Join(paths, ", "))
+		}
+	}
+
+	if len(formats) > 1 {
+		var described []string
+		for f, paths := range formats {
+			described = append(described, fmt.Sprintf("%s (%d, e.g. %s)", f, len(paths), paths[0]))
+		}
+
+		sort.Strings(described)
+		report.add(LintError, LintRuleMixedFormats, s.dir,
+			"migrations use several version formats: %s", strings.Join(described, ", "))
+	}
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		return report.Issues[i].Path < report.Issues[j].Path
+	})
+
+	return report, nil
+}
+
+// lintFile - checks the file name and remembers the file under its migration key
+func (s *FSSource) lintFile(report *LintReport, found map[string]*lintFiles, dir, name string) {
+	p := path.Join(dir, name)
+
+	key, err := convertLocalFilePathToKey(name)
+	single := false
+	if err != nil {
+		key, err = convertSingleFilePathToKey(name)
+		if err != nil {
+			if path.Ext(name) == "."+defaultSqlExtension {
+				report.add(LintError, LintRuleNaming, p,
+					"file name should be <version>_<name>.migrate.sql, <version>_<name>.rollback.sql or <version>_<name>.sql")
+			} else {
+				report.add(LintError, LintRuleStrayFile, p, "file is not a migration and breaks reading the folder")
+			}
+
+			return
+		}
+
+		single = true
+	}
+
+	if _, err := s.extractVersionFromKey(key); err != nil {
+		report.add(LintError, LintRuleNaming, p, "version does not match %s version format", s.versionFormat)
+		return
+	}
+
+	id := path.Join(dir, key)
+	files, ok := found[id]
+	if !ok {
+		files = &lintFiles{dir: dir, key: key}
+		found[id] = files
+	}
+
+	switch {
+	case single:
+		files.single = p
+	case strings.HasSuffix(name, defaultRollbackFileFullExtension):
+		files.rollback = p
+	default:
+		files.migrate = p
+	}
+}
+
+// lintMigration - checks the files of a single migration
+func (s *FSSource) lintMigration(report *LintReport, files *lintFiles, rules LintRules) {
+	p := path.Join(files.dir, files.key)
+
+	if files.single != "" && (files.migrate != "" || files.rollback != "") {
+		report.add(LintError, LintRuleTooManyFiles, p, "migration has both single file and migrate/rollback files")
+		return
+	}
+
+	if name := s.extractNameFromKey(files.key); rules.MaxNameLength > 0 && utf8.RuneCountInString(name) > rules.MaxNameLength {
+		report.add(LintError, LintRuleNameTooLong, p,
+			"name is %d characters long, migrations table allows %d", utf8.RuneCountInString(name), rules.MaxNameLength)
+	}
+
+	if files.single != "" {
+		s.lintSingleFile(report, files.single)
+		return
+	}
+
+	if files.migrate == "" {
+		report.add(LintError, LintRuleMissingMigrate, p, "rollback file has no migrate file")
+		return
+	}
+
+	migrateContents, ok := s.lintRead(report, files.migrate)
+	if !ok {
+		return
+	}
+
+	d, err := parseDirectives(migrateContents)
+	if err != nil {
+		report.add(LintError, LintRuleInvalidDirective, files.migrate, "%s", err)
+	}
+
+	if isBlank(migrateContents) {
+		report.add(LintError, LintRuleEmptyScript, files.migrate, "migrate script is empty")
+	}
+
+	if files.rollback == "" {
+		if !d.irreversible {
+			report.add(LintWarning, LintRuleMissingRollback, p, "migration has no rollback file and is irreversible")
+		}
+
+		return
+	}
+
+	if rollbackContents, ok := s.lintRead(report, files.rollback); ok && isBlank(rollbackContents) {
+		report.add(LintWarning, LintRuleEmptyScript, files.rollback, "rollback script is empty")
+	}
+}
+
+func (s *FSSource) lintSingleFile(report *LintReport, p string) {
+	contents, ok := s.lintRead(report, p)
+	if !ok {
+		return
+	}
+
+	d, err := parseDirectives(contents)
+	if err != nil {
+		report.add(LintError, LintRuleInvalidDirective, p, "%s", err)
+	}
+
+	migrateContents, rollbackContents, err := splitSingleFile(contents)
+	if err != nil {
+		report.add(LintError, LintRuleMissingMigrate, p, "%s", err)
+		return
+	}
+
+	if isBlank(migrateContents) {
+		report.add(LintError, LintRuleEmptyScript, p, "migrate section is empty")
+	}
+
+	if isBlank(rollbackContents) && !d.irreversible {
+		report.add(LintWarning, LintRuleMissingRollback, p, "migration has no rollback section")
+	}
+}
+
+func (s *FSSource) lintRead(report *LintReport, p string) ([]byte, bool) {
+	contents, err := fs.ReadFile(s.fsys, p)
+	if err != nil {
+		report.add(LintError, LintRuleStrayFile, p, "could not read file: %s", err)
+		return nil, false
+	}
+
+	return contents, true
+}
+
+// isBlank - true when the script has nothing but whitespace and comments
+func isBlank(script []byte) bool {
+	for _, line := range bytes.Split(script, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 && !bytes.HasPrefix(line, []byte("--")) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,103 @@
+package source
+
+import (
+	"context"
+	"github.com/denismitr/tern/v2/internal/logger"
+	"github.com/denismitr/tern/v2/migration"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestFSSource_Lint(t *testing.T) {
+	lint := func(t *testing.T, fsys fstest.MapFS, vf migration.VersionFormat, opts ...Option) *LintReport {
+		t.Helper()
+		s, err := NewFSSource(fsys, "migrations", &logger.NullLogger{}, vf, opts...)
+		require.NoError(t, err)
+
+		report, err := s.Lint(context.Background(), LintRules{MaxNameLength: 20})
+		require.NoError(t, err)
+		return report
+	}
+
+	rulesOf := func(report *LintReport) []string {
+		var rules []string
+		for _, issue := range report.Issues {
+			rules = append(rules, string(issue.Severity)+":"+issue.Rule+":"+issue.Path)
+		}
+		return rules
+	}
+
+	t.Run("valid folder has no issues", func(t *testing.T) {
+		report := lint(t, fstest.MapFS{
+			"migrations/1596897167_create_foo_table.migrate.sql":  {Data: []byte("CREATE TABLE foo (id INT);")},
+			"migrations/1596897167_create_foo_table.rollback.sql": {Data: []byte("DROP TABLE foo;")},
+			"migrations/1596897188_create_bar_table.sql": {Data: []byte(
+				"-- +tern migrate\nCREATE TABLE bar (id INT);\n-- +tern rollback\nDROP TABLE bar;\n",
+			)},
+		}, migration.TimestampFormat)
+
+		assert.Empty(t, report.Issues)
+		assert.False(t, report.HasErrors())
+	})
+
+	t.Run("all problems are reported in one pass", func(t *testing.T) {
+		report := lint(t, fstest.MapFS{
+			"migrations/README.md":                                        {Data: []byte("# migrations")},
+			"migrations/create_foo_table.migrate.sql":                     {Data: []byte("CREATE TABLE foo (id INT);")},
+			"migrations/1596897167_create_foo_table.migrate.sql":          {Data: []byte("CREATE TABLE foo (id INT);")},
+			"migrations/1596897167_create_bar_table.migrate.sql":          {Data: []byte("CREATE TABLE bar (id INT);")},
+			"migrations/1596897167_create_bar_table.rollback.sql":         {Data: []byte("DROP TABLE bar;")},
+			"migrations/1596897188_create_a_very_long_table.migrate.sql":  {Data: []byte("-- nothing here\n")},
+			"migrations/1596897188_create_a_very_long_table.rollback.sql": {Data: []byte("")},
+			"migrations/1597897177_create_baz_table.rollback.sql":         {Data: []byte("DROP TABLE baz;")},
+			"migrations/20200818163457_create_qux_table.sql":              {Data: []byte("-- tern:unknown\n-- +tern migrate\nCREATE TABLE qux (id INT);\n")},
+		}, migration.AnyFormat)
+
+		assert.True(t, report.HasErrors())
+		assert.Equal(t, []string{
+			"error:mixed-formats:migrations",
+			"error:duplicate-version:migrations/1596897167_create_bar_table",
+			"warning:missing-rollback:migrations/1596897167_create_foo_table",
+			"error:name-too-long:migrations/1596897188_create_a_very_long_table",
+			"error:empty-script:migrations/1596897188_create_a_very_long_table.migrate.sql",
+			"warning:empty-script:migrations/1596897188_create_a_very_long_table.rollback.sql",
+			"error:missing-migrate:migrations/1597897177_create_baz_table",
+			"error:invalid-directive:migrations/20200818163457_create_qux_table.sql",
+			"warning:missing-rollback:migrations/20200818163457_create_qux_table.sql",
+			"error:stray-file:migrations/README.md",
+			"error:naming:migrations/create_foo_table.migrate.sql",
+		}, rulesOf(report))
+		assert.Equal(t, 8, report.Count(LintError))
+		assert.Equal(t, 3, report.Count(LintWarning))
+	})
+
+	t.Run("mixed migration layout for the same key is reported", func(t *testing.T) {
+		report := lint(t, fstest.MapFS{
+			"migrations/1596897167_create_foo_table.migrate.sql": {Data: []byte("CREATE TABLE foo (id INT);")},
+			"migrations/1596897167_create_foo_table.sql":         {Data: []byte("-- +tern migrate\nCREATE TABLE foo (id INT);\n")},
+		}, migration.TimestampFormat)
+
+		require.Len(t, report.Issues, 1)
+		assert.Equal(t, LintRuleTooManyFiles, report.Issues[0].Rule)
+	})
+
+	t.Run("duplicate versions in nested folders are reported", func(t *testing.T) {
+		report := lint(t, fstest.MapFS{
+			"migrations/2020/1596897167_create_foo_table.migrate.sql": {Data: []byte("CREATE TABLE foo (id INT);")},
+			"migrations/2021/1596897167_create_foo_table.migrate.sql": {Data: []byte("CREATE TABLE foo (id INT);")},
+		}, migration.TimestampFormat, WithRecursion())
+
+		var duplicates []LintIssue
+		for _, issue := range report.Issues {
+			if issue.Rule == LintRuleDuplicateVersion {
+				duplicates = append(duplicates, issue)
+			}
+		}
+
+		require.Len(t, duplicates, 1)
+		assert.True(t, strings.Contains(duplicates[0].Message, "migrations/2021/1596897167_create_foo_table"))
+	})
+}
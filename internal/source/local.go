@@ -1,17 +1,16 @@
 package source
 
 import (
-	"context"
+	"fmt"
 	"github.com/denismitr/tern/v2/internal/logger"
 	"github.com/denismitr/tern/v2/migration"
 	"github.com/pkg/errors"
-	"io/ioutil"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
-	"sort"
 	"strings"
-	"sync"
+	"time"
 )
 
 const DefaultMigrationsFolder = "./migrations"
@@ -24,13 +23,8 @@ const (
 	defaultMigrateFileFullExtension  = ".migrate.sql"
 	defaultRollbackFileFullExtension = ".rollback.sql"
 
-	timestampBasedVersionFormat = `^(?P<version>\d{9,11})(_\w+)?$`
-	timestampBasedNameFormat = `^\d{9,11}_(?P<name>\w+[\w_-]+)?$`
-	datetimeBasedVersionFormat = `^(?P<version>\d{14})(_\w+)?$`
-	datetimeBasedNameFormat = `^\d{14}_(?P<name>\w+[\w_-]+)?$`
-
-	anyBasedVersionFormat = `^(?P<version>\d{9,14})(_\w+)?$`
-	anyBasedNameFormat = `^\d{9,14}_(?P<name>\w+[\w_-]+)?$`
+	versionRegexFormat = `^(?P<version>%s)(_\w+)?$`
+	nameRegexFormat    = `^(?:%s)_(?P<name>\w+[\w_-]+)?$`
 )
 
 var ErrMigrationAlreadyExists = errors.New("migration already exists")
@@ -38,70 +32,91 @@ var ErrMigrationAlreadyExists = errors.New("migration already exists")
 type ParsingRules func() (*regexp.Regexp, *regexp.Regexp, error)
 
 type LocalFileSource struct {
-	folder        string
-	lg            logger.Logger
-	versionRegexp *regexp.Regexp
-	nameRegexp    *regexp.Regexp
-	versionFormat migration.VersionFormat
+	*FSSource
+	folder string
 }
 
 func (lfs *LocalFileSource) Create(dt, name string, withRollback bool) (*migration.Migration, error) {
 	key := migration.CreateKeyFromVersionAndName(dt, name)
-	migrateFilename := filepath.Join(lfs.folder, key + defaultMigrateFileFullExtension)
+	folder := lfs.createFolder(dt)
 
 	if lfs.AlreadyExists(dt, name) {
 		return nil, errors.Wrapf(ErrMigrationAlreadyExists, "migration %s with key already exists", key)
 	}
 
-	mf, err := os.Create(migrateFilename)
-	if err != nil {
-		return nil, errors.Wrapf(err, "could not create file [%s]", migrateFilename)
-	}
-
-	if cErr := mf.Close(); cErr != nil {
-		return nil, errors.Wrapf(cErr, "could not close file %s", migrateFilename)
+	if folder != lfs.folder {
+		if err := os.MkdirAll(folder, 0755); err != nil {
+			return nil, errors.Wrapf(err, "could not create folder [%s]", folder)
+		}
 	}
 
 	m := &migration.Migration{
-		Key: key,
+		Key:  key,
 		Name: name,
 		Version: migration.Version{
-			Value: dt,
+			Value:  dt,
 			Format: lfs.versionFormat,
 		},
 	}
 
-	if withRollback {
-		rollbackFilename := filepath.Join(lfs.folder, key + defaultRollbackFileFullExtension)
-		rf, err := os.Create(rollbackFilename)
-		if err != nil {
-			return nil, errors.Wrapf(err, "could not create file [%s]", rollbackFilename)
+	if lfs.opts.SingleFile {
+		filename := filepath.Join(folder, key+singleFileFullExtension)
+		if err := createFile(filename, singleFileStub(withRollback)); err != nil {
+			return nil, err
 		}
 
-		if cErr := rf.Close(); cErr != nil {
-			return nil, errors.Wrapf(cErr, "could not close file %s", rollbackFilename)
+		return m, nil
+	}
+
+	if err := createFile(filepath.Join(folder, key+defaultMigrateFileFullExtension), ""); err != nil {
+		return nil, err
+	}
+
+	if withRollback {
+		if err := createFile(filepath.Join(folder, key+defaultRollbackFileFullExtension), ""); err != nil {
+			return nil, err
 		}
 	}
 
 	return m, nil
 }
 
+func createFile(filename, contents string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return errors.Wrapf(err, "could not create file [%s]", filename)
+	}
+
+	if _, err := f.WriteString(contents); err != nil {
+		_ = f.Close()
+		return errors.Wrapf(err, "could not write to file [%s]", filename)
+	}
+
+	if cErr := f.Close(); cErr != nil {
+		return errors.Wrapf(cErr, "could not close file %s", filename)
+	}
+
+	return nil
+}
+
+// NewLocalFSSource creates a source that reads migrations from a folder
+// on the local file system and is able to create new migration files there
 func NewLocalFSSource(
 	folder string,
 	lg logger.Logger,
 	vf migration.VersionFormat,
+	opts ...Option,
 ) (*LocalFileSource, error) {
-	versionRegexp, nameRegexp, err := LocalFSParsingRules(vf)
+	fsSource, err := NewFSSource(os.DirFS(folder), ".", lg, vf, opts...)
 	if err != nil {
 		return nil, err
 	}
 
+	fsSource.location = folder
+
 	return &LocalFileSource{
-		folder: folder,
-		versionRegexp: versionRegexp,
-		nameRegexp: nameRegexp,
-		versionFormat: vf,
-		lg: lg,
+		FSSource: fsSource,
+		folder:   folder,
 	}, nil
 }
 
@@ -114,240 +129,115 @@ func (lfs *LocalFileSource) IsValid() bool {
 	return info.IsDir()
 }
 
+// AlreadyExists - checks the whole folder tree when recursion is enabled,
+// since a migration with the key may be placed in any of the sub folders
 func (lfs *LocalFileSource) AlreadyExists(dt, name string) bool {
 	key := migration.CreateKeyFromVersionAndName(dt, name)
-	filename := filepath.Join(lfs.folder, key +defaultMigrateFileFullExtension)
-	info, err := os.Stat(filename)
-	if os.IsNotExist(err) {
-		return false
-	}
-	return !info.IsDir()
-}
-
-func LocalFSParsingRules(vf migration.VersionFormat) (*regexp.Regexp, *regexp.Regexp, error) {
-	var versionRegexFormat string
-	var nameRegexFormat string
-
-	if vf == migration.TimestampFormat {
-		versionRegexFormat = timestampBasedVersionFormat
-		nameRegexFormat = timestampBasedNameFormat
-	} else if vf == migration.DatetimeFormat {
-		versionRegexFormat = datetimeBasedVersionFormat
-		nameRegexFormat = datetimeBasedNameFormat
-	} else {
-		versionRegexFormat = anyBasedVersionFormat
-		nameRegexFormat = anyBasedNameFormat
-	}
-
-	versionRegexp, err := regexp.Compile(versionRegexFormat)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	nameRegexp, err := regexp.Compile(nameRegexFormat)
-	if err != nil {
-		return nil, nil, err
-	}
-
-	return versionRegexp, nameRegexp, nil
-}
-
-func (lfs *LocalFileSource) Select(ctx context.Context, f Filter) (migration.Migrations, error) {
-	keys, err := lfs.getAllVersionsFromFolder(f)
-	if err != nil {
-		return nil, err
-	}
-
-	migrationsCh := make(chan *migration.Migration)
-	errorsCh := make(chan error)
-	var wg sync.WaitGroup
-
-	for k := range keys {
-		wg.Add(1)
-		go func(key string) {
-			defer wg.Done()
-			m, err := lfs.readOne(key)
-			if err != nil {
-				mErr := errors.Wrapf(err, "with key %s", key)
-				lfs.lg.Error(mErr)
-				errorsCh <- err
-			}
-
-			migrationsCh <- m
-		}(k)
-	}
+	extensions := []string{defaultMigrateFileFullExtension, singleFileFullExtension, goFileExtension}
 
-	go func() {
-		wg.Wait()
-		close(migrationsCh)
-		close(errorsCh)
-	}()
-
-	var result migration.Migrations
-
-	for {
-		select {
-		case <-ctx.Done():
-			return result, ctx.Err()
-		case m, ok := <-migrationsCh:
-			if ok {
-				if m == nil {
-					panic("how can a migration be null")
-				}
-
-				result = append(result, m)
-			} else {
-				sort.Sort(result)
-				return filterMigrations(result, f), nil
-			}
-		case err, ok := <-errorsCh:
-			if ok {
-				return nil, err
+	if !lfs.opts.Recursive {
+		for _, ext := range extensions {
+			info, err := os.Stat(filepath.Join(lfs.folder, key+ext))
+			if err == nil && !info.IsDir() {
+				return true
 			}
 		}
-	}
-}
-
-func (lfs *LocalFileSource) getAllVersionsFromFolder(f Filter) (map[string]int, error) {
-	var onlyVersions []string
-
-	for _, v := range f.Versions {
-		onlyVersions = append(onlyVersions, v.Value)
-	}
 
-	files, err := ioutil.ReadDir(lfs.folder)
-	if err != nil {
-		return nil, errors.Wrapf(err, "could not read keys from folder %s", lfs.folder)
+		return false
 	}
 
-	keys := make(map[string]int)
-
-	for i := range files {
-		if files[i].IsDir() {
-			continue
+	found := false
+	_ = filepath.WalkDir(lfs.folder, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || found {
+			return nil
 		}
 
-		key, err := convertLocalFilePathToKey(files[i].Name())
-		if err != nil {
-			return nil, errors.Wrapf(err, "file %s is not a valid migration name", files[i].Name()) // fixme
+		if d.IsDir() {
+			return nil
 		}
 
-		if len(onlyVersions) > 0 && !keyContainsOfVersions(key, onlyVersions) {
-			continue
-		}
-
-		if count, ok := keys[key]; ok {
-			keys[key] = count + 1
-			if keys[key] > 2 {
-				return nil, errors.Wrapf(ErrTooManyFilesForKey, "%s", key)
+		for _, ext := range extensions {
+			if d.Name() == key+ext {
+				found = true
+				return filepath.SkipDir
 			}
-		} else {
-			keys[key] = 1
 		}
-	}
 
-	return keys, nil
-}
+		return nil
+	})
 
-func (lfs *LocalFileSource) readOne(key string) (*migration.Migration, error) {
-	up := filepath.Join(lfs.folder, key+defaultMigrateFileFullExtension)
-	down := filepath.Join(lfs.folder, key+defaultRollbackFileFullExtension)
+	return found
+}
 
-	fUp, err := os.Open(up)
-	if err != nil {
-		return nil, err
+// createFolder - resolves the folder where a migration with given version
+// should be created according to the create path pattern
+func (lfs *LocalFileSource) createFolder(dt string) string {
+	if lfs.opts.CreatePath == "" {
+		return lfs.folder
 	}
 
-	defer func() {
-		if cErr := fUp.Close(); cErr != nil {
-			lfs.lg.Error(cErr)
-		}
-	}()
-
-	fDown, err := os.Open(down)
+	t, err := versionTime(dt)
 	if err != nil {
-		// rollback version may not be present
-		if !errors.Is(err, os.ErrNotExist) {
-			return nil, err
-		}
-	} else {
-		defer func() {
-			if cErr := fDown.Close(); cErr != nil {
-				lfs.lg.Error(cErr)
-			}
-		}()
+		t = time.Now()
 	}
 
-	migrateContents, err := ioutil.ReadAll(fUp);
-	if err != nil {
-		return nil, err
-	}
+	return filepath.Join(lfs.folder, filepath.FromSlash(expandPathPattern(lfs.opts.CreatePath, t)))
+}
 
-	var rollbackContents []byte
-	if fDown != nil {
-		if contents, err := ioutil.ReadAll(fDown); err != nil {
-			return nil, err
-		} else {
-			rollbackContents = contents
-		}
+// versionTime - extracts the date from timestamp or datetime based version
+func versionTime(dt string) (time.Time, error) {
+	v, err := migration.VersionFromString(dt)
+	if err != nil {
+		return time.Time{}, err
 	}
 
-	return lfs.createMigration(key, migrateContents, rollbackContents)
+	return v.Time()
 }
 
-func (lfs *LocalFileSource) createMigration(
-	key string,
-	migrateContents,
-	rollbackContents []byte,
-) (*migration.Migration, error) {
-	name := lfs.extractNameFromKey(key)
-	version, err := lfs.extractVersionFromKey(key)
-	if err != nil {
-		return nil, err
+// LocalFSParsingRules - regular expressions extracting version and name from migration keys
+// of the version format, empty format is the same as any format
+func LocalFSParsingRules(vf migration.VersionFormat) (*regexp.Regexp, *regexp.Regexp, error) {
+	if vf == "" {
+		vf = migration.AnyFormat
 	}
 
-	factory := migration.NewMigrationFromFile(key, name, version, string(migrateContents), string(rollbackContents))
-
-	m, err := factory()
+	f, err := migration.LookupFormat(vf)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return m, err
-}
 
-func (lfs *LocalFileSource) extractVersionFromKey(key string) (migration.Version, error) {
-	var result migration.Version
-	matches := lfs.versionRegexp.FindStringSubmatch(key)
-	if len(matches) < 2 {
-		return result, ErrInvalidTimestamp
+	versionRegexp, err := regexp.Compile(fmt.Sprintf(versionRegexFormat, f.Pattern()))
+	if err != nil {
+		return nil, nil, err
 	}
 
-	result.Value = matches[1]
-
-	return result, nil
-}
-
-func (lfs *LocalFileSource) extractNameFromKey(key string) string {
-	matches := lfs.nameRegexp.FindStringSubmatch(key)
-	if len(matches) < 2 {
-		return ""
+	nameRegexp, err := regexp.Compile(fmt.Sprintf(nameRegexFormat, f.Pattern()))
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return ucFirst(strings.Replace(matches[1], "_", " ", -1))
+	return versionRegexp, nameRegexp, nil
 }
 
+// convertLocalFilePathToKey - extracts key from <version>_<name>.migrate.sql
+// and <version>_<name>.rollback.sql file names, keys may contain dots e.g. 1.4.2_add_index
 func convertLocalFilePathToKey(path string) (string, error) {
 	_, name := filepath.Split(path)
-	base := filepath.Base(name)
-	segments := strings.Split(base, ".")
 
-	if len(segments) != 3 {
-		return "", ErrNotAMigrationFile
+	for _, ext := range []string{defaultMigrateFileFullExtension, defaultRollbackFileFullExtension} {
+		if strings.HasSuffix(name, ext) {
+			return validKey(strings.TrimSuffix(name, ext))
+		}
 	}
 
-	if segments[2] != defaultSqlExtension || !(segments[1] == migrateFileSuffix || segments[1] == rollbackFileSuffix) {
+	return "", ErrNotAMigrationFile
+}
+
+// validKey - hidden files and files without a key are not migrations
+func validKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, ".") {
 		return "", ErrNotAMigrationFile
 	}
 
-	return segments[0], nil
-}
\ No newline at end of file
+	return key, nil
+}
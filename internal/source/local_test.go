@@ -46,7 +46,7 @@ func Test_ConvertLocalFolder(t *testing.T) {
 	assert.NoError(t, err)
 
 	t.Run("all migrations can be read from local folder", func(t *testing.T) {
-		ctx, cancel := context.WithTimeout(context.Background(), 1 * time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 		defer cancel()
 
 		migrations, err := c.Select(ctx, Filter{})
@@ -74,7 +74,7 @@ func Test_ConvertLocalFolder(t *testing.T) {
 	})
 
 	t.Run("specified migrations can be read from local folder", func(t *testing.T) {
-		ctx, cancel := context.WithTimeout(context.Background(), 1 * time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 		defer cancel()
 
 		migrations, err := c.Select(
@@ -114,7 +114,7 @@ func Test_VersionCanBeExtractedFromKey(t *testing.T) {
 	}
 
 	invalid := []struct {
-		in string
+		in  string
 		err error
 	}{
 		{in: "M1596897167_create_foo_table", err: ErrInvalidTimestamp},
@@ -188,8 +188,8 @@ func Test_MigrationNameCanBeExtractedFromKey(t *testing.T) {
 func Test_ConvertPathToKey(t *testing.T) {
 	t.Parallel()
 
-	valid := []struct{
-		in string
+	valid := []struct {
+		in  string
 		out string
 	}{
 		{in: "/home/vagrant/code/migrations/mysql/1596897188_create_bar_table.migrate.sql", out: "1596897188_create_bar_table"},
@@ -198,8 +198,8 @@ func Test_ConvertPathToKey(t *testing.T) {
 		{in: "./1596897188_create_foo_table.rollback.sql", out: "1596897188_create_foo_table"},
 	}
 
-	invalid := []struct{
-		in string
+	invalid := []struct {
+		in  string
 		err error
 	}{
 		{in: "/home/vagrant/code/migrations/mysql/1596897188_create_bar_table.sql", err: ErrNotAMigrationFile},
@@ -357,3 +357,165 @@ func TestLocalFileSource_Create(t *testing.T) {
 		}
 	})
 }
+
+func TestLocalFileSource_CreateInSubPath(t *testing.T) {
+	t.Run("datetime version is placed by year and month", func(t *testing.T) {
+		folder := t.TempDir()
+
+		c, err := NewLocalFSSource(folder, &logger.NullLogger{}, migration.DatetimeFormat, WithRecursion(), WithCreatePath("{year}/{month}"))
+		require.NoError(t, err)
+
+		m, err := c.Create("20210618163457", "foo_bar", true)
+		require.NoError(t, err)
+		require.NotNil(t, m)
+
+		require.FileExists(t, filepath.Join(folder, "2021", "06", "20210618163457_foo_bar.migrate.sql"))
+		require.FileExists(t, filepath.Join(folder, "2021", "06", "20210618163457_foo_bar.rollback.sql"))
+		assert.True(t, c.AlreadyExists("20210618163457", "foo_bar"))
+
+		migrations, err := c.Select(context.Background(), Filter{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"20210618163457_foo_bar"}, migrations.Keys())
+	})
+
+	t.Run("timestamp version is placed by year and month in UTC", func(t *testing.T) {
+		folder := t.TempDir()
+
+		c, err := NewLocalFSSource(folder, &logger.NullLogger{}, migration.TimestampFormat, WithCreatePath("{year}/{month}/{day}"))
+		require.NoError(t, err)
+
+		_, err = c.Create("1596897188", "foo_bar", false)
+		require.NoError(t, err)
+
+		require.FileExists(t, filepath.Join(folder, "2020", "08", "08", "1596897188_foo_bar.migrate.sql"))
+
+		// create path implies recursion, otherwise the created migration would never be selected
+		migrations, err := c.Select(context.Background(), Filter{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1596897188_foo_bar"}, migrations.Keys())
+	})
+
+	t.Run("existing migration is found in any sub folder", func(t *testing.T) {
+		folder := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(folder, "legacy"), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(folder, "legacy", "1596897188_foo_bar.migrate.sql"), nil, 0644))
+
+		c, err := NewLocalFSSource(folder, &logger.NullLogger{}, migration.TimestampFormat, WithCreatePath("{year}/{month}"))
+		require.NoError(t, err)
+
+		assert.True(t, c.AlreadyExists("1596897188", "foo_bar"))
+		assert.False(t, c.AlreadyExists("1596897189", "foo_bar"))
+
+		_, err = c.Create("1596897188", "foo_bar", false)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrMigrationAlreadyExists))
+		assert.NoFileExists(t, filepath.Join(folder, "2020", "08", "1596897188_foo_bar.migrate.sql"))
+	})
+
+	t.Run("static sub path is used as is", func(t *testing.T) {
+		folder := t.TempDir()
+
+		c, err := NewLocalFSSource(folder, &logger.NullLogger{}, migration.TimestampFormat, WithCreatePath("billing"))
+		require.NoError(t, err)
+
+		_, err = c.Create("1596897188", "foo_bar", false)
+		require.NoError(t, err)
+
+		require.FileExists(t, filepath.Join(folder, "billing", "1596897188_foo_bar.migrate.sql"))
+	})
+}
+
+func TestLocalFileSource_CreateSingleFile(t *testing.T) {
+	t.Run("with rollback section", func(t *testing.T) {
+		folder := t.TempDir()
+
+		c, err := NewLocalFSSource(folder, &logger.NullLogger{}, migration.TimestampFormat, WithSingleFile())
+		require.NoError(t, err)
+
+		_, err = c.Create("1596897188", "foo_bar", true)
+		require.NoError(t, err)
+
+		filename := filepath.Join(folder, "1596897188_foo_bar.sql")
+		require.FileExists(t, filename)
+		assert.NoFileExists(t, filepath.Join(folder, "1596897188_foo_bar.migrate.sql"))
+		assert.True(t, c.AlreadyExists("1596897188", "foo_bar"))
+
+		contents, err := os.ReadFile(filename)
+		require.NoError(t, err)
+		assert.Equal(t, "-- +tern migrate\n\n-- +tern rollback\n", string(contents))
+	})
+
+	t.Run("without rollback section", func(t *testing.T) {
+		folder := t.TempDir()
+
+		c, err := NewLocalFSSource(folder, &logger.NullLogger{}, migration.TimestampFormat, WithSingleFile())
+		require.NoError(t, err)
+
+		_, err = c.Create("1596897188", "foo_bar", false)
+		require.NoError(t, err)
+
+		contents, err := os.ReadFile(filepath.Join(folder, "1596897188_foo_bar.sql"))
+		require.NoError(t, err)
+		assert.Equal(t, "-- +tern migrate\n", string(contents))
+	})
+}
+
+func TestLocalFileSource_CreateGo(t *testing.T) {
+	t.Run("with rollback function", func(t *testing.T) {
+		folder := filepath.Join(t.TempDir(), "db-migrations")
+		require.NoError(t, os.Mkdir(folder, 0755))
+
+		c, err := NewLocalFSSource(folder, &logger.NullLogger{}, migration.TimestampFormat)
+		require.NoError(t, err)
+
+		m, err := c.CreateGo("1596897188", "foo_bar", true)
+		require.NoError(t, err)
+		assert.Equal(t, "1596897188_foo_bar", m.Key)
+		assert.True(t, c.AlreadyExists("1596897188", "foo_bar"))
+
+		contents, err := os.ReadFile(filepath.Join(folder, "1596897188_foo_bar.go"))
+		require.NoError(t, err)
+		assert.Contains(t, string(contents), "package db_migrations\n")
+		assert.Contains(t, string(contents), `migration.Register("1596897188", "foo_bar", migrate1596897188FooBar, rollback1596897188FooBar)`)
+		assert.Contains(t, string(contents), "func rollback1596897188FooBar(ctx context.Context, ex migration.Executor) error {")
+	})
+
+	t.Run("without rollback function", func(t *testing.T) {
+		folder := t.TempDir()
+
+		c, err := NewLocalFSSource(folder, &logger.NullLogger{}, migration.TimestampFormat)
+		require.NoError(t, err)
+
+		_, err = c.CreateGo("1596897188", "foo_bar", false)
+		require.NoError(t, err)
+
+		contents, err := os.ReadFile(filepath.Join(folder, "1596897188_foo_bar.go"))
+		require.NoError(t, err)
+		assert.Contains(t, string(contents), `migration.Register("1596897188", "foo_bar", migrate1596897188FooBar, nil)`)
+		assert.NotContains(t, string(contents), "rollback1596897188FooBar")
+	})
+
+	t.Run("go files are skipped when migrations are selected", func(t *testing.T) {
+		folder := t.TempDir()
+
+		c, err := NewLocalFSSource(folder, &logger.NullLogger{}, migration.TimestampFormat)
+		require.NoError(t, err)
+
+		_, err = c.CreateGo("1596897188", "foo_bar", true)
+		require.NoError(t, err)
+		_, err = c.Create("1596897199", "baz", true)
+		require.NoError(t, err)
+
+		migrations, err := c.Select(context.Background(), Filter{})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1596897199_baz"}, migrations.Keys())
+	})
+
+	t.Run("test file names are rejected", func(t *testing.T) {
+		c, err := NewLocalFSSource(t.TempDir(), &logger.NullLogger{}, migration.TimestampFormat)
+		require.NoError(t, err)
+
+		_, err = c.CreateGo("1596897188", "foo_test", true)
+		assert.True(t, errors.Is(err, ErrInvalidGoMigrationName))
+	})
+}
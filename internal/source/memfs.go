@@ -0,0 +1,122 @@
+package source
+
+import (
+	"bytes"
+	"io/fs"
+	"path"
+	"sort"
+	"time"
+)
+
+// memFS - read only in memory file system, that is used to hold
+// the contents of archives which do not implement fs.FS themselves
+type memFS struct {
+	files map[string][]byte
+	dirs  map[string]bool
+}
+
+var _ fs.ReadDirFS = (*memFS)(nil)
+
+func newMemFS() *memFS {
+	return &memFS{
+		files: make(map[string][]byte),
+		dirs:  map[string]bool{".": true},
+	}
+}
+
+func (m *memFS) add(name string, data []byte) {
+	name = path.Clean(name)
+	m.files[name] = data
+
+	for dir := path.Dir(name); dir != "." && !m.dirs[dir]; dir = path.Dir(dir) {
+		m.dirs[dir] = true
+	}
+}
+
+func (m *memFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	if data, ok := m.files[name]; ok {
+		return &memFile{
+			memEntry: memEntry{name: path.Base(name), size: int64(len(data))},
+			r:        bytes.NewReader(data),
+		}, nil
+	}
+
+	if m.dirs[name] {
+		return &memFile{memEntry: memEntry{name: path.Base(name), dir: true}}, nil
+	}
+
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (m *memFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if !m.dirs[name] {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	var entries []fs.DirEntry
+	for dir := range m.dirs {
+		if dir != "." && path.Dir(dir) == name {
+			entries = append(entries, memEntry{name: path.Base(dir), dir: true})
+		}
+	}
+
+	for file, data := range m.files {
+		if path.Dir(file) == name {
+			entries = append(entries, memEntry{name: path.Base(file), size: int64(len(data))})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name() < entries[j].Name()
+	})
+
+	return entries, nil
+}
+
+// memEntry - implements both fs.FileInfo and fs.DirEntry
+type memEntry struct {
+	name string
+	size int64
+	dir  bool
+}
+
+func (e memEntry) Name() string               { return e.name }
+func (e memEntry) Size() int64                { return e.size }
+func (e memEntry) IsDir() bool                { return e.dir }
+func (e memEntry) Type() fs.FileMode          { return e.Mode().Type() }
+func (e memEntry) Info() (fs.FileInfo, error) { return e, nil }
+func (e memEntry) ModTime() time.Time         { return time.Time{} }
+func (e memEntry) Sys() interface{}           { return nil }
+
+func (e memEntry) Mode() fs.FileMode {
+	if e.dir {
+		return fs.ModeDir | 0555
+	}
+
+	return 0444
+}
+
+type memFile struct {
+	memEntry
+	r *bytes.Reader
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) {
+	return f.memEntry, nil
+}
+
+func (f *memFile) Read(b []byte) (int, error) {
+	if f.r == nil {
+		return 0, &fs.PathError{Op: "read", Path: f.name, Err: fs.ErrInvalid}
+	}
+
+	return f.r.Read(b)
+}
+
+func (f *memFile) Close() error {
+	return nil
+}
@@ -0,0 +1,88 @@
+package source
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	yearPlaceholder  = "{year}"
+	monthPlaceholder = "{month}"
+	dayPlaceholder   = "{day}"
+)
+
+type (
+	// Options customize the way migration files are discovered and created
+	Options struct {
+		// Recursive enables walking of the sub folders of the migrations folder
+		Recursive bool
+
+		// CreatePath is a sub path pattern relative to the migrations folder
+		// where new migrations are created, e.g. {year}/{month} or core,
+		// it turns on Recursive since the sub folders must be read as well
+		CreatePath string
+
+		// SingleFile makes new migrations to be created as a single <version>_<name>.sql
+		// file with migrate and rollback sections instead of two separate files
+		SingleFile bool
+	}
+
+	Option func(o *Options)
+)
+
+// WithRecursion enables reading of migrations from the nested sub folders
+func WithRecursion() Option {
+	return func(o *Options) {
+		o.Recursive = true
+	}
+}
+
+// WithCreatePath sets the sub path pattern for newly created migrations,
+// {year}, {month} and {day} placeholders are replaced with the values taken from
+// the migration version or the current date if version does not contain it,
+// recursion is enabled so that the migrations created there are selected
+func WithCreatePath(pattern string) Option {
+	return func(o *Options) {
+		o.CreatePath = pattern
+	}
+}
+
+// WithSingleFile makes new migrations to be created in a single file layout
+func WithSingleFile() Option {
+	return func(o *Options) {
+		o.SingleFile = true
+	}
+}
+
+func newOptions(opts []Option) Options {
+	var o Options
+	for _, fn := range opts {
+		fn(&o)
+	}
+
+	if o.CreatePath != "" {
+		o.Recursive = true
+	}
+
+	return o
+}
+
+func expandPathPattern(pattern string, t time.Time) string {
+	r := strings.NewReplacer(
+		yearPlaceholder, strconv.Itoa(t.Year()),
+		monthPlaceholder, leftPadTwoDigits(int(t.Month())),
+		dayPlaceholder, leftPadTwoDigits(t.Day()),
+	)
+
+	return r.Replace(pattern)
+}
+
+func leftPadTwoDigits(n int) string {
+	s := strconv.Itoa(n)
+	if len(s) == 1 {
+		return "0" + s
+	}
+
+	return s
+}
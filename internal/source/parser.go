@@ -0,0 +1,96 @@
+package source
+
+import (
+	"github.com/denismitr/tern/v2/migration"
+	"github.com/pkg/errors"
+	"regexp"
+	"strings"
+)
+
+// keyParser - extracts migration version and name from the migration key
+// and creates migrations from the migration files contents
+type keyParser struct {
+	versionRegexp *regexp.Regexp
+	nameRegexp    *regexp.Regexp
+	versionFormat migration.VersionFormat
+	format        migration.Format
+}
+
+func newKeyParser(vf migration.VersionFormat) (keyParser, error) {
+	versionRegexp, nameRegexp, err := LocalFSParsingRules(vf)
+	if err != nil {
+		return keyParser{}, err
+	}
+
+	if vf == "" {
+		vf = migration.AnyFormat
+	}
+
+	format, err := migration.LookupFormat(vf)
+	if err != nil {
+		return keyParser{}, err
+	}
+
+	return keyParser{
+		versionRegexp: versionRegexp,
+		nameRegexp:    nameRegexp,
+		versionFormat: vf,
+		format:        format,
+	}, nil
+}
+
+// createMigration - creates a migration from the file contents, directives
+// are read from the header of the file, nil rollback contents mean the migration
+// has no rollback at all and makes it irreversible
+func (p keyParser) createMigration(
+	key string,
+	header,
+	migrateContents,
+	rollbackContents []byte,
+) (*migration.Migration, error) {
+	name := p.extractNameFromKey(key)
+	version, err := p.extractVersionFromKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := parseDirectives(header)
+	if err != nil {
+		return nil, err
+	}
+
+	factory := migration.NewMigrationFromFile(key, name, version, string(migrateContents), string(rollbackContents))
+
+	m, err := factory()
+	if err != nil {
+		return nil, err
+	}
+
+	d.applyTo(m)
+	m.Irreversible = m.Irreversible || rollbackContents == nil
+
+	return m, err
+}
+
+func (p keyParser) extractVersionFromKey(key string) (migration.Version, error) {
+	matches := p.versionRegexp.FindStringSubmatch(key)
+	if len(matches) < 2 {
+		return migration.Version{}, ErrInvalidTimestamp
+	}
+
+	result, err := p.format.Parse(matches[1])
+	if err != nil {
+		return migration.Version{}, errors.Wrapf(ErrInvalidTimestamp, "%s", err)
+	}
+
+	return result, nil
+}
+
+func (p keyParser) extractNameFromKey(key string) string {
+	matches := p.nameRegexp.FindStringSubmatch(key)
+	if len(matches) < 2 {
+		return ""
+	}
+
+	return ucFirst(strings.Replace(matches[1], "_", " ", -1))
+}
@@ -0,0 +1,21 @@
+package source
+
+import (
+	"context"
+	"github.com/denismitr/tern/v2/migration"
+)
+
+// RegistrySource selects Go migrations added to the registry with migration.Register
+type RegistrySource struct{}
+
+var _ Selector = RegistrySource{}
+
+// Select - registered migrations of the filter versions
+func (RegistrySource) Select(_ context.Context, f Filter) (migration.Migrations, error) {
+	return filterMigrations(migration.Registered(), f), nil
+}
+
+// String - Go migrations have no location
+func (RegistrySource) String() string {
+	return "registry"
+}
@@ -0,0 +1,90 @@
+package source
+
+import (
+	"context"
+	"github.com/pkg/errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+var ErrRenameTargetExists = errors.New("migration file with the new key already exists")
+
+type (
+	// VersionRenamer - source able to change keys of its migrations in place
+	VersionRenamer interface {
+		// RenameVersions - renames the files of migrations, renames map old keys to new ones,
+		// either all files are renamed or none of them
+		RenameVersions(ctx context.Context, renames map[string]string) error
+	}
+
+	fileRename struct {
+		from, to string
+	}
+)
+
+var _ VersionRenamer = (*LocalFileSource)(nil)
+
+// RenameVersions - renames migrate, rollback and single files of the migrations,
+// files renamed before a failure are renamed back
+func (lfs *LocalFileSource) RenameVersions(ctx context.Context, renames map[string]string) error {
+	var planned []fileRename
+
+	err := lfs.walk(func(dir string, d fs.DirEntry) error {
+		key, err := convertLocalFilePathToKey(d.Name())
+		if err != nil {
+			key, err = convertSingleFilePathToKey(d.Name())
+			if err != nil {
+				return nil
+			}
+		}
+
+		newKey, ok := renames[key]
+		if !ok {
+			return nil
+		}
+
+		folder := filepath.Join(lfs.folder, filepath.FromSlash(dir))
+		planned = append(planned, fileRename{
+			from: filepath.Join(folder, d.Name()),
+			to:   filepath.Join(folder, newKey+d.Name()[len(key):]),
+		})
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for _, r := range planned {
+		if _, err := os.Stat(r.to); err == nil {
+			return errors.Wrapf(ErrRenameTargetExists, "%s", r.to)
+		}
+	}
+
+	for i, r := range planned {
+		if err := ctx.Err(); err != nil {
+			return revertRenames(planned[:i], err)
+		}
+
+		if err := os.Rename(r.from, r.to); err != nil {
+			return revertRenames(planned[:i], errors.Wrapf(err, "could not rename %s to %s", r.from, r.to))
+		}
+
+		lfs.lg.Debugf("renamed %s to %s", r.from, r.to)
+	}
+
+	return nil
+}
+
+// revertRenames - renames the files back after the cause error
+func revertRenames(done []fileRename, cause error) error {
+	for i := len(done) - 1; i >= 0; i-- {
+		if err := os.Rename(done[i].to, done[i].from); err != nil {
+			return errors.Wrapf(cause, "could not rename %s back to %s: %s", done[i].to, done[i].from, err)
+		}
+	}
+
+	return cause
+}
@@ -0,0 +1,120 @@
+package source
+
+import (
+	"context"
+	"github.com/denismitr/tern/v2/internal/logger"
+	"github.com/denismitr/tern/v2/migration"
+	"github.com/pkg/errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	DefaultSeedsFolder = "./seeds"
+
+	seedFileFullExtension = ".seed.sql"
+)
+
+var ErrNotASeedFile = errors.New("not a seed file")
+
+// SeedSource reads seeds, <version>_<name>.seed.sql files that are only ever applied,
+// directives of the file header select environments and labels of the seed
+//
+//	-- tern:env=local,staging
+//	-- tern:labels=demo
+type SeedSource struct {
+	*FSSource
+}
+
+var _ Selector = (*SeedSource)(nil)
+
+// NewSeedSource creates a seeds source reading seed files from the dir folder of the fsys file system
+func NewSeedSource(
+	fsys fs.FS,
+	dir string,
+	lg logger.Logger,
+	vf migration.VersionFormat,
+	opts ...Option,
+) (*SeedSource, error) {
+	fsSource, err := NewFSSource(fsys, dir, lg, vf, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SeedSource{FSSource: fsSource}, nil
+}
+
+// NewLocalSeedSource creates a seeds source reading seed files from a local folder
+func NewLocalSeedSource(
+	folder string,
+	lg logger.Logger,
+	vf migration.VersionFormat,
+	opts ...Option,
+) (*SeedSource, error) {
+	s, err := NewSeedSource(os.DirFS(folder), ".", lg, vf, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	s.location = folder
+
+	return s, nil
+}
+
+// Select - seeds of the filter versions ordered by version, seeds have no rollback
+func (s *SeedSource) Select(ctx context.Context, f Filter) (migration.Migrations, error) {
+	var result migration.Migrations
+	versions := make(map[string]string)
+
+	err := s.walk(func(dir string, d fs.DirEntry) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		p := path.Join(dir, d.Name())
+		key, err := convertSeedFilePathToKey(d.Name())
+		if err != nil {
+			return errors.Wrapf(err, "file %s is not a valid seed name", p)
+		}
+
+		contents, err := fs.ReadFile(s.fsys, p)
+		if err != nil {
+			return err
+		}
+
+		m, err := s.createMigration(key, contents, contents, nil)
+		if err != nil {
+			return errors.Wrapf(err, "with key %s", key)
+		}
+
+		if other, ok := versions[m.Version.Value]; ok {
+			return errors.Wrapf(ErrDuplicateVersion, "version %s found in %s and %s", m.Version.Value, other, p)
+		}
+
+		versions[m.Version.Value] = p
+		result = append(result, m)
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Sort(result)
+
+	return filterMigrations(result, f), nil
+}
+
+func convertSeedFilePathToKey(p string) (string, error) {
+	_, name := filepath.Split(p)
+	if !strings.HasSuffix(name, seedFileFullExtension) {
+		return "", ErrNotASeedFile
+	}
+
+	return validKey(strings.TrimSuffix(name, seedFileFullExtension))
+}
@@ -0,0 +1,65 @@
+package source
+
+import (
+	"context"
+	"github.com/denismitr/tern/v2/internal/logger"
+	"github.com/denismitr/tern/v2/migration"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSeedSource_Select(t *testing.T) {
+	t.Parallel()
+
+	t.Run("seeds are read with their directives and ordered by version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"seeds/0002_demo_users.seed.sql": {Data: []byte(
+				"-- tern:env=local,staging\n-- tern:labels=demo\nINSERT INTO users (name) VALUES ('demo');",
+			)},
+			"seeds/0001_roles.seed.sql": {Data: []byte("INSERT INTO roles (name) VALUES ('admin');")},
+		}
+
+		s, err := NewSeedSource(fsys, "seeds", &logger.NullLogger{}, migration.NumberFormat)
+		require.NoError(t, err)
+
+		seeds, err := s.Select(context.Background(), Filter{})
+		require.NoError(t, err)
+		require.Len(t, seeds, 2)
+
+		assert.Equal(t, []string{"0001_roles", "0002_demo_users"}, seeds.Keys())
+		assert.Equal(t, []string{"local", "staging"}, seeds[1].Environments)
+		assert.Equal(t, []string{"demo"}, seeds[1].Labels)
+		assert.Len(t, seeds[0].Migrate, 1)
+		assert.True(t, seeds[0].Irreversible)
+	})
+
+	t.Run("migration files are not seeds", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"seeds/0001_roles.migrate.sql": {Data: []byte("INSERT INTO roles (name) VALUES ('admin');")},
+		}
+
+		s, err := NewSeedSource(fsys, "seeds", &logger.NullLogger{}, migration.NumberFormat)
+		require.NoError(t, err)
+
+		_, err = s.Select(context.Background(), Filter{})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrNotASeedFile))
+	})
+
+	t.Run("seeds with the same version", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"seeds/0001_roles.seed.sql": {Data: []byte("INSERT INTO roles (name) VALUES ('admin');")},
+			"seeds/0001_users.seed.sql": {Data: []byte("INSERT INTO users (name) VALUES ('admin');")},
+		}
+
+		s, err := NewSeedSource(fsys, "seeds", &logger.NullLogger{}, migration.NumberFormat)
+		require.NoError(t, err)
+
+		_, err = s.Select(context.Background(), Filter{})
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrDuplicateVersion))
+	})
+}
@@ -28,8 +28,21 @@ type Source interface {
 	Create(dt, name string, withRollback bool) (*migration.Migration, error)
 }
 
+// filterMigrations - leaves only the migrations of the filter versions,
+// all migrations are kept when the filter has no versions
 func filterMigrations(m migration.Migrations, f Filter) migration.Migrations {
-	return m
+	if len(f.Versions) == 0 {
+		return m
+	}
+
+	var result migration.Migrations
+	for i := range m {
+		if migration.InVersions(m[i].Version, f.Versions) {
+			result = append(result, m[i])
+		}
+	}
+
+	return result
 }
 
 func ucFirst(s string) string {
@@ -55,7 +68,7 @@ func keyContainsOfVersions(key string, versions []string) bool {
 	}
 
 	for i := range versions {
-		if segments[0] == versions[i] {
+		if migration.SameVersion(migration.Version{Value: segments[0]}, migration.Version{Value: versions[i]}) {
 			return true
 		}
 	}
@@ -0,0 +1,89 @@
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/pkg/errors"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	singleFileFullExtension = ".sql"
+
+	sectionMarkerPrefix   = "-- +tern"
+	migrateSectionMarker  = sectionMarkerPrefix + " " + migrateFileSuffix
+	rollbackSectionMarker = sectionMarkerPrefix + " " + rollbackFileSuffix
+)
+
+var ErrMissingMigrateSection = errors.New("migrate section marker not found in migration file")
+
+// singleFileStub - contents of a newly created single file migration
+func singleFileStub(withRollback bool) string {
+	if withRollback {
+		return migrateSectionMarker + "\n\n" + rollbackSectionMarker + "\n"
+	}
+
+	return migrateSectionMarker + "\n"
+}
+
+// convertSingleFilePathToKey - extracts key from <version>_<name>.sql file name
+func convertSingleFilePathToKey(path string) (string, error) {
+	_, name := filepath.Split(path)
+
+	if !strings.HasSuffix(name, singleFileFullExtension) ||
+		strings.HasSuffix(name, defaultMigrateFileFullExtension) ||
+		strings.HasSuffix(name, defaultRollbackFileFullExtension) {
+		return "", ErrNotAMigrationFile
+	}
+
+	return validKey(strings.TrimSuffix(name, singleFileFullExtension))
+}
+
+// splitSingleFile splits single file migration contents into migrate and rollback
+// sections marked with -- +tern migrate and -- +tern rollback lines,
+// rollback is nil only when the file has no rollback section
+func splitSingleFile(contents []byte) ([]byte, []byte, error) {
+	var migrate, rollback bytes.Buffer
+	var current *bytes.Buffer
+	var migrateFound, rollbackFound bool
+
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(contents)+1)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case migrateSectionMarker:
+			migrateFound = true
+			current = &migrate
+			continue
+		case rollbackSectionMarker:
+			rollbackFound = true
+			current = &rollback
+			continue
+		}
+
+		if current == nil {
+			continue
+		}
+
+		current.WriteString(line)
+		current.WriteString("\n")
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if !migrateFound {
+		return nil, nil, ErrMissingMigrateSection
+	}
+
+	if !rollbackFound {
+		return bytes.TrimSpace(migrate.Bytes()), nil, nil
+	}
+
+	return bytes.TrimSpace(migrate.Bytes()), append([]byte{}, bytes.TrimSpace(rollback.Bytes())...), nil
+}
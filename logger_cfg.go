@@ -2,6 +2,28 @@ package tern
 
 import (
 	"github.com/denismitr/tern/v2/internal/logger"
+	"io"
+)
+
+type (
+	// LogLevel - severity of a log entry: LogLevelSQL, LogLevelDebug, LogLevelInfo, LogLevelWarn or LogLevelError
+	LogLevel = logger.Level
+	// LogEntry - structured log record with key value fields, e.g. key, version, operation and duration
+	LogEntry = logger.Entry
+	// LogField - key value pair of a log entry
+	LogField = logger.Field
+	// LogSink - destination of the log entries, implement it to ship the entries anywhere
+	LogSink = logger.Sink
+	// LogSinkFunc - adapter allowing an ordinary function to be used as a LogSink
+	LogSinkFunc = logger.SinkFunc
+)
+
+const (
+	LogLevelSQL   = logger.LevelSQL
+	LogLevelDebug = logger.LevelDebug
+	LogLevelInfo  = logger.LevelInfo
+	LogLevelWarn  = logger.LevelWarn
+	LogLevelError = logger.LevelError
 )
 
 func UseColorLogger(p logger.Printer, printSql, printDebug bool) OptionFunc {
@@ -18,4 +40,35 @@ func UseLogger(p logger.Printer, printSql, printDebug bool) OptionFunc {
 	}
 }
 
+// UseLogSink writes structured log entries of the level and above to the sink
+func UseLogSink(sink LogSink, level LogLevel) OptionFunc {
+	return func(m *Migrator) error {
+		m.lg = logger.New(sink, level)
+		return nil
+	}
+}
 
+// JSONLogSink writes every entry as a single line JSON object
+func JSONLogSink(w io.Writer) LogSink {
+	return logger.NewJSONSink(w)
+}
+
+// TextLogSink writes every entry as a single line of text with key=value fields
+func TextLogSink(w io.Writer) LogSink {
+	return logger.NewTextSink(w)
+}
+
+// ColorLogSink writes every entry as a line of text colored by its level
+func ColorLogSink(w io.Writer) LogSink {
+	return logger.NewColorSink(w)
+}
+
+// PrinterLogSink prints entries with the standard log package logger or any other printer
+func PrinterLogSink(p logger.Printer, color bool) LogSink {
+	return logger.NewPrinterSink(p, color)
+}
+
+// ParseLogLevel - log level by its name: sql, debug, info, warn or error
+func ParseLogLevel(level string) (LogLevel, error) {
+	return logger.ParseLevel(level)
+}
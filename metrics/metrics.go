@@ -0,0 +1,25 @@
+// Package metrics - collector of the migration run metrics fed by the database gateway
+// and its Prometheus text format exporter, that can be served over HTTP or written
+// to the textfile collector folder of node exporter
+package metrics
+
+import (
+	"time"
+)
+
+// Collector - receives the metrics of the operations from the database gateway,
+// it is called from the goroutine running the operation
+type Collector interface {
+	// ObserveMigration - migrate or rollback step of the migration with the key finished, err is set if it failed
+	ObserveMigration(table, operation, key string, d time.Duration, err error)
+	// ObserveLockWait - the operation acquired the database lock after waiting for it
+	ObserveLockWait(operation string, d time.Duration)
+	// ObserveOperation - the operation finished, err is nil when it succeeded or had nothing to do
+	ObserveOperation(table, operation string, err error)
+	// SetPending - number of migrations of the table that are not migrated after the operation
+	SetPending(table string, pending int)
+}
+
+// DefaultBuckets - upper bounds in seconds of the duration histograms,
+// migrations tend to be slower than requests so the buckets go up to 15 minutes
+var DefaultBuckets = []float64{0.01, 0.05, 0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 300, 900}
@@ -0,0 +1,342 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/pkg/errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ContentType - content type of the Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+const (
+	operationMigrate  = "migrate"
+	operationRollback = "rollback"
+)
+
+type (
+	// Prometheus - collector keeping the metrics in memory and exporting them
+	// in the Prometheus text format, it is safe for concurrent use
+	Prometheus struct {
+		mu       sync.Mutex
+		now      func() time.Time
+		keyLabel bool
+
+		applied          *vec
+		rolledBack       *vec
+		failed           *vec
+		operationsFailed *vec
+		lastSuccess      *vec
+		pending          *vec
+		durations        *histogramVec
+		lockWaits        *histogramVec
+	}
+
+	vec struct {
+		name       string
+		help       string
+		kind       string
+		labelNames []string
+		values     map[string]float64
+	}
+
+	histogramVec struct {
+		name       string
+		help       string
+		labelNames []string
+		buckets    []float64
+		values     map[string]*histogram
+	}
+
+	histogram struct {
+		counts []uint64
+		sum    float64
+		count  uint64
+	}
+)
+
+var _ Collector = (*Prometheus)(nil)
+var _ http.Handler = (*Prometheus)(nil)
+
+// NewPrometheus - creates a collector with the duration histograms using the buckets, DefaultBuckets if none
+func NewPrometheus(buckets ...float64) *Prometheus {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+
+	return &Prometheus{
+		now: time.Now,
+
+		applied:          newVec("tern_migrations_applied_total", "Migrations applied.", "counter", "table"),
+		rolledBack:       newVec("tern_migrations_rolled_back_total", "Migrations rolled back.", "counter", "table"),
+		failed:           newVec("tern_migrations_failed_total", "Migrate or rollback steps that failed.", "counter", "table", "operation"),
+		operationsFailed: newVec("tern_operations_failed_total", "Operations that failed.", "counter", "table", "operation"),
+		lastSuccess:      newVec("tern_last_success_timestamp_seconds", "Unix time of the last successful operation.", "gauge", "table"),
+		pending:          newVec("tern_pending_migrations", "Migrations not migrated yet.", "gauge", "table"),
+		durations:        newDurationsVec(sorted, false),
+		lockWaits: newHistogramVec(
+			"tern_lock_wait_seconds", "Time spent waiting for the database lock.", sorted, "operation",
+		),
+	}
+}
+
+// WithKeyLabel - labels the migration duration histogram with the migration key as well,
+// every migration gets series of its own, so it is meant for a limited number of migrations,
+// e.g. a textfile of a single run, it has to be called before the collector is used
+func (p *Prometheus) WithKeyLabel() *Prometheus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.keyLabel = true
+	p.durations = newDurationsVec(p.durations.buckets, true)
+
+	return p
+}
+
+func (p *Prometheus) ObserveMigration(table, operation, key string, d time.Duration, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.keyLabel {
+		p.durations.observe(d.Seconds(), table, operation, key)
+	} else {
+		p.durations.observe(d.Seconds(), table, operation)
+	}
+
+	switch {
+	case err != nil:
+		p.failed.add(1, table, operation)
+	case operation == operationRollback:
+		p.rolledBack.add(1, table)
+	case operation == operationMigrate:
+		p.applied.add(1, table)
+	}
+}
+
+func (p *Prometheus) ObserveLockWait(operation string, d time.Duration) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.lockWaits.observe(d.Seconds(), operation)
+}
+
+func (p *Prometheus) ObserveOperation(table, operation string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err != nil {
+		p.operationsFailed.add(1, table, operation)
+		return
+	}
+
+	p.lastSuccess.set(float64(p.now().UnixNano())/1e9, table)
+}
+
+func (p *Prometheus) SetPending(table string, pending int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pending.set(float64(pending), table)
+}
+
+// WriteTo - writes all the metrics in the Prometheus text format
+func (p *Prometheus) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+
+	p.mu.Lock()
+	for _, v := range []*vec{p.applied, p.rolledBack, p.failed, p.operationsFailed, p.lastSuccess, p.pending} {
+		v.write(&buf)
+	}
+
+	for _, h := range []*histogramVec{p.durations, p.lockWaits} {
+		h.write(&buf)
+	}
+	p.mu.Unlock()
+
+	return buf.WriteTo(w)
+}
+
+// ServeHTTP - serves the metrics, e.g. mounted at /metrics of the embedding application
+func (p *Prometheus) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	if _, err := p.WriteTo(w); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// WriteTextfile - atomically replaces the file with the metrics, the path is meant to be
+// a .prom file in the folder of the node exporter textfile collector
+func (p *Prometheus) WriteTextfile(path string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return errors.Wrapf(err, "could not create metrics file in [%s]", filepath.Dir(path))
+	}
+
+	if _, err := p.WriteTo(tmp); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return errors.Wrapf(err, "could not write metrics to [%s]", tmp.Name())
+	}
+
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return errors.Wrapf(err, "could not close metrics file [%s]", tmp.Name())
+	}
+
+	// temp files are created with 0600, the node exporter usually runs as another user
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		_ = os.Remove(tmp.Name())
+		return errors.Wrapf(err, "could not change mode of metrics file [%s]", tmp.Name())
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return errors.Wrapf(err, "could not move metrics file to [%s]", path)
+	}
+
+	return nil
+}
+
+func newVec(name, help, kind string, labelNames ...string) *vec {
+	return &vec{name: name, help: help, kind: kind, labelNames: labelNames, values: make(map[string]float64)}
+}
+
+func (v *vec) add(delta float64, labelValues ...string) {
+	v.values[labelKey(labelValues)] += delta
+}
+
+func (v *vec) set(value float64, labelValues ...string) {
+	v.values[labelKey(labelValues)] = value
+}
+
+func (v *vec) write(buf *bytes.Buffer) {
+	writeHeader(buf, v.name, v.help, v.kind)
+
+	for _, key := range sortedKeys(v.values) {
+		fmt.Fprintf(buf, "%s%s %s\n", v.name, formatLabels(v.labelNames, splitLabelKey(key)), formatFloat(v.values[key]))
+	}
+}
+
+func newHistogramVec(name, help string, buckets []float64, labelNames ...string) *histogramVec {
+	return &histogramVec{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		buckets:    buckets,
+		values:     make(map[string]*histogram),
+	}
+}
+
+func newDurationsVec(buckets []float64, keyLabel bool) *histogramVec {
+	labelNames := []string{"table", "operation"}
+	if keyLabel {
+		labelNames = append(labelNames, "key")
+	}
+
+	return newHistogramVec(
+		"tern_migration_duration_seconds", "Duration of the migrate and rollback steps.", buckets, labelNames...,
+	)
+}
+
+func (h *histogramVec) observe(value float64, labelValues ...string) {
+	key := labelKey(labelValues)
+	hist, ok := h.values[key]
+	if !ok {
+		hist = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.values[key] = hist
+	}
+
+	for i, upper := range h.buckets {
+		if value <= upper {
+			hist.counts[i]++
+			break
+		}
+	}
+
+	hist.sum += value
+	hist.count++
+}
+
+func (h *histogramVec) write(buf *bytes.Buffer) {
+	writeHeader(buf, h.name, h.help, "histogram")
+
+	keys := make([]string, 0, len(h.values))
+	for key := range h.values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		hist := h.values[key]
+		labelValues := splitLabelKey(key)
+		names := append(append([]string(nil), h.labelNames...), "le")
+
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += hist.counts[i]
+			values := append(append([]string(nil), labelValues...), formatFloat(upper))
+			fmt.Fprintf(buf, "%s_bucket%s %d\n", h.name, formatLabels(names, values), cumulative)
+		}
+
+		values := append(append([]string(nil), labelValues...), "+Inf")
+		fmt.Fprintf(buf, "%s_bucket%s %d\n", h.name, formatLabels(names, values), hist.count)
+		fmt.Fprintf(buf, "%s_sum%s %s\n", h.name, formatLabels(h.labelNames, labelValues), formatFloat(hist.sum))
+		fmt.Fprintf(buf, "%s_count%s %d\n", h.name, formatLabels(h.labelNames, labelValues), hist.count)
+	}
+}
+
+func writeHeader(buf *bytes.Buffer, name, help, kind string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(buf, "# TYPE %s %s\n", name, kind)
+}
+
+// labelKey - label values joined with a byte that cannot be a part of a valid UTF-8 label value
+func labelKey(labelValues []string) string {
+	return strings.Join(labelValues, "\xff")
+}
+
+func splitLabelKey(key string) []string {
+	return strings.Split(key, "\xff")
+}
+
+func sortedKeys(values map[string]float64) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, len(names))
+	for i := range names {
+		pairs[i] = fmt.Sprintf(`%s="%s"`, names[i], labelValueEscaper.Replace(values[i]))
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
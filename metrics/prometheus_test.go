@@ -0,0 +1,134 @@
+package metrics
+
+import (
+	"bytes"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPrometheus(t *testing.T) {
+	t.Parallel()
+
+	newCollector := func() *Prometheus {
+		p := NewPrometheus(1, 0.1)
+		p.now = func() time.Time { return time.Unix(1624033497, 0) }
+
+		p.ObserveLockWait("migrate", 20*time.Millisecond)
+		p.ObserveMigration("migrations", "migrate", "1596897167_create_foo_table", 50*time.Millisecond, nil)
+		p.ObserveMigration("migrations", "migrate", "1596897167_create_foo_table", 2*time.Second, errors.New("boom"))
+		p.ObserveMigration("seeds", "rollback", "1596897188_seed_foo", 500*time.Millisecond, nil)
+		p.ObserveOperation("migrations", "migrate", errors.New("boom"))
+		p.ObserveOperation("seeds", "rollback", nil)
+		p.SetPending("migrations", 1)
+		p.SetPending("seeds", 3)
+
+		return p
+	}
+
+	t.Run("text format", func(t *testing.T) {
+		var buf bytes.Buffer
+		_, err := newCollector().WriteTo(&buf)
+		require.NoError(t, err)
+
+		assert.Equal(t, `# HELP tern_migrations_applied_total Migrations applied.
+# TYPE tern_migrations_applied_total counter
+tern_migrations_applied_total{table="migrations"} 1
+# HELP tern_migrations_rolled_back_total Migrations rolled back.
+# TYPE tern_migrations_rolled_back_total counter
+tern_migrations_rolled_back_total{table="seeds"} 1
+# HELP tern_migrations_failed_total Migrate or rollback steps that failed.
+# TYPE tern_migrations_failed_total counter
+tern_migrations_failed_total{table="migrations",operation="migrate"} 1
+# HELP tern_operations_failed_total Operations that failed.
+# TYPE tern_operations_failed_total counter
+tern_operations_failed_total{table="migrations",operation="migrate"} 1
+# HELP tern_last_success_timestamp_seconds Unix time of the last successful operation.
+# TYPE tern_last_success_timestamp_seconds gauge
+tern_last_success_timestamp_seconds{table="seeds"} 1.624033497e+09
+# HELP tern_pending_migrations Migrations not migrated yet.
+# TYPE tern_pending_migrations gauge
+tern_pending_migrations{table="migrations"} 1
+tern_pending_migrations{table="seeds"} 3
+# HELP tern_migration_duration_seconds Duration of the migrate and rollback steps.
+# TYPE tern_migration_duration_seconds histogram
+tern_migration_duration_seconds_bucket{table="migrations",operation="migrate",le="0.1"} 1
+tern_migration_duration_seconds_bucket{table="migrations",operation="migrate",le="1"} 1
+tern_migration_duration_seconds_bucket{table="migrations",operation="migrate",le="+Inf"} 2
+tern_migration_duration_seconds_sum{table="migrations",operation="migrate"} 2.05
+tern_migration_duration_seconds_count{table="migrations",operation="migrate"} 2
+tern_migration_duration_seconds_bucket{table="seeds",operation="rollback",le="0.1"} 0
+tern_migration_duration_seconds_bucket{table="seeds",operation="rollback",le="1"} 1
+tern_migration_duration_seconds_bucket{table="seeds",operation="rollback",le="+Inf"} 1
+tern_migration_duration_seconds_sum{table="seeds",operation="rollback"} 0.5
+tern_migration_duration_seconds_count{table="seeds",operation="rollback"} 1
+# HELP tern_lock_wait_seconds Time spent waiting for the database lock.
+# TYPE tern_lock_wait_seconds histogram
+tern_lock_wait_seconds_bucket{operation="migrate",le="0.1"} 1
+tern_lock_wait_seconds_bucket{operation="migrate",le="1"} 1
+tern_lock_wait_seconds_bucket{operation="migrate",le="+Inf"} 1
+tern_lock_wait_seconds_sum{operation="migrate"} 0.02
+tern_lock_wait_seconds_count{operation="migrate"} 1
+`, buf.String())
+	})
+
+	t.Run("durations are labelled by key when enabled", func(t *testing.T) {
+		p := NewPrometheus(1).WithKeyLabel()
+		p.ObserveMigration("migrations", "migrate", "1596897167_create_foo_table", 50*time.Millisecond, nil)
+		p.ObserveMigration("migrations", "migrate", "1596897188_create_bar_table", 2*time.Second, nil)
+
+		var buf bytes.Buffer
+		_, err := p.WriteTo(&buf)
+		require.NoError(t, err)
+		assert.Contains(t, buf.String(), `tern_migration_duration_seconds_count{table="migrations",operation="migrate",key="1596897167_create_foo_table"} 1
+`)
+		assert.Contains(t, buf.String(), `tern_migration_duration_seconds_bucket{table="migrations",operation="migrate",key="1596897188_create_bar_table",le="+Inf"} 1
+`)
+		assert.Contains(t, buf.String(), `tern_migrations_applied_total{table="migrations"} 2
+`)
+	})
+
+	t.Run("label values are escaped", func(t *testing.T) {
+		p := NewPrometheus()
+		p.SetPending("a\"b\\c\nd", 1)
+
+		var buf bytes.Buffer
+		_, err := p.WriteTo(&buf)
+		require.NoError(t, err)
+		assert.Contains(t, buf.String(), `tern_pending_migrations{table="a\"b\\c\nd"} 1`)
+	})
+
+	t.Run("served over http", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newCollector().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+		assert.Equal(t, 200, rec.Code)
+		assert.Equal(t, ContentType, rec.Header().Get("Content-Type"))
+		assert.Contains(t, rec.Body.String(), `tern_pending_migrations{table="seeds"} 3`)
+	})
+
+	t.Run("textfile is replaced", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "tern_metrics")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+
+		path := filepath.Join(dir, "tern.prom")
+		require.NoError(t, ioutil.WriteFile(path, []byte("stale"), 0644))
+		require.NoError(t, newCollector().WriteTextfile(path))
+
+		b, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		assert.Contains(t, string(b), `tern_migrations_applied_total{table="migrations"} 1`)
+
+		files, err := ioutil.ReadDir(dir)
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+		assert.Equal(t, os.FileMode(0644), files[0].Mode().Perm())
+	})
+}
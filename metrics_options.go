@@ -0,0 +1,14 @@
+package tern
+
+import (
+	"github.com/denismitr/tern/v2/metrics"
+)
+
+// UseMetrics feeds the collector with the metrics of every operation,
+// e.g. metrics.NewPrometheus() served at /metrics or written to a node exporter textfile
+func UseMetrics(c metrics.Collector) OptionFunc {
+	return func(m *Migrator) error {
+		m.metrics = c
+		return nil
+	}
+}
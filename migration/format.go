@@ -0,0 +1,317 @@
+package migration
+
+import (
+	"github.com/pkg/errors"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrUnknownVersionFormat = errors.New("unknown version format")
+var ErrVersionFormatRegistered = errors.New("version format is already registered")
+
+const datetimeLayout = "20060102150405"
+
+// Format - describes how versions of a kind are validated, ordered and generated,
+// custom formats e.g. semver can be added with RegisterFormat
+type Format interface {
+	// Name - unique name of the format, used in configuration
+	Name() VersionFormat
+
+	// Pattern - regular expression without anchors matching versions of the format in file names
+	Pattern() string
+
+	// Parse - validates the version value
+	Parse(s string) (Version, error)
+
+	// Compare - returns a negative number, zero or a positive number
+	// when version a goes before, is the same as or goes after version b
+	Compare(a, b string) int
+
+	// Next - generates the version of a new migration
+	Next(cf ClockFunc, existing []Version) (Version, error)
+}
+
+var (
+	formatsMu sync.RWMutex
+	formats   = map[VersionFormat]Format{
+		TimestampFormat: timestampFormat{},
+		DatetimeFormat:  datetimeFormat{},
+		NumberFormat:    numberFormat{},
+	}
+	builtinFormats = []VersionFormat{NumberFormat, DatetimeFormat, TimestampFormat}
+	customFormats  []VersionFormat
+)
+
+// RegisterFormat - makes a custom version format available by its name
+func RegisterFormat(f Format) error {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+
+	if _, ok := formats[f.Name()]; ok || f.Name() == AnyFormat {
+		return errors.Wrapf(ErrVersionFormatRegistered, "%s", f.Name())
+	}
+
+	formats[f.Name()] = f
+	customFormats = append(customFormats, f.Name())
+
+	return nil
+}
+
+// LookupFormat - finds a registered format by its name, any format
+// accepts versions of every registered format
+func LookupFormat(name VersionFormat) (Format, error) {
+	if name == AnyFormat {
+		return anyFormat{}, nil
+	}
+
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+
+	f, ok := formats[name]
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownVersionFormat, "%s", name)
+	}
+
+	return f, nil
+}
+
+// CompareVersions - compares versions of the same format with the rules of that format,
+// timestamp and datetime versions are compared by the time they represent (datetime in UTC),
+// other digit only versions by their numeric value and the rest as strings
+func CompareVersions(a, b Version) int {
+	a, b = withFormat(a), withFormat(b)
+
+	if a.Format == b.Format && a.Format != "" {
+		if f, err := LookupFormat(a.Format); err == nil {
+			return f.Compare(a.Value, b.Value)
+		}
+	}
+
+	if isTimeBased(a.Format) && isTimeBased(b.Format) {
+		ta, errA := a.Time()
+		tb, errB := b.Time()
+		if errA == nil && errB == nil && !ta.Equal(tb) {
+			if ta.Before(tb) {
+				return -1
+			}
+
+			return 1
+		}
+	}
+
+	if isDigits(a.Value) && isDigits(b.Value) {
+		return compareNumbers(a.Value, b.Value)
+	}
+
+	return strings.Compare(a.Value, b.Value)
+}
+
+// SameVersion - versions are equal according to the rules of their format, e.g. 0001 and 1 numbers
+func SameVersion(a, b Version) bool {
+	if a.Value == b.Value {
+		return true
+	}
+
+	a, b = withFormat(a), withFormat(b)
+
+	return a.Format == b.Format && a.Format != "" && CompareVersions(a, b) == 0
+}
+
+// Time - moment in time represented by timestamp or datetime (in UTC) version
+func (v Version) Time() (time.Time, error) {
+	v = withFormat(v)
+
+	switch v.Format {
+	case DatetimeFormat:
+		return time.Parse(datetimeLayout, v.Value)
+	case TimestampFormat:
+		ts, err := strconv.ParseInt(v.Value, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		return time.Unix(ts, 0).UTC(), nil
+	default:
+		return time.Time{}, errors.Wrapf(ErrInvalidVersionFormat, "version %s does not contain a date", v.Value)
+	}
+}
+
+// ConvertVersion - converts timestamp version to datetime (in UTC) and back,
+// versions already in the target format are returned as is
+func ConvertVersion(v Version, to VersionFormat) (Version, error) {
+	v = withFormat(v)
+	if v.Format == to {
+		return v, nil
+	}
+
+	if !isTimeBased(v.Format) || !isTimeBased(to) {
+		return Version{}, errors.Wrapf(ErrInvalidVersionFormat, "%s version %s cannot be converted to %s", v.Format, v.Value, to)
+	}
+
+	t, err := v.Time()
+	if err != nil {
+		return Version{}, err
+	}
+
+	if to == DatetimeFormat {
+		return Version{Format: DatetimeFormat, Value: t.Format(datetimeLayout)}, nil
+	}
+
+	return Timestamp(strconv.FormatInt(t.Unix(), 10))()
+}
+
+// withFormat - detects the format of the version if it is unknown
+func withFormat(v Version) Version {
+	if v.Format != "" && v.Format != AnyFormat {
+		return v
+	}
+
+	if detected, err := VersionFromString(v.Value); err == nil {
+		v.Format = detected.Format
+	}
+
+	return v
+}
+
+func isTimeBased(vf VersionFormat) bool {
+	return vf == TimestampFormat || vf == DatetimeFormat
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// detectFormat - parses the value with the custom formats in order of their registration
+func detectFormat(s string) (Version, error) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+
+	for _, name := range customFormats {
+		if v, err := formats[name].Parse(s); err == nil {
+			return v, nil
+		}
+	}
+
+	return Version{}, errors.Wrapf(ErrInvalidVersionFormat, "input: %s", s)
+}
+
+type timestampFormat struct{}
+
+func (timestampFormat) Name() VersionFormat { return TimestampFormat }
+
+func (timestampFormat) Pattern() string { return `\d{9,12}` }
+
+func (timestampFormat) Parse(s string) (Version, error) {
+	return Timestamp(s)()
+}
+
+func (timestampFormat) Compare(a, b string) int {
+	return compareNumbers(a, b)
+}
+
+func (timestampFormat) Next(cf ClockFunc, _ []Version) (Version, error) {
+	return Version{Format: TimestampFormat, Value: strconv.Itoa(int(cf().Unix()))}, nil
+}
+
+type datetimeFormat struct{}
+
+func (datetimeFormat) Name() VersionFormat { return DatetimeFormat }
+
+func (datetimeFormat) Pattern() string { return `\d{14}` }
+
+func (datetimeFormat) Parse(s string) (Version, error) {
+	if !datetimeRx.MatchString(s) {
+		return Version{}, errors.Wrapf(ErrInvalidVersionFormat, "datetime [%s] should be 14 digits long", s)
+	}
+
+	return Version{Format: DatetimeFormat, Value: s}, nil
+}
+
+func (datetimeFormat) Compare(a, b string) int {
+	return compareNumbers(a, b)
+}
+
+func (datetimeFormat) Next(cf ClockFunc, _ []Version) (Version, error) {
+	return Version{Format: DatetimeFormat, Value: cf().Format(datetimeLayout)}, nil
+}
+
+type numberFormat struct{}
+
+func (numberFormat) Name() VersionFormat { return NumberFormat }
+
+func (numberFormat) Pattern() string { return `\d{1,14}` }
+
+func (numberFormat) Parse(s string) (Version, error) {
+	if s == "" || len(s) > MaxVersionLen || !isPositiveInt(s) {
+		return Version{}, errors.Wrapf(ErrInvalidVersionFormat, "number [%s] should contain 1 to %d digits", s, MaxVersionLen)
+	}
+
+	return Version{Format: NumberFormat, Value: s}, nil
+}
+
+func (numberFormat) Compare(a, b string) int {
+	return compareNumbers(a, b)
+}
+
+func (numberFormat) Next(_ ClockFunc, existing []Version) (Version, error) {
+	return Version{Format: NumberFormat, Value: nextNumber(existing)}, nil
+}
+
+// anyFormat - accepts versions of all registered formats
+type anyFormat struct{}
+
+func (anyFormat) Name() VersionFormat { return AnyFormat }
+
+func (anyFormat) Pattern() string {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+
+	patterns := make([]string, 0, len(formats))
+	for _, name := range append(builtinFormats, customFormats...) {
+		patterns = append(patterns, formats[name].Pattern())
+	}
+
+	return strings.Join(patterns, "|")
+}
+
+func (anyFormat) Parse(s string) (Version, error) {
+	return VersionFromString(s)
+}
+
+func (anyFormat) Compare(a, b string) int {
+	va, errA := VersionFromString(a)
+	vb, errB := VersionFromString(b)
+	if errA != nil || errB != nil {
+		return strings.Compare(a, b)
+	}
+
+	return CompareVersions(va, vb)
+}
+
+func (anyFormat) Next(_ ClockFunc, _ []Version) (Version, error) {
+	return Version{}, errors.Wrap(ErrInvalidVersionFormat, "version cannot be generated in any format")
+}
+
+// compareNumbers - compares digit only values by their numeric value
+func compareNumbers(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+
+	if len(a) != len(b) {
+		return len(a) - len(b)
+	}
+
+	return strings.Compare(a, b)
+}
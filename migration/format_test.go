@@ -0,0 +1,174 @@
+package migration
+
+import (
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const semverFormat VersionFormat = "semver"
+
+var semverRx = regexp.MustCompile(`^\d+\.\d+\.\d+$`)
+
+// semver - custom format used to check that formats are pluggable
+type semver struct{}
+
+func (semver) Name() VersionFormat { return semverFormat }
+
+func (semver) Pattern() string { return `\d+\.\d+\.\d+` }
+
+func (semver) Parse(s string) (Version, error) {
+	if !semverRx.MatchString(s) {
+		return Version{}, errors.Wrapf(ErrInvalidVersionFormat, "%s is not a semver", s)
+	}
+
+	return Version{Format: semverFormat, Value: s}, nil
+}
+
+func (semver) Compare(a, b string) int {
+	pa, pb := strings.Split(a, "."), strings.Split(b, ".")
+	for i := range pa {
+		na, _ := strconv.Atoi(pa[i])
+		nb, _ := strconv.Atoi(pb[i])
+		if na != nb {
+			return na - nb
+		}
+	}
+
+	return 0
+}
+
+func (s semver) Next(_ ClockFunc, existing []Version) (Version, error) {
+	last := "0.0.0"
+	for _, v := range existing {
+		if s.Compare(v.Value, last) > 0 {
+			last = v.Value
+		}
+	}
+
+	parts := strings.Split(last, ".")
+	patch, _ := strconv.Atoi(parts[2])
+
+	return Version{Format: semverFormat, Value: parts[0] + "." + parts[1] + "." + strconv.Itoa(patch+1)}, nil
+}
+
+func TestCustomFormat(t *testing.T) {
+	require.NoError(t, RegisterFormat(semver{}))
+
+	t.Run("format cannot be registered twice", func(t *testing.T) {
+		err := RegisterFormat(semver{})
+		assert.True(t, errors.Is(err, ErrVersionFormatRegistered))
+	})
+
+	t.Run("registered format can be found", func(t *testing.T) {
+		f, err := LookupFormat(semverFormat)
+		require.NoError(t, err)
+		assert.Equal(t, semverFormat, f.Name())
+
+		_, err = LookupFormat("calver")
+		assert.True(t, errors.Is(err, ErrUnknownVersionFormat))
+	})
+
+	t.Run("versions are detected and ordered with the format rules", func(t *testing.T) {
+		v, err := VersionFromString("1.10.0")
+		require.NoError(t, err)
+		assert.Equal(t, semverFormat, v.Format)
+
+		migrations := Migrations{
+			{Key: "1.10.0_c", Version: Version{Format: semverFormat, Value: "1.10.0"}},
+			{Key: "1.4.2_b", Version: Version{Format: semverFormat, Value: "1.4.2"}},
+			{Key: "1.4.0_a", Version: Version{Format: semverFormat, Value: "1.4.0"}},
+		}
+
+		sort.Sort(migrations)
+		assert.Equal(t, []string{"1.4.0_a", "1.4.2_b", "1.10.0_c"}, migrations.Keys())
+	})
+
+	t.Run("migration from db gets the custom format", func(t *testing.T) {
+		m, err := NewMigrationFromDB("1.4.2", time.Now(), "add_index")()
+		require.NoError(t, err)
+		assert.Equal(t, semverFormat, m.Version.Format)
+	})
+
+	t.Run("next version is generated by the format", func(t *testing.T) {
+		v, err := NextVersion(time.Now, semverFormat, Version{Value: "1.4.2"}, Version{Value: "1.10.0"})
+		require.NoError(t, err)
+		assert.Equal(t, "1.10.1", v.Value)
+	})
+}
+
+func TestBuiltinFormats(t *testing.T) {
+	t.Parallel()
+
+	t.Run("numbers are compared by value", func(t *testing.T) {
+		f, err := LookupFormat(NumberFormat)
+		require.NoError(t, err)
+		assert.True(t, f.Compare("9", "0010") < 0)
+		assert.Equal(t, 0, f.Compare("0010", "10"))
+	})
+
+	t.Run("any format cannot generate versions", func(t *testing.T) {
+		_, err := NextVersion(time.Now, AnyFormat)
+		assert.True(t, errors.Is(err, ErrInvalidVersionFormat))
+	})
+}
+
+func TestMixedVersionOrdering(t *testing.T) {
+	t.Parallel()
+
+	t.Run("timestamps and datetimes are ordered by the time they represent", func(t *testing.T) {
+		migrations := Migrations{
+			{Key: "20210101000000_c", Version: Version{Value: "20210101000000"}},
+			{Key: "1602439886_b", Version: Version{Value: "1602439886"}},
+			{Key: "20190101000000_a", Version: Version{Value: "20190101000000"}},
+		}
+
+		sort.Sort(migrations)
+
+		assert.Equal(t, []string{"20190101000000_a", "1602439886_b", "20210101000000_c"}, migrations.Keys())
+	})
+
+	t.Run("digit only versions of different lengths are compared numerically", func(t *testing.T) {
+		assert.True(t, CompareVersions(Version{Value: "999999999"}, Version{Value: "1000000000"}) < 0)
+		assert.True(t, CompareVersions(Version{Value: "0009"}, Version{Value: "0010"}) < 0)
+	})
+
+	t.Run("zero padded numbers are the same version", func(t *testing.T) {
+		assert.True(t, SameVersion(Version{Value: "0001"}, Version{Value: "1"}))
+		assert.True(t, InVersions(Version{Value: "1"}, []Version{{Value: "0002"}, {Value: "0001"}}))
+		assert.False(t, InVersions(Version{Value: "1602439886"}, []Version{{Value: "20201011181126"}}))
+	})
+}
+
+func TestConvertVersion(t *testing.T) {
+	t.Parallel()
+
+	t.Run("timestamp is converted to datetime in UTC", func(t *testing.T) {
+		v, err := ConvertVersion(Version{Value: "1602439886"}, DatetimeFormat)
+		require.NoError(t, err)
+		assert.Equal(t, Version{Format: DatetimeFormat, Value: "20201011181126"}, v)
+	})
+
+	t.Run("datetime is converted to timestamp", func(t *testing.T) {
+		v, err := ConvertVersion(Version{Value: "20201011181126"}, TimestampFormat)
+		require.NoError(t, err)
+		assert.Equal(t, Version{Format: TimestampFormat, Value: "1602439886"}, v)
+	})
+
+	t.Run("version in the target format is kept", func(t *testing.T) {
+		v, err := ConvertVersion(Version{Value: "20201011181126"}, DatetimeFormat)
+		require.NoError(t, err)
+		assert.Equal(t, "20201011181126", v.Value)
+	})
+
+	t.Run("numbers cannot be converted", func(t *testing.T) {
+		_, err := ConvertVersion(Version{Value: "0001"}, DatetimeFormat)
+		assert.True(t, errors.Is(err, ErrInvalidVersionFormat))
+	})
+}
@@ -29,6 +29,27 @@ type (
 		Version  Version
 		Migrate  []string
 		Rollback []string
+
+		// NoTransaction - migration must be executed outside of the operation transaction
+		NoTransaction bool
+		// Timeout - max duration of the migration execution, zero means no limit
+		Timeout time.Duration
+		// LockMode - lock level of the ALTER TABLE statements of the migration:
+		// none, shared, exclusive or default, empty means the statements are left as is
+		LockMode string
+		// Labels - arbitrary labels assigned to the migration
+		Labels []string
+		// Environments - environments the migration is limited to, used to select seeds
+		Environments []string
+		// Origin - source the migration was read from, set when several sources are combined
+		Origin string
+		// MigrateFunc - Go migrate step, executed after the migrate scripts
+		MigrateFunc Func
+		// RollbackFunc - Go rollback step, executed after the rollback scripts
+		RollbackFunc Func
+		// Irreversible - migration has no rollback or is marked irreversible,
+		// rolling it back requires an explicit permission
+		Irreversible bool
 	}
 
 	ClockFunc func() time.Time
@@ -44,6 +65,7 @@ const (
 	MaxTimestampLength = 12
 	MinTimestampLength = 9
 	MaxVersionLen      = 14
+	DefaultNumberWidth = 4
 )
 
 var timestampRx = regexp.MustCompile(fmt.Sprintf(`^\d{%d,%d}$`, MinTimestampLength, MaxTimestampLength))
@@ -208,11 +230,12 @@ func New(vf VersionFactory, name string, migrate, rollback []string) Factory {
 		}
 
 		m := &Migration{
-			Key:      CreateKeyFromVersionAndName(v.Value, name),
-			Name:     name,
-			Version:  v,
-			Migrate:  migrate,
-			Rollback: rollback,
+			Key:          CreateKeyFromVersionAndName(v.Value, name),
+			Name:         name,
+			Version:      v,
+			Migrate:      migrate,
+			Rollback:     rollback,
+			Irreversible: len(rollback) == 0,
 		}
 
 		return m, nil
@@ -248,7 +271,7 @@ func (m Migrations) Len() int {
 }
 
 func (m Migrations) Less(i, j int) bool {
-	return m[i].Version.Value < m[j].Version.Value
+	return CompareVersions(m[i].Version, m[j].Version) < 0
 }
 
 func (m Migrations) Swap(i, j int) {
@@ -263,27 +286,71 @@ func CreateKeyFromVersionAndName(v, name string) string {
 	return result.String()
 }
 
-func GenerateVersion(cf ClockFunc, vf VersionFormat) Version {
-	var v Version
+// GenerateVersion - creates a version for a new migration with the format rules,
+// formats unable to generate versions fall back to datetime
+func GenerateVersion(cf ClockFunc, vf VersionFormat, existing ...Version) Version {
+	if f, err := LookupFormat(vf); err == nil {
+		if v, err := f.Next(cf, existing); err == nil {
+			return v
+		}
+	}
 
+	v, _ := datetimeFormat{}.Next(cf, existing)
 	v.Format = vf
-	if v.Format == TimestampFormat {
-		v.Value = strconv.Itoa(int(cf().Unix()))
-	} else {
-		v.Value = cf().Format("2006-01-02 15:04:05")
-		v.Value = strings.ReplaceAll(v.Value, "-", "")
-		v.Value = strings.ReplaceAll(v.Value, ":", "")
-		v.Value = strings.ReplaceAll(v.Value, " ", "")
-	}
 
 	return v
 }
 
+// NextVersion - creates a version for a new migration with the format rules
+func NextVersion(cf ClockFunc, vf VersionFormat, existing ...Version) (Version, error) {
+	f, err := LookupFormat(vf)
+	if err != nil {
+		return Version{}, err
+	}
+
+	return f.Next(cf, existing)
+}
+
+// nextNumber - largest number of the versions plus one, left padded with zeros
+// to the width of the existing versions e.g. 0007 after 0006
+func nextNumber(versions []Version) string {
+	var max uint64
+	width := DefaultNumberWidth
+
+	for _, v := range versions {
+		n, err := strconv.ParseUint(v.Value, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		if n > max {
+			max = n
+		}
+
+		if len(v.Value) > width {
+			width = len(v.Value)
+		}
+	}
+
+	s := strconv.FormatUint(max+1, 10)
+	if len(s) < width {
+		s = strings.Repeat("0", width-len(s)) + s
+	}
+
+	return s
+}
+
 func SetVersionFormat(m *Migration) error {
-	if len(m.Version.Value) > MinTimestampLength && len(m.Version.Value) <= MaxTimestampLength {
+	digits := isPositiveInt(m.Version.Value)
+
+	if isNumber(m.Version.Value) {
+		m.Version.Format = NumberFormat
+	} else if digits && len(m.Version.Value) >= MinTimestampLength && len(m.Version.Value) <= MaxTimestampLength {
 		m.Version.Format = TimestampFormat
-	} else if len(m.Version.Value) > MaxTimestampLength {
+	} else if digits && len(m.Version.Value) > MaxTimestampLength {
 		m.Version.Format = DatetimeFormat
+	} else if v, err := detectFormat(m.Version.Value); err == nil {
+		m.Version.Format = v.Format
 	} else {
 		return errors.Wrapf(ErrInvalidVersionFormat, "%s", m.Version.Value)
 	}
@@ -291,9 +358,10 @@ func SetVersionFormat(m *Migration) error {
 	return nil
 }
 
+// InVersions - checks if the version is among the versions, see SameVersion
 func InVersions(version Version, versions []Version) bool {
 	for _, v := range versions {
-		if v.Value == version.Value {
+		if SameVersion(v, version) {
 			return true
 		}
 	}
@@ -302,10 +370,17 @@ func InVersions(version Version, versions []Version) bool {
 }
 
 func VersionFromString(s string) (Version, error) {
+	if isNumber(s) {
+		return Version{
+			Value:  s,
+			Format: NumberFormat,
+		}, nil
+	}
+
 	isDatetime := datetimeRx.MatchString(s)
 	if isDatetime {
 		return Version{
-			Value: s,
+			Value:  s,
 			Format: DatetimeFormat,
 		}, nil
 	}
@@ -313,19 +388,29 @@ func VersionFromString(s string) (Version, error) {
 	isTimestamp := timestampRx.MatchString(s)
 	if isTimestamp {
 		return Version{
-			Value: s,
+			Value:  s,
 			Format: TimestampFormat,
 		}, nil
 	}
 
 	if isPositiveInt(s) {
 		return Version{
-			Value: s,
+			Value:  s,
 			Format: NumberFormat,
 		}, nil
 	}
 
-	return Version{}, errors.Wrapf(ErrInvalidVersionFormat, "input: %s", s)
+	return detectFormat(s)
+}
+
+// isNumber - sequential numbers are either zero padded or too short
+// to be confused with timestamps and datetimes
+func isNumber(s string) bool {
+	if s == "" || len(s) > MaxVersionLen || !isPositiveInt(s) {
+		return false
+	}
+
+	return s[0] == '0' || len(s) < MinTimestampLength
 }
 
 func isPositiveInt(s string) bool {
@@ -335,4 +420,4 @@ func isPositiveInt(s string) bool {
 	}
 
 	return n >= 0
-}
\ No newline at end of file
+}
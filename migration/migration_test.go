@@ -66,10 +66,31 @@ func Test_GenerateVersion_InTimestampFormat(t *testing.T) {
 	assert.Equal(t, "20190102171819", versionDt.Value)
 }
 
+func Test_GenerateVersion_InNumberFormat(t *testing.T) {
+	tt := []struct {
+		name     string
+		existing []Version
+		exp      string
+	}{
+		{name: "first number", exp: "0001"},
+		{name: "next after max", existing: []Version{{Value: "0002"}, {Value: "0007"}, {Value: "0003"}}, exp: "0008"},
+		{name: "width of existing is kept", existing: []Version{{Value: "000009"}}, exp: "000010"},
+		{name: "grows past width", existing: []Version{{Value: "9999"}}, exp: "10000"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			v := GenerateVersion(time.Now, NumberFormat, tc.existing...)
+			assert.Equal(t, NumberFormat, v.Format)
+			assert.Equal(t, tc.exp, v.Value)
+		})
+	}
+}
+
 func TestInVersions(t *testing.T) {
-	tt := []struct{
-		name string
-		version Version
+	tt := []struct {
+		name     string
+		version  Version
 		versions []Version
 		expected bool
 	}{
@@ -77,7 +98,7 @@ func TestInVersions(t *testing.T) {
 			name: "one version and one match in timestamp format",
 			version: Version{
 				Format: TimestampFormat,
-				Value: "1546449499",
+				Value:  "1546449499",
 			},
 			versions: []Version{
 				{Value: "1546449499", Format: TimestampFormat},
@@ -88,7 +109,7 @@ func TestInVersions(t *testing.T) {
 			name: "two versions and no match in timestamp format",
 			version: Version{
 				Format: TimestampFormat,
-				Value: "1546449499",
+				Value:  "1546449499",
 			},
 			versions: []Version{
 				{Value: "1546449498", Format: TimestampFormat},
@@ -109,7 +130,7 @@ func TestInVersions(t *testing.T) {
 func TestTimestampVersion(t *testing.T) {
 	t.Parallel()
 
-	validInputs := []struct{
+	validInputs := []struct {
 		t string
 	}{
 		{"154644949"},
@@ -128,7 +149,7 @@ func TestTimestampVersion(t *testing.T) {
 		})
 	}
 
-	invalidInputs := []struct{
+	invalidInputs := []struct {
 		t string
 	}{
 		{""},
@@ -153,32 +174,32 @@ func TestTimestampVersion(t *testing.T) {
 func TestDateTimeVersion(t *testing.T) {
 	t.Parallel()
 
-	validInputs := []struct{
-		year int
-		month int
-		day int
-		hour int
+	validInputs := []struct {
+		year   int
+		month  int
+		day    int
+		hour   int
 		minute int
 		second int
-		exp string
+		exp    string
 	}{
 		{
-			year: 2019,
-			month: 1,
-			day: 30,
-			hour: 10,
+			year:   2019,
+			month:  1,
+			day:    30,
+			hour:   10,
 			minute: 5,
 			second: 59,
-			exp: "20190130100559",
+			exp:    "20190130100559",
 		},
 		{
-			year: 2021,
-			month: 12,
-			day: 31,
-			hour: 0,
+			year:   2021,
+			month:  12,
+			day:    31,
+			hour:   0,
 			minute: 0,
 			second: 1,
-			exp: "20211231000001",
+			exp:    "20211231000001",
 		},
 	}
 
@@ -192,43 +213,43 @@ func TestDateTimeVersion(t *testing.T) {
 		})
 	}
 
-	invalidInputs := []struct{
-		year int
-		month int
-		day int
-		hour int
+	invalidInputs := []struct {
+		year   int
+		month  int
+		day    int
+		hour   int
 		minute int
 		second int
 	}{
 		{
-			year: 19,
-			month: 1,
-			day: 30,
-			hour: 10,
+			year:   19,
+			month:  1,
+			day:    30,
+			hour:   10,
 			minute: 5,
 			second: 59,
 		},
 		{
-			year: 19564,
-			month: 1,
-			day: 30,
-			hour: 10,
+			year:   19564,
+			month:  1,
+			day:    30,
+			hour:   10,
 			minute: 5,
 			second: 59,
 		},
 		{
-			year: 1956,
-			month: -1,
-			day: 30,
-			hour: 10,
+			year:   1956,
+			month:  -1,
+			day:    30,
+			hour:   10,
 			minute: 5,
 			second: 59,
 		},
 		{
-			year: 1956,
-			month: 1,
-			day: 30,
-			hour: 10,
+			year:   1956,
+			month:  1,
+			day:    30,
+			hour:   10,
 			minute: 5,
 			second: -19,
 		},
@@ -246,8 +267,8 @@ func TestDateTimeVersion(t *testing.T) {
 func TestNumberFormat(t *testing.T) {
 	t.Parallel()
 
-	validInputs := []struct{
-		n uint
+	validInputs := []struct {
+		n   uint
 		exp string
 	}{
 		{n: 1, exp: "00000000000001"},
@@ -266,7 +287,7 @@ func TestNumberFormat(t *testing.T) {
 		})
 	}
 
-	invalidInputs := []struct{
+	invalidInputs := []struct {
 		n uint
 	}{
 		{n: 123_456_789_000_000},
@@ -321,12 +342,14 @@ func TestNew(t *testing.T) {
 func TestVersionFromString(t *testing.T) {
 	t.Parallel()
 
-	validInputs := []struct{
-		in string
+	validInputs := []struct {
+		in     string
 		format VersionFormat
 	}{
 		{in: "15464494912", format: TimestampFormat},
 		{in: "00000000000001", format: NumberFormat},
+		{in: "0001", format: NumberFormat},
+		{in: "12", format: NumberFormat},
 		{in: "20190130100559", format: DatetimeFormat},
 	}
 
@@ -338,4 +361,4 @@ func TestVersionFromString(t *testing.T) {
 			assert.Equal(t, tc.format, v.Format)
 		})
 	}
-}
\ No newline at end of file
+}
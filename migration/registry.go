@@ -0,0 +1,74 @@
+package migration
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+type (
+	// Executor - runs statements of a Go migration, it is either the transaction
+	// of the running operation or the connection for no-transaction migrations
+	Executor interface {
+		ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	}
+
+	// Func - migrate or rollback step of a Go migration
+	Func func(ctx context.Context, ex Executor) error
+)
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]*Migration)
+)
+
+// Register - adds a Go migration to the package level registry, it is meant to be called
+// from init functions of generated migration files and panics if the version is invalid
+// or already registered, the same way database/sql.Register does
+func Register(version, name string, migrate, rollback Func) {
+	v, err := VersionFromString(version)
+	if err != nil {
+		panic(fmt.Sprintf("tern: could not register Go migration %s: %s", name, err))
+	}
+
+	if name == "" || migrate == nil {
+		panic(fmt.Sprintf("tern: Go migration %s requires a name and a migrate function", version))
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for _, m := range registry {
+		if SameVersion(m.Version, v) {
+			panic(fmt.Sprintf("tern: Go migration version %s is registered twice (%s)", version, m.Key))
+		}
+	}
+
+	key := CreateKeyFromVersionAndName(v.Value, name)
+	registry[key] = &Migration{
+		Key:          key,
+		Name:         name,
+		Version:      v,
+		MigrateFunc:  migrate,
+		RollbackFunc: rollback,
+		Irreversible: rollback == nil,
+	}
+}
+
+// Registered - copies of all registered Go migrations ordered by version
+func Registered() Migrations {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	result := make(Migrations, 0, len(registry))
+	for _, m := range registry {
+		cp := *m
+		result = append(result, &cp)
+	}
+
+	sort.Sort(result)
+
+	return result
+}
@@ -0,0 +1,50 @@
+package migration
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func unregister(key string) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	delete(registry, key)
+}
+
+func noop(context.Context, Executor) error {
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	t.Run("registered migrations are ordered by version", func(t *testing.T) {
+		Register("0002", "seed_users", noop, nil)
+		Register("0001", "create_users", noop, noop)
+		defer unregister("0001_create_users")
+		defer unregister("0002_seed_users")
+
+		registered := Registered()
+		require.Len(t, registered, 2)
+		assert.Equal(t, []string{"0001_create_users", "0002_seed_users"}, registered.Keys())
+		assert.Equal(t, NumberFormat, registered[0].Version.Format)
+		assert.NotNil(t, registered[0].RollbackFunc)
+		assert.Nil(t, registered[1].RollbackFunc)
+	})
+
+	t.Run("same version cannot be registered twice", func(t *testing.T) {
+		Register("1602439886", "create_users", noop, nil)
+		defer unregister("1602439886_create_users")
+
+		assert.Panics(t, func() {
+			Register("1602439886", "create_posts", noop, nil)
+		})
+	})
+
+	t.Run("invalid version panics", func(t *testing.T) {
+		assert.Panics(t, func() {
+			Register("", "create_users", noop, nil)
+		})
+	})
+}
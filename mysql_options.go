@@ -2,12 +2,13 @@ package tern
 
 import (
 	"database/sql"
+	"github.com/denismitr/tern/v2/internal/analyzer"
 	"github.com/denismitr/tern/v2/internal/database"
 	"github.com/denismitr/tern/v2/internal/database/sqlgateway"
 	"time"
 )
 
-type MySQLOptionFunc  func(*sqlgateway.MySQLOptions, *sqlgateway.ConnectOptions)
+type MySQLOptionFunc func(*sqlgateway.MySQLOptions, *sqlgateway.ConnectOptions)
 
 func UseMySQL(db *sql.DB, options ...MySQLOptionFunc) OptionFunc {
 	return func(m *Migrator) error {
@@ -31,12 +32,12 @@ func UseMySQL(db *sql.DB, options ...MySQLOptionFunc) OptionFunc {
 
 		m.closerFns = append(m.closerFns, CloserFunc(closer))
 		m.gateway = gateway
+		m.dialect = analyzer.DialectMySQL
 
 		return nil
 	}
 }
 
-
 func WithMySQLNoLock() MySQLOptionFunc {
 	return func(mysqlOpts *sqlgateway.MySQLOptions, connectOpts *sqlgateway.ConnectOptions) {
 		mysqlOpts.NoLock = true
@@ -61,6 +62,13 @@ func WithMySQLMigratedAtColumn(column string) MySQLOptionFunc {
 	}
 }
 
+// WithMySQLHistoryTable sets the append-only history table, tern_history by default
+func WithMySQLHistoryTable(historyTable string) MySQLOptionFunc {
+	return func(mysqlOpts *sqlgateway.MySQLOptions, connectOpts *sqlgateway.ConnectOptions) {
+		mysqlOpts.HistoryTable = historyTable
+	}
+}
+
 func WithMySQLLockFor(lockFor int) MySQLOptionFunc {
 	return func(mysqlOpts *sqlgateway.MySQLOptions, connectOpts *sqlgateway.ConnectOptions) {
 		mysqlOpts.LockFor = lockFor
@@ -78,3 +86,26 @@ func WithMySQLMaxConnectionAttempts(attempts int) MySQLOptionFunc {
 		connectOpts.MaxAttempts = attempts
 	}
 }
+
+// WithMySQLSessionInit executes the statements on the connection right after it is established,
+// e.g. SET SESSION lock_wait_timeout=5 or SET SESSION sql_mode='STRICT_ALL_TABLES'
+func WithMySQLSessionInit(statements ...string) MySQLOptionFunc {
+	return func(mysqlOpts *sqlgateway.MySQLOptions, connectOpts *sqlgateway.ConnectOptions) {
+		mysqlOpts.SessionInit = append(mysqlOpts.SessionInit, statements...)
+	}
+}
+
+// WithMySQLStatementTimeout limits every statement of SQL migrations, the driver
+// cancels a statement that runs longer by closing the connection
+func WithMySQLStatementTimeout(timeout time.Duration) MySQLOptionFunc {
+	return func(mysqlOpts *sqlgateway.MySQLOptions, connectOpts *sqlgateway.ConnectOptions) {
+		mysqlOpts.StatementTimeout = timeout
+	}
+}
+
+// WithMySQLMigrationTimeout limits the migrations without their own timeout directive
+func WithMySQLMigrationTimeout(timeout time.Duration) MySQLOptionFunc {
+	return func(mysqlOpts *sqlgateway.MySQLOptions, connectOpts *sqlgateway.ConnectOptions) {
+		mysqlOpts.MigrationTimeout = timeout
+	}
+}
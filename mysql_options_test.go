@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 func TestUseMySQL(t *testing.T) {
@@ -14,7 +15,7 @@ func TestUseMySQL(t *testing.T) {
 	t.Run("default mysql options", func(t *testing.T) {
 		m := Migrator{}
 		checkerRuns := 0
-		checker :=  func(mysqlOpts *sqlgateway.MySQLOptions, cOpts *sqlgateway.ConnectOptions) {
+		checker := func(mysqlOpts *sqlgateway.MySQLOptions, cOpts *sqlgateway.ConnectOptions) {
 			assert.Equal(t, "migrations", mysqlOpts.MigrationsTable)
 			assert.Equal(t, "tern_migrations", mysqlOpts.LockKey)
 			assert.Equal(t, 3, mysqlOpts.LockFor)
@@ -33,7 +34,7 @@ func TestUseMySQL(t *testing.T) {
 		m := Migrator{}
 
 		checkerRuns := 0
-		checker :=  func(mysqlOpts *sqlgateway.MySQLOptions, cOpts *sqlgateway.ConnectOptions) {
+		checker := func(mysqlOpts *sqlgateway.MySQLOptions, cOpts *sqlgateway.ConnectOptions) {
 			assert.Equal(t, "migrations", mysqlOpts.MigrationsTable)
 			assert.Equal(t, "tern_migrations", mysqlOpts.LockKey)
 			assert.Equal(t, 3, mysqlOpts.LockFor)
@@ -52,7 +53,7 @@ func TestUseMySQL(t *testing.T) {
 		m := Migrator{}
 
 		checkerRuns := 0
-		checker :=  func(mysqlOpts *sqlgateway.MySQLOptions, cOpts *sqlgateway.ConnectOptions) {
+		checker := func(mysqlOpts *sqlgateway.MySQLOptions, cOpts *sqlgateway.ConnectOptions) {
 			assert.Equal(t, "versions", mysqlOpts.MigrationsTable)
 			assert.Equal(t, "created_at", mysqlOpts.MigratedAtColumn)
 			assert.Equal(t, "foo", mysqlOpts.LockKey)
@@ -73,4 +74,28 @@ func TestUseMySQL(t *testing.T) {
 		require.NoError(t, err)
 		require.Equal(t, 1, checkerRuns)
 	})
-}
\ No newline at end of file
+
+	t.Run("session init and timeouts", func(t *testing.T) {
+		m := Migrator{}
+
+		checkerRuns := 0
+		checker := func(mysqlOpts *sqlgateway.MySQLOptions, cOpts *sqlgateway.ConnectOptions) {
+			assert.Equal(t, []string{"SET SESSION lock_wait_timeout=5", "SET SESSION sql_mode='STRICT_ALL_TABLES'"}, mysqlOpts.SessionInit)
+			assert.Equal(t, 30*time.Second, mysqlOpts.StatementTimeout)
+			assert.Equal(t, 10*time.Minute, mysqlOpts.MigrationTimeout)
+			checkerRuns++
+		}
+
+		optionsFn := UseMySQL(
+			&sql.DB{},
+			WithMySQLSessionInit("SET SESSION lock_wait_timeout=5"),
+			WithMySQLSessionInit("SET SESSION sql_mode='STRICT_ALL_TABLES'"),
+			WithMySQLStatementTimeout(30*time.Second),
+			WithMySQLMigrationTimeout(10*time.Minute),
+			checker)
+
+		err := optionsFn(&m)
+		require.NoError(t, err)
+		require.Equal(t, 1, checkerRuns)
+	})
+}
@@ -0,0 +1,171 @@
+package tern
+
+import (
+	"context"
+	"github.com/denismitr/tern/v2/internal/database"
+	"github.com/denismitr/tern/v2/internal/source"
+	"github.com/denismitr/tern/v2/migration"
+	"github.com/pkg/errors"
+	"io/fs"
+)
+
+var ErrSeedsNotConfigured = errors.New("seeds source has not been configured")
+
+type (
+	// SeedAction - selection of the seeds to run
+	SeedAction struct {
+		labels []string
+	}
+
+	SeedConfigurator func(a *SeedAction)
+)
+
+// WithLabels limits seeding to the seeds having at least one of the labels
+func WithLabels(labels ...string) SeedConfigurator {
+	return func(a *SeedAction) {
+		a.labels = append(a.labels, labels...)
+	}
+}
+
+// WithSeeding runs the seeds after a successful Migrate or Fresh
+func WithSeeding(cfs ...SeedConfigurator) ActionConfigurator {
+	return func(a *Action) {
+		a.seed = true
+		a.seedConfigurators = append(a.seedConfigurators, cfs...)
+	}
+}
+
+// UseSeedsFolder reads seeds, <version>_<name>.seed.sql files, from a local folder
+func UseSeedsFolder(folder string, configurators ...SourceConfigurator) OptionFunc {
+	var sc sourceConfig
+	sc.versionFormat = migration.AnyFormat
+	for _, c := range configurators {
+		c(&sc)
+	}
+
+	return func(m *Migrator) error {
+		s, err := source.NewLocalSeedSource(folder, m.lg, sc.versionFormat, sc.options()...)
+		if err != nil {
+			return err
+		}
+
+		m.seeds = s
+		return nil
+	}
+}
+
+// UseSeedsFS reads seeds from the dir folder of any fs.FS implementation
+func UseSeedsFS(fsys fs.FS, dir string, configurators ...SourceConfigurator) OptionFunc {
+	var sc sourceConfig
+	sc.versionFormat = migration.AnyFormat
+	for _, c := range configurators {
+		c(&sc)
+	}
+
+	return func(m *Migrator) error {
+		s, err := source.NewSeedSource(fsys, dir, m.lg, sc.versionFormat, sc.options()...)
+		if err != nil {
+			return err
+		}
+
+		m.seeds = s
+		return nil
+	}
+}
+
+// UseSeedsTable sets the table keeping applied seeds, seeds table by default
+func UseSeedsTable(table string) OptionFunc {
+	return func(m *Migrator) error {
+		m.seedsTable = table
+		return nil
+	}
+}
+
+// Seed applies the seeds that have not been applied yet, seeds limited to environments
+// with the env directive run only in those environments (see UseEnvironment),
+// applied seeds are kept in their own table and are never rolled back
+func (m *Migrator) Seed(ctx context.Context, cfs ...SeedConfigurator) (migration.Migrations, error) {
+	if m.seeds == nil {
+		return nil, ErrSeedsNotConfigured
+	}
+
+	act := new(SeedAction)
+	for _, f := range cfs {
+		f(act)
+	}
+
+	seeds, err := m.seeds.Select(ctx, source.Filter{})
+	if err != nil {
+		m.lg.Error(err)
+		return nil, err
+	}
+
+	if connErr := m.gateway.Connect(ctx); connErr != nil {
+		return nil, connErr
+	}
+
+	seeded, err := m.gateway.ForTable(m.seedsTable).Migrate(ctx, selectSeeds(seeds, m.environment, act.labels), database.Plan{})
+	if err != nil {
+		if errors.Is(err, database.ErrNoChangesRequired) {
+			return nil, ErrNothingToMigrateOrRollback
+		}
+
+		m.lg.Error(err)
+		return seeded, errors.Wrap(err, "could not seed")
+	}
+
+	return seeded, nil
+}
+
+// seedAfter runs the seeds requested with WithSeeding once the operation is done,
+// having no seeds to apply is not an error
+func (m *Migrator) seedAfter(ctx context.Context, act *Action) error {
+	if !act.seed {
+		return nil
+	}
+
+	if _, err := m.Seed(ctx, act.seedConfigurators...); err != nil && !errors.Is(err, ErrNothingToMigrateOrRollback) {
+		return err
+	}
+
+	return nil
+}
+
+// selectSeeds - seeds of the environment having at least one of the labels,
+// seeds without environments run everywhere and no labels select all of the seeds
+func selectSeeds(seeds migration.Migrations, env string, labels []string) migration.Migrations {
+	var result migration.Migrations
+	for _, s := range seeds {
+		if len(s.Environments) > 0 && !containsString(s.Environments, env) {
+			continue
+		}
+
+		if len(labels) > 0 && !containsAny(s.Labels, labels) {
+			continue
+		}
+
+		result = append(result, s)
+	}
+
+	return result
+}
+
+func containsAny(items, values []string) bool {
+	for _, v := range values {
+		if containsString(items, v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsString(items []string, value string) bool {
+	for _, item := range items {
+		if item == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -3,11 +3,20 @@ package tern
 import (
 	"github.com/denismitr/tern/v2/internal/source"
 	"github.com/denismitr/tern/v2/migration"
+	"github.com/pkg/errors"
+	"io/fs"
+	"net/http"
 )
 
+var ErrNotASource = errors.New("option does not configure a migrations source")
+
 type (
 	sourceConfig struct {
 		versionFormat migration.VersionFormat
+		recursive     bool
+		createPath    string
+		singleFile    bool
+		httpOptions   source.HTTPOptions
 	}
 
 	SourceConfigurator func(sc *sourceConfig)
@@ -21,7 +30,7 @@ func UseLocalFolderSource(folder string, configurators ...SourceConfigurator) Op
 	}
 
 	return func(m *Migrator) error {
-		conv, err := source.NewLocalFSSource(folder, m.lg, sc.versionFormat)
+		conv, err := source.NewLocalFSSource(folder, m.lg, sc.versionFormat, sc.options()...)
 		if err != nil {
 			return err
 		}
@@ -31,6 +40,127 @@ func UseLocalFolderSource(folder string, configurators ...SourceConfigurator) Op
 	}
 }
 
+// UseFSSource reads migrations from the dir folder of any fs.FS implementation
+// e.g. embed.FS, which allows to ship migrations embedded into the binary
+func UseFSSource(fsys fs.FS, dir string, configurators ...SourceConfigurator) OptionFunc {
+	var sc sourceConfig
+	sc.versionFormat = migration.AnyFormat
+	for _, c := range configurators {
+		c(&sc)
+	}
+
+	return func(m *Migrator) error {
+		s, err := source.NewFSSource(fsys, dir, m.lg, sc.versionFormat, sc.options()...)
+		if err != nil {
+			return err
+		}
+
+		m.selector = s
+		return nil
+	}
+}
+
+// UseArchiveSource reads migrations from the dir folder inside of a .zip or .tar.gz archive,
+// empty dir means the root of the archive
+func UseArchiveSource(archive, dir string, configurators ...SourceConfigurator) OptionFunc {
+	var sc sourceConfig
+	sc.versionFormat = migration.AnyFormat
+	for _, c := range configurators {
+		c(&sc)
+	}
+
+	return func(m *Migrator) error {
+		s, err := source.NewArchiveSource(archive, dir, m.lg, sc.versionFormat, sc.options()...)
+		if err != nil {
+			return err
+		}
+
+		m.selector = s
+		return nil
+	}
+}
+
+// UseHTTPSource fetches migrations listed in a JSON manifest from a remote server
+func UseHTTPSource(manifestURL string, configurators ...SourceConfigurator) OptionFunc {
+	var sc sourceConfig
+	sc.versionFormat = migration.AnyFormat
+	for _, c := range configurators {
+		c(&sc)
+	}
+
+	return func(m *Migrator) error {
+		s, err := source.NewHTTPSource(manifestURL, m.lg, sc.versionFormat, sc.httpOptions)
+		if err != nil {
+			return err
+		}
+
+		m.selector = s
+		return nil
+	}
+}
+
+// UseGitSource reads migrations folder of a local git repository as it exists
+// at the ref revision (commit, branch or tag) without checking it out
+func UseGitSource(folder, ref string, configurators ...SourceConfigurator) OptionFunc {
+	var sc sourceConfig
+	sc.versionFormat = migration.AnyFormat
+	for _, c := range configurators {
+		c(&sc)
+	}
+
+	return func(m *Migrator) error {
+		s, err := source.NewGitSource(folder, ref, m.lg, sc.versionFormat, sc.options()...)
+		if err != nil {
+			return err
+		}
+
+		m.selector = s
+		return nil
+	}
+}
+
+// UseCompositeSource merges migrations of several sources into a single ordered set,
+// each of the sources is configured with its own Use...Source option
+//
+//	tern.UseCompositeSource(
+//	    tern.UseLocalFolderSource("./migrations"),
+//	    tern.UseFSSource(plugin.Migrations, "migrations"),
+//	)
+func UseCompositeSource(sources ...OptionFunc) OptionFunc {
+	return func(m *Migrator) error {
+		var selectors []source.Selector
+		for i, opt := range sources {
+			sub := &Migrator{lg: m.lg}
+			if err := opt(sub); err != nil {
+				return err
+			}
+
+			if sub.selector == nil {
+				return errors.Wrapf(ErrNotASource, "composite source option #%d", i+1)
+			}
+
+			selectors = append(selectors, sub.selector)
+		}
+
+		s, err := source.NewCompositeSource(selectors...)
+		if err != nil {
+			return err
+		}
+
+		m.selector = s
+		return nil
+	}
+}
+
+// UseRegisteredMigrations adds Go migrations registered with migration.Register
+// to the migrations of the configured source
+func UseRegisteredMigrations() OptionFunc {
+	return func(m *Migrator) error {
+		m.registered = true
+		return nil
+	}
+}
+
 func UseInMemorySource(factories ...migration.Factory) OptionFunc {
 	return func(m *Migrator) error {
 		s, err := source.NewInMemorySource(factories...)
@@ -48,3 +178,65 @@ func WithVersionFormat(vf migration.VersionFormat) SourceConfigurator {
 		sc.versionFormat = vf
 	}
 }
+
+// WithRecursion makes the source read migrations from the nested sub folders as well
+func WithRecursion() SourceConfigurator {
+	return func(sc *sourceConfig) {
+		sc.recursive = true
+	}
+}
+
+// WithCreatePath sets a sub folder pattern, e.g. {year}/{month},
+// where the local folder source creates new migrations, it implies WithRecursion
+func WithCreatePath(pattern string) SourceConfigurator {
+	return func(sc *sourceConfig) {
+		sc.createPath = pattern
+	}
+}
+
+// WithSingleFile makes the local folder source create new migrations
+// as a single file with -- +tern migrate and -- +tern rollback sections
+func WithSingleFile() SourceConfigurator {
+	return func(sc *sourceConfig) {
+		sc.singleFile = true
+	}
+}
+
+// WithBearerToken sets a token the HTTP source sends in Authorization header,
+// only to the scheme and host of the manifest url
+func WithBearerToken(token string) SourceConfigurator {
+	return func(sc *sourceConfig) {
+		sc.httpOptions.BearerToken = token
+	}
+}
+
+// WithCacheDir enables ETag based caching of the files fetched by the HTTP source
+func WithCacheDir(dir string) SourceConfigurator {
+	return func(sc *sourceConfig) {
+		sc.httpOptions.CacheDir = dir
+	}
+}
+
+// WithHTTPClient overrides http.DefaultClient used by the HTTP source
+func WithHTTPClient(client *http.Client) SourceConfigurator {
+	return func(sc *sourceConfig) {
+		sc.httpOptions.Client = client
+	}
+}
+
+func (sc *sourceConfig) options() []source.Option {
+	var opts []source.Option
+	if sc.recursive {
+		opts = append(opts, source.WithRecursion())
+	}
+
+	if sc.createPath != "" {
+		opts = append(opts, source.WithCreatePath(sc.createPath))
+	}
+
+	if sc.singleFile {
+		opts = append(opts, source.WithSingleFile())
+	}
+
+	return opts
+}
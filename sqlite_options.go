@@ -2,6 +2,7 @@ package tern
 
 import (
 	"database/sql"
+	"github.com/denismitr/tern/v2/internal/analyzer"
 	"github.com/denismitr/tern/v2/internal/database"
 	"github.com/denismitr/tern/v2/internal/database/sqlgateway"
 	"time"
@@ -28,6 +29,7 @@ func UseSqlite(db *sql.DB, options ...SqliteOptionFunc) OptionFunc {
 		gateway, closer := sqlgateway.NewSqliteGateway(connector, sqliteOpts)
 
 		m.gateway = gateway
+		m.dialect = analyzer.DialectSqlite
 		m.closerFns = append(m.closerFns, CloserFunc(closer))
 
 		return nil
@@ -57,3 +59,32 @@ func WithSqliteMigrationTable(migrationTable string) SqliteOptionFunc {
 		mysqlOpts.MigrationsTable = migrationTable
 	}
 }
+
+// WithSqliteHistoryTable sets the append-only history table, tern_history by default
+func WithSqliteHistoryTable(historyTable string) SqliteOptionFunc {
+	return func(sqliteOpts *sqlgateway.SqliteOptions, connectOpts *sqlgateway.ConnectOptions) {
+		sqliteOpts.HistoryTable = historyTable
+	}
+}
+
+// WithSqliteSessionInit executes the statements on the connection right after it is established,
+// e.g. PRAGMA foreign_keys = ON
+func WithSqliteSessionInit(statements ...string) SqliteOptionFunc {
+	return func(sqliteOpts *sqlgateway.SqliteOptions, connectOpts *sqlgateway.ConnectOptions) {
+		sqliteOpts.SessionInit = append(sqliteOpts.SessionInit, statements...)
+	}
+}
+
+// WithSqliteStatementTimeout limits every statement of SQL migrations
+func WithSqliteStatementTimeout(timeout time.Duration) SqliteOptionFunc {
+	return func(sqliteOpts *sqlgateway.SqliteOptions, connectOpts *sqlgateway.ConnectOptions) {
+		sqliteOpts.StatementTimeout = timeout
+	}
+}
+
+// WithSqliteMigrationTimeout limits the migrations without their own timeout directive
+func WithSqliteMigrationTimeout(timeout time.Duration) SqliteOptionFunc {
+	return func(sqliteOpts *sqlgateway.SqliteOptions, connectOpts *sqlgateway.ConnectOptions) {
+		sqliteOpts.MigrationTimeout = timeout
+	}
+}
@@ -2,23 +2,44 @@ package tern
 
 import (
 	"context"
+	"github.com/denismitr/tern/v2/event"
+	"github.com/denismitr/tern/v2/internal/analyzer"
 	"github.com/denismitr/tern/v2/internal/database"
 	"github.com/denismitr/tern/v2/internal/logger"
 	"github.com/denismitr/tern/v2/internal/source"
+	"github.com/denismitr/tern/v2/metrics"
 	"github.com/denismitr/tern/v2/migration"
+	"github.com/denismitr/tern/v2/trace"
 	"github.com/pkg/errors"
 )
 
 var ErrGatewayNotInitialized = errors.New("database gateway has not been initialized")
 var ErrNothingToMigrateOrRollback = errors.New("nothing to migrate or rollback")
 
+// ErrIrreversibleMigration - rollback reached a migration that cannot be rolled back, see AllowIrreversible
+var ErrIrreversibleMigration = database.ErrIrreversibleMigration
+
+// ErrDirtyMigration - a migration or a rollback failed half way through, see ForceClean
+var ErrDirtyMigration = database.ErrDirtyMigration
+
 type CloserFunc func() error
 
 type Migrator struct {
-	lg             logger.Logger
-	gateway        database.Gateway
-	selector       source.Selector
-	closerFns      []CloserFunc
+	lg          logger.Logger
+	gateway     database.Gateway
+	selector    source.Selector
+	registered  bool
+	migrations  source.Selector
+	closerFns   []CloserFunc
+	dialect     string
+	analyzerCfg *analyzerConfig
+	analyzer    *analyzer.Analyzer
+	environment string
+	seeds       source.Selector
+	seedsTable  string
+	events      event.Handler
+	metrics     metrics.Collector
+	tracer      trace.Tracer
 }
 
 // NewMigrator creates a migrator using the sql.DB and option callbacks
@@ -27,6 +48,7 @@ type Migrator struct {
 func NewMigrator(opts ...OptionFunc) (*Migrator, CloserFunc, error) {
 	m := new(Migrator)
 	m.lg = &logger.NullLogger{}
+	m.seedsTable = database.DefaultSeedsTable
 
 	for _, oFunc := range opts {
 		if err := oFunc(m); err != nil {
@@ -53,7 +75,29 @@ func NewMigrator(opts ...OptionFunc) (*Migrator, CloserFunc, error) {
 		m.selector = localFsConverter
 	}
 
+	m.migrations = m.selector
+	if m.registered {
+		s, err := source.NewCompositeSource(m.selector, source.RegistrySource{})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		m.migrations = s
+	}
+
+	if m.analyzerCfg != nil {
+		a, err := analyzer.New(m.dialect, m.analyzerCfg.disabledRules...)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		m.analyzer = a
+	}
+
 	m.gateway.SetLogger(m.lg)
+	m.gateway.SetEventHandler(m.events)
+	m.gateway.SetMetricsCollector(m.metrics)
+	m.gateway.SetTracer(m.tracer)
 
 	closer := func() error {
 		for _, fn := range m.closerFns {
@@ -76,20 +120,24 @@ func (m *Migrator) Migrate(ctx context.Context, cfs ...ActionConfigurator) (migr
 		f(act)
 	}
 
-	migrations, err := m.selector.Select(ctx, source.Filter{Versions: act.versions})
+	migrations, err := m.migrations.Select(ctx, source.Filter{Versions: act.versions})
 	if err != nil {
 		m.lg.Error(err)
 		return nil, err
 	}
 
-	if connErr := m.gateway.Connect(); connErr != nil {
+	if connErr := m.gateway.Connect(ctx); connErr != nil {
 		return nil, connErr
 	}
 
-	p := database.Plan{Steps: act.steps, Versions: act.versions}
+	p := database.Plan{Steps: act.steps, Versions: act.versions, Check: m.checkFunc(act)}
 	migrated, err := m.gateway.Migrate(ctx, migrations, p)
 	if err != nil {
 		if errors.Is(err, database.ErrNoChangesRequired) {
+			if seedErr := m.seedAfter(ctx, act); seedErr != nil {
+				return nil, seedErr
+			}
+
 			return nil, ErrNothingToMigrateOrRollback
 		}
 
@@ -98,6 +146,10 @@ func (m *Migrator) Migrate(ctx context.Context, cfs ...ActionConfigurator) (migr
 		return migrated, err
 	}
 
+	if err := m.seedAfter(ctx, act); err != nil {
+		return migrated, err
+	}
+
 	return migrated, nil
 }
 
@@ -109,17 +161,18 @@ func (m *Migrator) Rollback(ctx context.Context, cfs ...ActionConfigurator) (mig
 		f(act)
 	}
 
-	migrations, err := m.selector.Select(ctx, source.Filter{Versions: act.versions})
+	migrations, err := m.migrations.Select(ctx, source.Filter{Versions: act.versions})
 	if err != nil {
 		m.lg.Error(err)
 		return nil, errors.Wrap(err, "could not rollback migrations")
 	}
 
-	if connErr := m.gateway.Connect(); connErr != nil {
+	if connErr := m.gateway.Connect(ctx); connErr != nil {
 		return nil, connErr
 	}
 
-	rolledBack, err := m.gateway.Rollback(ctx, migrations, database.Plan{Steps: act.steps, Versions: act.versions})
+	p := database.Plan{Steps: act.steps, Versions: act.versions, AllowIrreversible: act.allowIrreversible}
+	rolledBack, err := m.gateway.Rollback(ctx, migrations, p)
 	if err != nil {
 		if errors.Is(err, database.ErrNoChangesRequired) {
 			return nil, ErrNothingToMigrateOrRollback
@@ -141,17 +194,24 @@ func (m *Migrator) Refresh(ctx context.Context, cfs ...ActionConfigurator) (migr
 		f(act)
 	}
 
-	migrations, err := m.selector.Select(ctx, source.Filter{Versions: act.versions})
+	migrations, err := m.migrations.Select(ctx, source.Filter{Versions: act.versions})
 	if err != nil {
 		m.lg.Error(err)
 		return nil, nil, err
 	}
 
-	if connErr := m.gateway.Connect(); connErr != nil {
+	if connErr := m.gateway.Connect(ctx); connErr != nil {
 		return nil, nil, connErr
 	}
 
-	rolledBack, migrated, err := m.gateway.Refresh(ctx, migrations, database.Plan{Steps: act.steps, Versions: act.versions})
+	p := database.Plan{
+		Steps:             act.steps,
+		Versions:          act.versions,
+		Check:             m.checkFunc(act),
+		AllowIrreversible: act.allowIrreversible,
+	}
+
+	rolledBack, migrated, err := m.gateway.Refresh(ctx, migrations, p)
 	if err != nil {
 		if errors.Is(err, database.ErrNoChangesRequired) {
 			return nil, nil, ErrNothingToMigrateOrRollback
@@ -164,6 +224,21 @@ func (m *Migrator) Refresh(ctx context.Context, cfs ...ActionConfigurator) (migr
 	return rolledBack, migrated, nil
 }
 
+// ForceClean clears the dirty flag of the migration version once the database has been fixed by hand,
+// every operation is refused while a migration is dirty, the version stays migrated
+func (m *Migrator) ForceClean(ctx context.Context, version string) error {
+	if connErr := m.gateway.Connect(ctx); connErr != nil {
+		return connErr
+	}
+
+	if err := m.gateway.ForceClean(ctx, version); err != nil {
+		m.lg.Error(err)
+		return err
+	}
+
+	return nil
+}
+
 // Source - returns migrator selector if it implements the full source.Source interface
 func (m *Migrator) Source() source.Source {
 	if s, ok := m.selector.(source.Source); ok {
@@ -173,9 +248,18 @@ func (m *Migrator) Source() source.Source {
 	return nil
 }
 
+// Linter - returns migrator selector if it is able to lint its migrations
+func (m *Migrator) Linter() source.Linter {
+	if l, ok := m.selector.(source.Linter); ok {
+		return l
+	}
+
+	return nil
+}
+
 // dbGateway - return database gateway for internal testing usage
 func (m *Migrator) dbGateway() database.Gateway {
-	if err := m.gateway.Connect(); err != nil {
+	if err := m.gateway.Connect(context.Background()); err != nil {
 		panic(err)
 	}
 
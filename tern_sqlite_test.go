@@ -1,15 +1,23 @@
 package tern
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
+	"github.com/denismitr/tern/v2/event"
 	"github.com/denismitr/tern/v2/internal/database"
+	"github.com/denismitr/tern/v2/metrics"
 	"github.com/denismitr/tern/v2/migration"
+	"github.com/denismitr/tern/v2/trace"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
 	"testing"
+	"testing/fstest"
 	"time"
 )
 
@@ -49,7 +57,7 @@ func Test_Tern_WithSqlite(t *testing.T) {
 			assert.NoError(t, closer())
 		}()
 
-		ctx, cancel := context.WithTimeout(context.Background(), 20 * time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 		defer cancel()
 
 		// DO: clean up
@@ -110,7 +118,7 @@ func Test_Tern_WithSqlite(t *testing.T) {
 	})
 
 	t.Run("it_will_skip_migrations_that_are_already_in_migrations_table", func(t *testing.T) {
-		ctx, cancel := context.WithTimeout(context.Background(), 3 * time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 		defer cancel()
 
 		m, closer, err := NewMigrator(UseSqlite(db.DB), UseLocalFolderSource(sqliteMigrationsFolder))
@@ -180,7 +188,7 @@ func Test_Tern_WithSqlite(t *testing.T) {
 	})
 
 	t.Run("it_will_run_no_migrations_if_all_available_versions_are_in_migrations_table", func(t *testing.T) {
-		ctx, cancel := context.WithTimeout(context.Background(), 3 * time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 		defer cancel()
 
 		m, closer, err := NewMigrator(UseSqlite(db.DB), UseLocalFolderSource(sqliteMigrationsFolder))
@@ -200,9 +208,9 @@ func Test_Tern_WithSqlite(t *testing.T) {
 
 		// given we have already migrated these 2 migrations
 		existingMigrations, err := migration.NewMigrations(
-			migration.NewMigrationFromDB("1596897167", time.Now().Add(-2 * time.Hour), "Create foo table"),
-			migration.NewMigrationFromDB("1596897188", time.Now().Add(-2 * time.Hour), "Create bar table"),
-			migration.NewMigrationFromDB("1597897177", time.Now().Add(-2 * time.Hour), "Create baz table"),
+			migration.NewMigrationFromDB("1596897167", time.Now().Add(-2*time.Hour), "Create foo table"),
+			migration.NewMigrationFromDB("1596897188", time.Now().Add(-2*time.Hour), "Create bar table"),
+			migration.NewMigrationFromDB("1597897177", time.Now().Add(-2*time.Hour), "Create baz table"),
 		)
 
 		if err != nil {
@@ -263,7 +271,7 @@ func Test_Tern_WithSqlite(t *testing.T) {
 			assert.NoError(t, closer())
 		}()
 
-		ctx, cancel := context.WithTimeout(context.Background(), 3 * time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 		defer cancel()
 
 		keys, err := m.Migrate(ctx, WithSteps(1))
@@ -314,7 +322,7 @@ func Test_Tern_WithSqlite(t *testing.T) {
 			assert.NoError(t, closer())
 		}()
 
-		ctx, cancel := context.WithTimeout(context.Background(), 3 * time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 		defer cancel()
 
 		keys, err := m.Migrate(ctx, WithVersions(migration.Version{Value: "1596897188"}))
@@ -359,7 +367,7 @@ func Test_Tern_WithSqlite(t *testing.T) {
 			assert.NoError(t, closer())
 		}()
 
-		ctx, cancel := context.WithTimeout(context.Background(), 3 * time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 		defer cancel()
 
 		_, err = m.Migrate(ctx)
@@ -415,7 +423,7 @@ func Test_Tern_WithSqlite(t *testing.T) {
 			assert.NoError(t, closer())
 		}()
 
-		ctx, cancel := context.WithTimeout(context.Background(), 20 * time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 		defer cancel()
 
 		// DO: clean up
@@ -461,7 +469,6 @@ func Test_Tern_WithSqlite(t *testing.T) {
 	})
 }
 
-
 func Test_InMemorySourceMigrations_Sqlite(t *testing.T) {
 	db, err := sqlx.Open("sqlite3", sqliteConnection)
 	if err != nil {
@@ -499,7 +506,7 @@ func Test_InMemorySourceMigrations_Sqlite(t *testing.T) {
 			assert.NoError(t, closer())
 		}()
 
-		ctx, cancel := context.WithTimeout(context.Background(), 20 * time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 		defer cancel()
 
 		// DO: clean up
@@ -591,7 +598,7 @@ func Test_InMemorySourceMigrations_Sqlite(t *testing.T) {
 			assert.NoError(t, closer())
 		}()
 
-		ctx, cancel := context.WithTimeout(context.Background(), 20 * time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 		defer cancel()
 
 		// DO: clean up
@@ -678,7 +685,7 @@ func Test_InMemorySourceMigrations_Sqlite(t *testing.T) {
 				db.DB,
 				WithSqliteMigrationTable("migration_versions"),
 				WithSqliteMaxConnectionAttempts(10),
-				WithSqliteConnectionTimeout(3 * time.Second),
+				WithSqliteConnectionTimeout(3*time.Second),
 			),
 			source,
 		)
@@ -689,7 +696,7 @@ func Test_InMemorySourceMigrations_Sqlite(t *testing.T) {
 			assert.NoError(t, closer())
 		}()
 
-		ctx, cancel := context.WithTimeout(context.Background(), 20 * time.Second)
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
 		defer cancel()
 
 		// DO: clean up
@@ -750,4 +757,1255 @@ func Test_InMemorySourceMigrations_Sqlite(t *testing.T) {
 	})
 }
 
+func Test_MigrationDirectives_Sqlite(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", sqliteConnection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	t.Run("it can run a no transaction migration between transactional ones", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"migrations/1596897167_create_foo_table.migrate.sql":  {Data: []byte("CREATE TABLE IF NOT EXISTS foo (id binary(16) PRIMARY KEY);")},
+			"migrations/1596897167_create_foo_table.rollback.sql": {Data: []byte("DROP TABLE IF EXISTS foo;")},
+			"migrations/1596897188_vacuum.sql": {Data: []byte(
+				"-- tern:no-transaction\n-- +tern migrate\nVACUUM;\n-- +tern rollback\nVACUUM;\n",
+			)},
+			"migrations/1597897177_create_baz_table.sql": {Data: []byte(
+				"-- tern:timeout=5s\n-- tern:labels=baz\n-- +tern migrate\nCREATE TABLE IF NOT EXISTS baz (id INT);\n-- +tern rollback\nDROP TABLE IF EXISTS baz;\n",
+			)},
+		}
+
+		m, closer, err := NewMigrator(UseSqlite(db.DB), UseFSSource(fsys, "migrations"))
+		require.NoError(t, err)
+
+		defer func() {
+			assert.NoError(t, closer())
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+
+		if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		migrated, err := m.Migrate(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"1596897167_create_foo_table",
+			"1596897188_vacuum",
+			"1597897177_create_baz_table",
+		}, migrated.Keys())
+
+		assert.True(t, migrated[1].NoTransaction)
+		assert.Equal(t, 5*time.Second, migrated[2].Timeout)
+		assert.Equal(t, []string{"baz"}, migrated[2].Labels)
+
+		versions, err := m.dbGateway().ReadVersions(ctx)
+		require.NoError(t, err)
+		assert.Len(t, versions, 3)
+
+		rolledBack, err := m.Rollback(ctx)
+		require.NoError(t, err)
+		assert.Len(t, rolledBack, 3)
+
+		tables, err := m.dbGateway().ShowTables(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"migrations"}, tables)
+
+		if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func Test_CompositeSource_Sqlite(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", sqliteConnection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	t.Run("it migrates merged sources and records the origin of each migration", func(t *testing.T) {
+		core := fstest.MapFS{
+			"core/1596897167_create_foo_table.migrate.sql":  {Data: []byte("CREATE TABLE IF NOT EXISTS foo (id INT);")},
+			"core/1596897167_create_foo_table.rollback.sql": {Data: []byte("DROP TABLE IF EXISTS foo;")},
+		}
+
+		plugin := fstest.MapFS{
+			"plugin/1596897188_create_bar_table.migrate.sql":  {Data: []byte("CREATE TABLE IF NOT EXISTS bar (id INT);")},
+			"plugin/1596897188_create_bar_table.rollback.sql": {Data: []byte("DROP TABLE IF EXISTS bar;")},
+		}
+
+		m, closer, err := NewMigrator(
+			UseSqlite(db.DB),
+			UseCompositeSource(UseFSSource(core, "core"), UseFSSource(plugin, "plugin")),
+		)
+		require.NoError(t, err)
+
+		defer func() {
+			assert.NoError(t, closer())
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+
+		if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		migrated, err := m.Migrate(ctx)
+		require.NoError(t, err)
+		require.Equal(t, []string{"1596897167_create_foo_table", "1596897188_create_bar_table"}, migrated.Keys())
+
+		var origins []string
+		require.NoError(t, db.Select(&origins, "SELECT origin FROM migrations ORDER BY version"))
+		assert.Equal(t, []string{"core", "plugin"}, origins)
+
+		rolledBack, err := m.Rollback(ctx)
+		require.NoError(t, err)
+		assert.Len(t, rolledBack, 2)
+
+		if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("it adds the origin column to an existing migrations table", func(t *testing.T) {
+		_, err := db.Exec("DROP TABLE IF EXISTS migrations")
+		require.NoError(t, err)
+		_, err = db.Exec("CREATE TABLE migrations (version VARCHAR(13) PRIMARY KEY, name VARCHAR(255), migrated_at TIMESTAMP default CURRENT_TIMESTAMP)")
+		require.NoError(t, err)
+
+		m, closer, err := NewMigrator(UseSqlite(db.DB), UseLocalFolderSource(sqliteMigrationsFolder))
+		require.NoError(t, err)
+
+		defer func() {
+			assert.NoError(t, closer())
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+
+		_, err = m.Migrate(ctx)
+		require.NoError(t, err)
+
+		var origins []sql.NullString
+		require.NoError(t, db.Select(&origins, "SELECT origin FROM migrations"))
+		assert.NotEmpty(t, origins)
+
+		_, err = m.Rollback(ctx)
+		require.NoError(t, err)
+
+		if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func Test_SQLAnalyzer_Sqlite(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", sqliteConnection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	fsys := fstest.MapFS{
+		"migrations/1596897167_create_foo_table.migrate.sql":  {Data: []byte("CREATE TABLE IF NOT EXISTS foo (id INT);")},
+		"migrations/1596897167_create_foo_table.rollback.sql": {Data: []byte("DROP TABLE IF EXISTS foo;")},
+		"migrations/1596897188_clean_foo_table.migrate.sql":   {Data: []byte("DELETE FROM foo;")},
+	}
+
+	t.Run("flagged migrations are refused by the destructive guard", func(t *testing.T) {
+		m, closer, err := NewMigrator(
+			UseSqlite(db.DB),
+			UseFSSource(fsys, "migrations"),
+			UseSQLAnalyzer(WithDestructiveGuard()),
+		)
+		require.NoError(t, err)
+
+		defer func() {
+			assert.NoError(t, closer())
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+
+		if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = m.Migrate(ctx)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrDestructiveMigration))
+		assert.Contains(t, err.Error(), "1596897188_clean_foo_table [delete-without-where]")
+
+		versions, err := m.dbGateway().ReadVersions(ctx)
+		require.NoError(t, err)
+		assert.Len(t, versions, 0)
+
+		migrated, err := m.Migrate(ctx, AllowDestructive())
+		require.NoError(t, err)
+		assert.Len(t, migrated, 2)
+
+		_, err = m.Rollback(ctx, AllowIrreversible())
+		require.NoError(t, err)
+
+		if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("disabled rules do not flag migrations", func(t *testing.T) {
+		m, closer, err := NewMigrator(
+			UseSqlite(db.DB),
+			UseFSSource(fsys, "migrations"),
+			UseSQLAnalyzer(WithDestructiveGuard(), WithDisabledRules("delete-without-where")),
+		)
+		require.NoError(t, err)
+
+		defer func() {
+			assert.NoError(t, closer())
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+
+		migrated, err := m.Migrate(ctx)
+		require.NoError(t, err)
+		assert.Len(t, migrated, 2)
+
+		_, err = m.Rollback(ctx, AllowIrreversible())
+		require.NoError(t, err)
+
+		if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func Test_NumberVersions_Sqlite(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", sqliteConnection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	t.Run("it migrates and rollbacks number based versions", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"migrations/0001_create_foo_table.migrate.sql":  {Data: []byte("CREATE TABLE IF NOT EXISTS foo (id INT);")},
+			"migrations/0001_create_foo_table.rollback.sql": {Data: []byte("DROP TABLE IF EXISTS foo;")},
+			"migrations/0002_create_bar_table.migrate.sql":  {Data: []byte("CREATE TABLE IF NOT EXISTS bar (id INT);")},
+			"migrations/0002_create_bar_table.rollback.sql": {Data: []byte("DROP TABLE IF EXISTS bar;")},
+		}
+
+		m, closer, err := NewMigrator(
+			UseSqlite(db.DB),
+			UseFSSource(fsys, "migrations", WithVersionFormat(migration.NumberFormat)),
+		)
+		require.NoError(t, err)
+
+		defer func() {
+			assert.NoError(t, closer())
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+
+		if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		migrated, err := m.Migrate(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"0001_create_foo_table", "0002_create_bar_table"}, migrated.Keys())
+
+		versions, err := m.dbGateway().ReadVersions(ctx)
+		require.NoError(t, err)
+		require.Len(t, versions, 2)
+		assert.Equal(t, "0001", versions[0].Value)
+		assert.Equal(t, migration.NumberFormat, versions[0].Format)
+
+		rolledBack, err := m.Rollback(ctx, WithVersions(migration.Version{Value: "0002"}))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"0002_create_bar_table"}, rolledBack.Keys())
+
+		_, err = m.Rollback(ctx)
+		require.NoError(t, err)
+
+		if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func Test_ConvertVersions_Sqlite(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", sqliteConnection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	t.Run("mixed versions are ordered by time and converted to datetime", func(t *testing.T) {
+		folder := t.TempDir()
+		files := map[string]string{
+			"1602439886_create_foo_table.migrate.sql":      "CREATE TABLE IF NOT EXISTS foo (id INT);",
+			"1602439886_create_foo_table.rollback.sql":     "DROP TABLE IF EXISTS foo;",
+			"20190101000000_create_bar_table.migrate.sql":  "CREATE TABLE IF NOT EXISTS bar (id INT);",
+			"20190101000000_create_bar_table.rollback.sql": "DROP TABLE IF EXISTS bar;",
+		}
+
+		for name, contents := range files {
+			require.NoError(t, os.WriteFile(filepath.Join(folder, name), []byte(contents), 0644))
+		}
+
+		m, closer, err := NewMigrator(UseSqlite(db.DB), UseLocalFolderSource(folder))
+		require.NoError(t, err)
+
+		defer func() {
+			assert.NoError(t, closer())
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+
+		if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		migrated, err := m.Migrate(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"20190101000000_create_bar_table", "1602439886_create_foo_table"}, migrated.Keys())
+
+		versions, err := m.dbGateway().ReadVersions(ctx)
+		require.NoError(t, err)
+		require.Len(t, versions, 2)
+		assert.Equal(t, "20190101000000", versions[0].Value)
+		assert.Equal(t, "1602439886", versions[1].Value)
+
+		conversions, err := m.ConvertVersions(ctx, migration.DatetimeFormat)
+		require.NoError(t, err)
+		assert.Equal(t, []VersionConversion{
+			{From: "1602439886_create_foo_table", To: "20201011181126_create_foo_table"},
+		}, conversions)
+
+		assert.FileExists(t, filepath.Join(folder, "20201011181126_create_foo_table.migrate.sql"))
+		assert.FileExists(t, filepath.Join(folder, "20201011181126_create_foo_table.rollback.sql"))
+		assert.NoFileExists(t, filepath.Join(folder, "1602439886_create_foo_table.migrate.sql"))
+
+		versions, err = m.dbGateway().ReadVersions(ctx)
+		require.NoError(t, err)
+		require.Len(t, versions, 2)
+		assert.Equal(t, "20190101000000", versions[0].Value)
+		assert.Equal(t, "20201011181126", versions[1].Value)
+
+		rolledBack, err := m.Rollback(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"20201011181126_create_foo_table", "20190101000000_create_bar_table"}, rolledBack.Keys())
+
+		if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func Test_RegisteredMigrations_Sqlite(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", sqliteConnection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
 
+	migration.Register("0002", "create_bar_table", func(ctx context.Context, ex migration.Executor) error {
+		_, err := ex.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS bar (id INT);")
+		return err
+	}, func(ctx context.Context, ex migration.Executor) error {
+		_, err := ex.ExecContext(ctx, "DROP TABLE IF EXISTS bar;")
+		return err
+	})
+
+	t.Run("go migrations run alongside sql migrations", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"migrations/0001_create_foo_table.migrate.sql":  {Data: []byte("CREATE TABLE IF NOT EXISTS foo (id INT);")},
+			"migrations/0001_create_foo_table.rollback.sql": {Data: []byte("DROP TABLE IF EXISTS foo;")},
+			"migrations/0003_create_baz_table.migrate.sql":  {Data: []byte("CREATE TABLE IF NOT EXISTS baz (id INT);")},
+			"migrations/0003_create_baz_table.rollback.sql": {Data: []byte("DROP TABLE IF EXISTS baz;")},
+		}
+
+		m, closer, err := NewMigrator(
+			UseSqlite(db.DB),
+			UseRegisteredMigrations(),
+			UseFSSource(fsys, "migrations", WithVersionFormat(migration.NumberFormat)),
+		)
+		require.NoError(t, err)
+
+		defer func() {
+			assert.NoError(t, closer())
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+
+		if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		migrated, err := m.Migrate(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"0001_create_foo_table", "0002_create_bar_table", "0003_create_baz_table"}, migrated.Keys())
+
+		tables, err := m.dbGateway().ShowTables(ctx)
+		require.NoError(t, err)
+		assert.Contains(t, tables, "bar")
+
+		rolledBack, err := m.Rollback(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"0003_create_baz_table", "0002_create_bar_table", "0001_create_foo_table"}, rolledBack.Keys())
+
+		tables, err = m.dbGateway().ShowTables(ctx)
+		require.NoError(t, err)
+		assert.NotContains(t, tables, "bar")
+
+		if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func Test_IrreversibleMigrations_Sqlite(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", sqliteConnection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	t.Run("irreversible migration is rolled back only when allowed", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"migrations/0001_create_foo_table.migrate.sql":  {Data: []byte("CREATE TABLE IF NOT EXISTS foo (id INT, bar INT);")},
+			"migrations/0001_create_foo_table.rollback.sql": {Data: []byte("DROP TABLE IF EXISTS foo;")},
+			"migrations/0002_seed_foo_table.migrate.sql":    {Data: []byte("INSERT INTO foo (id, bar) VALUES (1, 2);")},
+			"migrations/0003_create_bar_table.migrate.sql":  {Data: []byte("CREATE TABLE IF NOT EXISTS bar (id INT);")},
+			"migrations/0003_create_bar_table.rollback.sql": {Data: []byte("DROP TABLE IF EXISTS bar;")},
+		}
+
+		m, closer, err := NewMigrator(UseSqlite(db.DB), UseFSSource(fsys, "migrations"))
+		require.NoError(t, err)
+
+		defer func() {
+			assert.NoError(t, closer())
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+
+		if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = m.Migrate(ctx)
+		require.NoError(t, err)
+
+		rolledBack, err := m.Rollback(ctx, WithSteps(1))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"0003_create_bar_table"}, rolledBack.Keys())
+
+		_, err = m.Rollback(ctx)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrIrreversibleMigration))
+		assert.Contains(t, err.Error(), "0002_seed_foo_table")
+
+		versions, err := m.dbGateway().ReadVersions(ctx)
+		require.NoError(t, err)
+		assert.Len(t, versions, 2)
+
+		_, _, err = m.Refresh(ctx)
+		assert.True(t, errors.Is(err, ErrIrreversibleMigration))
+
+		rolledBack, err = m.Rollback(ctx, AllowIrreversible())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"0002_seed_foo_table", "0001_create_foo_table"}, rolledBack.Keys())
+
+		if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("rollback script of irreversible migration is not run", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"migrations/0001_create_baz_table.migrate.sql":  {Data: []byte("-- tern:irreversible\nCREATE TABLE IF NOT EXISTS baz (id INT);")},
+			"migrations/0001_create_baz_table.rollback.sql": {Data: []byte("DROP TABLE IF EXISTS baz;")},
+		}
+
+		m, closer, err := NewMigrator(UseSqlite(db.DB), UseFSSource(fsys, "migrations"))
+		require.NoError(t, err)
+
+		defer func() {
+			assert.NoError(t, closer())
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+
+		if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = m.Migrate(ctx)
+		require.NoError(t, err)
+
+		rolledBack, err := m.Rollback(ctx, AllowIrreversible())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"0001_create_baz_table"}, rolledBack.Keys())
+
+		versions, err := m.dbGateway().ReadVersions(ctx)
+		require.NoError(t, err)
+		assert.Len(t, versions, 0)
+
+		var count int
+		require.NoError(t, db.Get(&count, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'baz'"))
+		assert.Equal(t, 1, count)
+
+		if _, err := db.ExecContext(ctx, "DROP TABLE IF EXISTS baz"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func Test_ResetAndFresh_Sqlite(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", sqliteConnection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	fsys := fstest.MapFS{
+		"migrations/0001_create_foo_table.migrate.sql": {Data: []byte(
+			"CREATE TABLE IF NOT EXISTS foo (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT); INSERT INTO foo (name) VALUES ('foo');",
+		)},
+		"migrations/0001_create_foo_table.rollback.sql": {Data: []byte("DROP TABLE IF EXISTS foo;")},
+		"migrations/0002_create_bar_table.migrate.sql":  {Data: []byte("CREATE TABLE IF NOT EXISTS bar (id INT);")},
+		"migrations/0002_create_bar_table.rollback.sql": {Data: []byte("DROP TABLE IF EXISTS bar;")},
+	}
+
+	t.Run("reset rolls back applied migrations and migrates all of them", func(t *testing.T) {
+		m, closer, err := NewMigrator(UseSqlite(db.DB), UseFSSource(fsys, "migrations"))
+		require.NoError(t, err)
+
+		defer func() {
+			assert.NoError(t, closer())
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+
+		if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = m.Migrate(ctx, WithSteps(1))
+		require.NoError(t, err)
+
+		rolledBack, migrated, err := m.Reset(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"0001_create_foo_table"}, rolledBack.Keys())
+		assert.Equal(t, []string{"0001_create_foo_table", "0002_create_bar_table"}, migrated.Keys())
+
+		versions, err := m.dbGateway().ReadVersions(ctx)
+		require.NoError(t, err)
+		assert.Len(t, versions, 2)
+
+		_, err = m.Rollback(ctx)
+		require.NoError(t, err)
+
+		if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("fresh drops all tables and migrates from scratch", func(t *testing.T) {
+		m, closer, err := NewMigrator(UseSqlite(db.DB), UseFSSource(fsys, "migrations"), UseEnvironment("local"))
+		require.NoError(t, err)
+
+		defer func() {
+			assert.NoError(t, closer())
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+
+		_, err = m.Migrate(ctx)
+		require.NoError(t, err)
+
+		_, err = db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS extra (id INT);")
+		require.NoError(t, err)
+
+		_, err = db.ExecContext(ctx, "CREATE VIEW IF NOT EXISTS extra_view AS SELECT id FROM extra;")
+		require.NoError(t, err)
+
+		dropped, migrated, err := m.Fresh(ctx)
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []string{"bar", "extra", "extra_view", "foo", "migrations"}, dropped)
+		assert.Equal(t, []string{"0001_create_foo_table", "0002_create_bar_table"}, migrated.Keys())
+
+		tables, err := m.dbGateway().ShowTables(ctx)
+		require.NoError(t, err)
+		assert.Contains(t, tables, "foo")
+		assert.NotContains(t, tables, "extra")
+
+		var views int
+		require.NoError(t, db.Get(&views, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'view'"))
+		assert.Equal(t, 0, views)
+
+		_, err = m.Rollback(ctx)
+		require.NoError(t, err)
+
+		if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("fresh refuses to run in production", func(t *testing.T) {
+		m, closer, err := NewMigrator(UseSqlite(db.DB), UseFSSource(fsys, "migrations"), UseEnvironment("production"))
+		require.NoError(t, err)
+
+		defer func() {
+			assert.NoError(t, closer())
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+
+		_, err = db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS extra (id INT);")
+		require.NoError(t, err)
+
+		dropped, _, err := m.Fresh(ctx)
+		assert.True(t, errors.Is(err, ErrProductionEnvironment))
+		assert.Empty(t, dropped)
+
+		tables, err := m.dbGateway().ShowTables(ctx)
+		require.NoError(t, err)
+		assert.Contains(t, tables, "extra")
+
+		_, err = db.ExecContext(ctx, "DROP TABLE extra;")
+		require.NoError(t, err)
+	})
+}
+
+func Test_Seeds_Sqlite(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", sqliteConnection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	fsys := fstest.MapFS{
+		"migrations/0001_create_roles_table.migrate.sql": {Data: []byte(
+			"CREATE TABLE IF NOT EXISTS roles (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT);",
+		)},
+		"migrations/0001_create_roles_table.rollback.sql": {Data: []byte("DROP TABLE IF EXISTS roles;")},
+		"seeds/0001_admin_role.seed.sql":                  {Data: []byte("INSERT INTO roles (name) VALUES ('admin');")},
+		"seeds/0002_demo_role.seed.sql": {Data: []byte(
+			"-- tern:env=local\n-- tern:labels=demo\nINSERT INTO roles (name) VALUES ('demo');",
+		)},
+		"seeds/0003_staging_role.seed.sql": {Data: []byte(
+			"-- tern:env=staging\nINSERT INTO roles (name) VALUES ('staging');",
+		)},
+	}
+
+	countRoles := func(ctx context.Context) int {
+		var count int
+		require.NoError(t, db.GetContext(ctx, &count, "SELECT COUNT(*) FROM roles"))
+		return count
+	}
+
+	t.Run("seeds of the environment are applied once", func(t *testing.T) {
+		m, closer, err := NewMigrator(
+			UseSqlite(db.DB),
+			UseFSSource(fsys, "migrations"),
+			UseSeedsFS(fsys, "seeds"),
+			UseEnvironment("local"),
+		)
+		require.NoError(t, err)
+
+		defer func() {
+			assert.NoError(t, closer())
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+
+		migrated, err := m.Migrate(ctx, WithSeeding(WithLabels("none")))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"0001_create_roles_table"}, migrated.Keys())
+		assert.Equal(t, 0, countRoles(ctx))
+
+		seeded, err := m.Seed(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"0001_admin_role", "0002_demo_role"}, seeded.Keys())
+		assert.Equal(t, 2, countRoles(ctx))
+
+		_, err = m.Seed(ctx)
+		assert.True(t, errors.Is(err, ErrNothingToMigrateOrRollback))
+		assert.Equal(t, 2, countRoles(ctx))
+
+		versions, err := m.dbGateway().ReadVersions(ctx)
+		require.NoError(t, err)
+		assert.Len(t, versions, 1)
+
+		_, err = m.Rollback(ctx)
+		require.NoError(t, err)
+
+		_, err = db.ExecContext(ctx, "DROP TABLE IF EXISTS seeds;")
+		require.NoError(t, err)
+
+		if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("fresh runs the seeds with the labels after the migrations", func(t *testing.T) {
+		m, closer, err := NewMigrator(
+			UseSqlite(db.DB),
+			UseFSSource(fsys, "migrations"),
+			UseSeedsFS(fsys, "seeds"),
+			UseEnvironment("local"),
+		)
+		require.NoError(t, err)
+
+		defer func() {
+			assert.NoError(t, closer())
+		}()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		defer cancel()
+
+		_, migrated, err := m.Fresh(ctx, WithSeeding(WithLabels("demo")))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"0001_create_roles_table"}, migrated.Keys())
+
+		var names []string
+		require.NoError(t, db.SelectContext(ctx, &names, "SELECT name FROM roles"))
+		assert.Equal(t, []string{"demo"}, names)
+
+		_, err = m.Rollback(ctx)
+		require.NoError(t, err)
+
+		_, err = db.ExecContext(ctx, "DROP TABLE IF EXISTS seeds;")
+		require.NoError(t, err)
+
+		if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("seeding requires a seeds source", func(t *testing.T) {
+		m, closer, err := NewMigrator(UseSqlite(db.DB), UseFSSource(fsys, "migrations"))
+		require.NoError(t, err)
+
+		defer func() {
+			assert.NoError(t, closer())
+		}()
+
+		_, err = m.Seed(context.Background())
+		assert.True(t, errors.Is(err, ErrSeedsNotConfigured))
+	})
+}
+
+func Test_History_Sqlite(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", sqliteConnection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	fsys := fstest.MapFS{
+		"migrations/0001_create_foo_table.migrate.sql":  {Data: []byte("CREATE TABLE IF NOT EXISTS foo (id INT);")},
+		"migrations/0001_create_foo_table.rollback.sql": {Data: []byte("DROP TABLE IF EXISTS foo;")},
+		"migrations/0002_broken.migrate.sql":            {Data: []byte("INSERT INTO missing_table VALUES (1);")},
+		"migrations/0002_broken.rollback.sql":           {Data: []byte("")},
+	}
+
+	m, closer, err := NewMigrator(UseSqlite(db.DB), UseFSSource(fsys, "migrations"), UseEnvironment("local"))
+	require.NoError(t, err)
+
+	defer func() {
+		assert.NoError(t, closer())
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	_, err = db.ExecContext(ctx, "DROP TABLE IF EXISTS tern_history;")
+	require.NoError(t, err)
+
+	t.Run("failed steps are kept although the transaction is rolled back", func(t *testing.T) {
+		_, err := m.Migrate(ctx)
+		require.Error(t, err)
+
+		entries, err := m.History(ctx, 0)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+
+		assert.Equal(t, "0002_broken", entries[0].Key)
+		assert.False(t, entries[0].Success)
+		assert.Contains(t, entries[0].Error, "missing_table")
+
+		assert.Equal(t, "0001_create_foo_table", entries[1].Key)
+		assert.Equal(t, "migrate", entries[1].Operation)
+		assert.Equal(t, "migrations", entries[1].Table)
+		assert.True(t, entries[1].Success)
+		assert.Equal(t, database.TernVersion, entries[1].TernVersion)
+		assert.False(t, entries[1].StartedAt.IsZero())
+
+		versions, err := m.dbGateway().ReadVersions(ctx)
+		require.NoError(t, err)
+		assert.Len(t, versions, 0)
+	})
+
+	t.Run("rollback is recorded and history survives fresh", func(t *testing.T) {
+		_, err := m.Migrate(ctx, WithSteps(1))
+		require.NoError(t, err)
+
+		_, err = m.Rollback(ctx)
+		require.NoError(t, err)
+
+		_, _, err = m.Fresh(ctx, WithVersions(migration.Version{Value: "0001"}))
+		require.NoError(t, err)
+
+		tables, err := m.dbGateway().ShowTables(ctx)
+		require.NoError(t, err)
+		assert.NotContains(t, tables, "tern_history")
+
+		entries, err := m.History(ctx, 3)
+		require.NoError(t, err)
+		require.Len(t, entries, 3)
+		assert.Equal(t, []string{"migrate", "rollback", "migrate"}, []string{
+			entries[0].Operation, entries[1].Operation, entries[2].Operation,
+		})
+
+		_, err = m.Rollback(ctx)
+		require.NoError(t, err)
+
+		all, err := m.History(ctx, 0)
+		require.NoError(t, err)
+		assert.Len(t, all, 6)
+	})
+
+	if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.ExecContext(ctx, "DROP TABLE IF EXISTS tern_history;")
+	require.NoError(t, err)
+}
+
+func Test_DirtyMigrations_Sqlite(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", sqliteConnection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	fsys := fstest.MapFS{
+		"migrations/0001_create_foo_table.migrate.sql":  {Data: []byte("CREATE TABLE IF NOT EXISTS foo (id INT);")},
+		"migrations/0001_create_foo_table.rollback.sql": {Data: []byte("DROP TABLE IF EXISTS foo;")},
+		"migrations/0002_fill_foo.migrate.sql": {Data: []byte(
+			"-- tern:no-transaction\nINSERT INTO foo VALUES (1); INSERT INTO missing_table VALUES (1);",
+		)},
+		"migrations/0002_fill_foo.rollback.sql":         {Data: []byte("DELETE FROM foo;")},
+		"migrations/0003_create_bar_table.migrate.sql":  {Data: []byte("CREATE TABLE IF NOT EXISTS bar (id INT);")},
+		"migrations/0003_create_bar_table.rollback.sql": {Data: []byte("DROP TABLE IF EXISTS bar;")},
+	}
+
+	m, closer, err := NewMigrator(UseSqlite(db.DB), UseFSSource(fsys, "migrations"))
+	require.NoError(t, err)
+
+	defer func() {
+		assert.NoError(t, closer())
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("failed no-transaction migration is left dirty", func(t *testing.T) {
+		_, err := m.Migrate(ctx)
+		require.Error(t, err)
+		assert.False(t, errors.Is(err, ErrDirtyMigration))
+
+		_, err = m.Migrate(ctx)
+		require.Error(t, err)
+		assert.True(t, errors.Is(err, ErrDirtyMigration))
+		assert.Contains(t, err.Error(), "0002_fill_foo")
+
+		_, err = m.Rollback(ctx)
+		assert.True(t, errors.Is(err, ErrDirtyMigration))
+	})
+
+	t.Run("force clean lets the operations run again", func(t *testing.T) {
+		err := m.ForceClean(ctx, "0003")
+		assert.True(t, errors.Is(err, database.ErrNoChangesRequired))
+
+		require.NoError(t, m.ForceClean(ctx, "0002"))
+
+		migrated, err := m.Migrate(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"0003_create_bar_table"}, migrated.Keys())
+
+		entries, err := m.History(ctx, 2)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, database.OperationForceClean, entries[1].Operation)
+		assert.Equal(t, "0002_fill_foo", entries[1].Key)
+
+		rolledBack, err := m.Rollback(ctx)
+		require.NoError(t, err)
+		assert.Len(t, rolledBack, 3)
+	})
+
+	if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_ProgressEvents_Sqlite(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", sqliteConnection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	fsys := fstest.MapFS{
+		"migrations/0001_create_foo_table.migrate.sql":  {Data: []byte("CREATE TABLE IF NOT EXISTS foo (id INT);")},
+		"migrations/0001_create_foo_table.rollback.sql": {Data: []byte("DROP TABLE IF EXISTS foo;")},
+		"migrations/0002_broken.migrate.sql":            {Data: []byte("INSERT INTO missing_table VALUES (1);")},
+		"migrations/0002_broken.rollback.sql":           {Data: []byte("")},
+	}
+
+	events := make(chan event.Event, 100)
+	m, closer, err := NewMigrator(UseSqlite(db.DB), UseFSSource(fsys, "migrations"), UseEventChannel(events))
+	require.NoError(t, err)
+
+	defer func() {
+		assert.NoError(t, closer())
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	drain := func() (kinds []event.Kind, result []event.Event) {
+		for {
+			select {
+			case e := <-events:
+				kinds = append(kinds, e.Kind)
+				result = append(result, e)
+			default:
+				return kinds, result
+			}
+		}
+	}
+
+	t.Run("successful migration", func(t *testing.T) {
+		_, err := m.Migrate(ctx, WithSteps(1))
+		require.NoError(t, err)
+
+		kinds, emitted := drain()
+		assert.Equal(t, []event.Kind{
+			event.LockWaiting,
+			event.LockAcquired,
+			event.PlanComputed,
+			event.MigrationStarted,
+			event.StatementExecuted,
+			event.MigrationFinished,
+			event.OperationFinished,
+		}, kinds)
+
+		assert.Equal(t, []string{"0001_create_foo_table"}, emitted[2].Migrate)
+		assert.Equal(t, "CREATE TABLE IF NOT EXISTS foo (id INT);", emitted[4].Statement)
+		assert.Equal(t, "0001", emitted[5].Version)
+		assert.Equal(t, "migrations", emitted[5].Table)
+		assert.NoError(t, emitted[5].Err)
+		assert.Equal(t, database.OperationMigrate, emitted[6].Operation)
+	})
+
+	t.Run("failed migration", func(t *testing.T) {
+		_, err := m.Migrate(ctx)
+		require.Error(t, err)
+
+		kinds, emitted := drain()
+		require.Len(t, kinds, 7)
+		assert.Equal(t, event.StatementExecuted, kinds[4])
+		assert.Error(t, emitted[4].Err)
+		assert.Equal(t, event.MigrationFinished, kinds[5])
+		assert.Equal(t, "0002_broken", emitted[5].Key)
+		assert.Error(t, emitted[5].Err)
+		assert.Equal(t, event.OperationFailed, kinds[6])
+		assert.Error(t, emitted[6].Err)
+	})
+
+	t.Run("nothing to migrate finishes the operation", func(t *testing.T) {
+		_, err := m.Rollback(ctx, WithSteps(1))
+		require.NoError(t, err)
+		drain()
+
+		_, err = m.Rollback(ctx)
+		require.True(t, errors.Is(err, ErrNothingToMigrateOrRollback))
+
+		kinds, _ := drain()
+		assert.Equal(t, []event.Kind{event.LockWaiting, event.LockAcquired, event.OperationFinished}, kinds)
+	})
+
+	if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_Metrics_Sqlite(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", sqliteConnection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	fsys := fstest.MapFS{
+		"migrations/0001_create_foo_table.migrate.sql":  {Data: []byte("CREATE TABLE IF NOT EXISTS foo (id INT);")},
+		"migrations/0001_create_foo_table.rollback.sql": {Data: []byte("DROP TABLE IF EXISTS foo;")},
+		"migrations/0002_broken.migrate.sql":            {Data: []byte("INSERT INTO missing_table VALUES (1);")},
+		"migrations/0002_broken.rollback.sql":           {Data: []byte("")},
+	}
+
+	collector := metrics.NewPrometheus()
+	m, closer, err := NewMigrator(UseSqlite(db.DB), UseFSSource(fsys, "migrations"), UseMetrics(collector))
+	require.NoError(t, err)
+
+	defer func() {
+		assert.NoError(t, closer())
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = m.Migrate(ctx, WithSteps(1))
+	require.NoError(t, err)
+
+	_, err = m.Migrate(ctx)
+	require.Error(t, err)
+
+	var buf bytes.Buffer
+	_, err = collector.WriteTo(&buf)
+	require.NoError(t, err)
+
+	exported := buf.String()
+	assert.Contains(t, exported, `tern_migrations_applied_total{table="migrations"} 1`)
+	assert.Contains(t, exported, `tern_migrations_failed_total{table="migrations",operation="migrate"} 1`)
+	assert.Contains(t, exported, `tern_operations_failed_total{table="migrations",operation="migrate"} 1`)
+	assert.Contains(t, exported, `tern_pending_migrations{table="migrations"} 1`)
+	assert.Contains(t, exported, `tern_migration_duration_seconds_count{table="migrations",operation="migrate"} 2`)
+	assert.Contains(t, exported, `tern_lock_wait_seconds_count{operation="migrate"} 2`)
+	assert.Contains(t, exported, `tern_last_success_timestamp_seconds{table="migrations"}`)
+
+	if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type recordedSpan struct {
+	name   string
+	parent string
+	attrs  map[string]interface{}
+	err    error
+}
+
+type recordingTracer struct {
+	spans []*recordedSpan
+}
+
+type spanNameKey struct{}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, attrs ...trace.Attribute) (context.Context, trace.Span) {
+	parent, _ := ctx.Value(spanNameKey{}).(string)
+	s := &recordedSpan{name: name, parent: parent, attrs: make(map[string]interface{})}
+	s.SetAttributes(attrs...)
+	t.spans = append(t.spans, s)
+
+	return context.WithValue(ctx, spanNameKey{}, name), s
+}
+
+func (s *recordedSpan) SetAttributes(attrs ...trace.Attribute) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+
+func (s *recordedSpan) End(err error) {
+	s.err = err
+}
+
+func Test_Tracing_Sqlite(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", sqliteConnection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	fsys := fstest.MapFS{
+		"migrations/0001_create_foo_table.migrate.sql":  {Data: []byte("CREATE TABLE IF NOT EXISTS foo (id INT);")},
+		"migrations/0001_create_foo_table.rollback.sql": {Data: []byte("DROP TABLE IF EXISTS foo;")},
+	}
+
+	tracer := &recordingTracer{}
+	m, closer, err := NewMigrator(UseSqlite(db.DB), UseFSSource(fsys, "migrations"), UseTracer(tracer))
+	require.NoError(t, err)
+
+	defer func() {
+		assert.NoError(t, closer())
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	tracer.spans = nil
+
+	_, err = m.Migrate(ctx)
+	require.NoError(t, err)
+
+	var names, parents []string
+	for _, s := range tracer.spans {
+		names = append(names, s.name)
+		parents = append(parents, s.parent)
+		assert.NoError(t, s.err)
+	}
+
+	assert.Equal(t, []string{
+		trace.SpanOperation, trace.SpanLock, trace.SpanSchedule, trace.SpanMigration, trace.SpanStatement,
+	}, names)
+
+	assert.Equal(t, []string{
+		"", trace.SpanOperation, trace.SpanOperation, trace.SpanOperation, trace.SpanMigration,
+	}, parents)
+
+	assert.Equal(t, "migrate", tracer.spans[0].attrs["operation"])
+	assert.Equal(t, "0001_create_foo_table", tracer.spans[3].attrs["key"])
+	assert.Equal(t, "CREATE TABLE IF NOT EXISTS foo (id INT);", tracer.spans[4].attrs["db.statement"])
+
+	if err := m.dbGateway().DropMigrationsTable(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_SessionInitAndTimeouts_Sqlite(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", sqliteConnection)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	t.Run("session init statements run on the connection of the migrator", func(t *testing.T) {
+		// temporary tables are visible to the connection that created them only
+		fsys := fstest.MapFS{
+			"migrations/0001_mark_session.migrate.sql":  {Data: []byte("INSERT INTO session_marker VALUES (1);")},
+			"migrations/0001_mark_session.rollback.sql": {Data: []byte("DELETE FROM session_marker;")},
+		}
+
+		m, closer, err := NewMigrator(
+			UseSqlite(db.DB, WithSqliteSessionInit("CREATE TEMP TABLE IF NOT EXISTS session_marker (id INT)")),
+			UseFSSource(fsys, "migrations"),
+		)
+		require.NoError(t, err)
+
+		defer func() {
+			assert.NoError(t, closer())
+		}()
+
+		require.NoError(t, m.dbGateway().DropMigrationsTable(ctx))
+
+		migrated, err := m.Migrate(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"0001_mark_session"}, migrated.Keys())
+
+		require.NoError(t, m.dbGateway().DropMigrationsTable(ctx))
+	})
+
+	t.Run("failed session init statement fails the operation", func(t *testing.T) {
+		m, closer, err := NewMigrator(
+			UseSqlite(db.DB, WithSqliteSessionInit("PRAGMA foreign_keys = ON", "SELECT * FROM missing_table")),
+			UseFSSource(fstest.MapFS{"migrations/0001_noop.migrate.sql": {Data: []byte("SELECT 1;")}}, "migrations"),
+		)
+		require.NoError(t, err)
+
+		defer func() {
+			assert.NoError(t, closer())
+		}()
+
+		_, err = m.Migrate(ctx)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "missing_table")
+	})
+
+	t.Run("statement timeout cancels a slow statement", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"migrations/0001_slow.migrate.sql": {Data: []byte(
+				"WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c LIMIT 100000000) SELECT count(*) FROM c;",
+			)},
+			"migrations/0001_slow.rollback.sql": {Data: []byte("")},
+		}
+
+		m, closer, err := NewMigrator(
+			UseSqlite(db.DB, WithSqliteStatementTimeout(50*time.Millisecond)),
+			UseFSSource(fsys, "migrations"),
+		)
+		require.NoError(t, err)
+
+		defer func() {
+			assert.NoError(t, closer())
+		}()
+
+		require.NoError(t, m.dbGateway().DropMigrationsTable(ctx))
+
+		startedAt := time.Now()
+		_, err = m.Migrate(ctx)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "statement timed out")
+		assert.Less(t, int64(time.Since(startedAt)), int64(5*time.Second))
+
+		require.NoError(t, m.dbGateway().DropMigrationsTable(ctx))
+	})
+}
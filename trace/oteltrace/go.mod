@@ -0,0 +1,15 @@
+module github.com/denismitr/tern/trace/oteltrace
+
+go 1.16
+
+require (
+	github.com/denismitr/tern/v2 v2.1.0
+	github.com/pkg/errors v0.9.1
+	go.opentelemetry.io/otel v1.0.1
+	go.opentelemetry.io/otel/sdk v1.0.1
+	go.opentelemetry.io/otel/trace v1.0.1
+)
+
+// local development only, consumers ignore replace directives and use the required
+// tern release, see the release steps in the README
+replace github.com/denismitr/tern/v2 => ../..
@@ -0,0 +1,87 @@
+// Package oteltrace - OpenTelemetry adapter of the tern tracer, so that the spans of tern
+// show up in the trace of the service, e.g. as children of its startup span
+package oteltrace
+
+import (
+	"context"
+	"fmt"
+	"github.com/denismitr/tern/v2/trace"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+	"time"
+)
+
+// InstrumentationName - name of the OpenTelemetry tracer used by tern
+const InstrumentationName = "github.com/denismitr/tern/v2"
+
+type (
+	// Tracer - starts OpenTelemetry spans for tern
+	Tracer struct {
+		tracer oteltrace.Tracer
+	}
+
+	span struct {
+		span oteltrace.Span
+	}
+)
+
+var _ trace.Tracer = (*Tracer)(nil)
+
+// New - tracer using the tracer provider, the global one if it is nil
+func New(tp oteltrace.TracerProvider) *Tracer {
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+
+	return &Tracer{tracer: tp.Tracer(InstrumentationName)}
+}
+
+func (t *Tracer) Start(ctx context.Context, name string, attrs ...trace.Attribute) (context.Context, trace.Span) {
+	ctx, s := t.tracer.Start(ctx, name, oteltrace.WithAttributes(convert(attrs)...))
+	return ctx, &span{span: s}
+}
+
+func (s *span) SetAttributes(attrs ...trace.Attribute) {
+	s.span.SetAttributes(convert(attrs)...)
+}
+
+func (s *span) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, err.Error())
+	}
+
+	s.span.End()
+}
+
+func convert(attrs []trace.Attribute) []attribute.KeyValue {
+	result := make([]attribute.KeyValue, 0, len(attrs))
+	for _, a := range attrs {
+		result = append(result, keyValue(a.Key, a.Value))
+	}
+
+	return result
+}
+
+func keyValue(key string, value interface{}) attribute.KeyValue {
+	switch v := value.(type) {
+	case string:
+		return attribute.String(key, v)
+	case bool:
+		return attribute.Bool(key, v)
+	case int:
+		return attribute.Int(key, v)
+	case int64:
+		return attribute.Int64(key, v)
+	case float64:
+		return attribute.Float64(key, v)
+	case time.Duration:
+		return attribute.String(key, v.String())
+	case fmt.Stringer:
+		return attribute.String(key, v.String())
+	}
+
+	return attribute.String(key, fmt.Sprint(value))
+}
@@ -0,0 +1,73 @@
+package oteltrace
+
+import (
+	"context"
+	"github.com/denismitr/tern/v2/trace"
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+)
+
+// TestTracer - the adapter module uses the standard testing package only,
+// so it does not pull a testify version different from the one of tern
+func TestTracer(t *testing.T) {
+	t.Parallel()
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	ctx, startup := tp.Tracer("service").Start(context.Background(), "startup")
+
+	tracer := New(tp)
+	ctx, operation := tracer.Start(ctx, trace.SpanOperation, trace.Attr("operation", "migrate"))
+	_, statement := tracer.Start(ctx, trace.SpanStatement, trace.Attr("db.statement", "SELECT 1"))
+	statement.SetAttributes(trace.Attr("attempt", 2), trace.Attr("no_transaction", true))
+	statement.End(errors.New("boom"))
+	operation.End(nil)
+	startup.End()
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 ended spans, got %d", len(spans))
+	}
+
+	if spans[0].Name() != trace.SpanStatement {
+		t.Errorf("expected span %s, got %s", trace.SpanStatement, spans[0].Name())
+	}
+
+	if spans[0].Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Errorf("statement span is not a child of the operation span")
+	}
+
+	if status := spans[0].Status(); status.Code != codes.Error || status.Description != "boom" {
+		t.Errorf("expected error status with description boom, got %v %q", status.Code, status.Description)
+	}
+
+	expected := attribute.NewSet(
+		attribute.String("db.statement", "SELECT 1"),
+		attribute.Int("attempt", 2),
+		attribute.Bool("no_transaction", true),
+	)
+	if actual := attribute.NewSet(spans[0].Attributes()...); !actual.Equals(&expected) {
+		t.Errorf("expected attributes %v, got %v", expected.ToSlice(), actual.ToSlice())
+	}
+
+	if spans[1].Name() != trace.SpanOperation {
+		t.Errorf("expected span %s, got %s", trace.SpanOperation, spans[1].Name())
+	}
+
+	if spans[1].Parent().SpanID() != spans[2].SpanContext().SpanID() {
+		t.Errorf("operation span is not a child of the startup span")
+	}
+
+	if spans[1].Status().Code != codes.Unset {
+		t.Errorf("expected unset status, got %v", spans[1].Status().Code)
+	}
+
+	if name := spans[1].InstrumentationLibrary().Name; name != InstrumentationName {
+		t.Errorf("expected instrumentation %s, got %s", InstrumentationName, name)
+	}
+}
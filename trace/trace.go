@@ -0,0 +1,59 @@
+// Package trace - small tracing abstraction, tern starts spans for connecting to the database,
+// waiting for the lock, scheduling, every migration and every statement, see the oteltrace
+// package for the OpenTelemetry adapter
+package trace
+
+import (
+	"context"
+)
+
+// Span names
+const (
+	SpanOperation  = "tern.operation"
+	SpanConnect    = "tern.connect"
+	SpanConnectTry = "tern.connect.attempt"
+	SpanLock       = "tern.lock"
+	SpanSchedule   = "tern.schedule"
+	SpanMigration  = "tern.migration"
+	SpanStatement  = "tern.statement"
+)
+
+type (
+	// Attribute - key value pair describing the span, e.g. migration key or statement
+	Attribute struct {
+		Key   string
+		Value interface{}
+	}
+
+	// Span - unit of work started by the Tracer, it must be ended exactly once
+	Span interface {
+		SetAttributes(attrs ...Attribute)
+		// End - finishes the span, err is the outcome of the work if it failed
+		End(err error)
+	}
+
+	// Tracer - starts spans as children of the span of the context, if there is one
+	Tracer interface {
+		Start(ctx context.Context, name string, attrs ...Attribute) (context.Context, Span)
+	}
+
+	// NoopTracer - tracer that records nothing, used when no tracer is configured
+	NoopTracer struct{}
+
+	noopSpan struct{}
+)
+
+var _ Tracer = NoopTracer{}
+
+// Attr - creates a span attribute
+func Attr(key string, value interface{}) Attribute {
+	return Attribute{Key: key, Value: value}
+}
+
+func (NoopTracer) Start(ctx context.Context, _ string, _ ...Attribute) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+func (noopSpan) SetAttributes(...Attribute) {}
+
+func (noopSpan) End(error) {}
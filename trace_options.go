@@ -0,0 +1,15 @@
+package tern
+
+import (
+	"github.com/denismitr/tern/v2/trace"
+)
+
+// UseTracer starts spans for connecting, waiting for the lock, scheduling, every migration
+// and every statement as children of the span of the context passed to the operation,
+// see trace/oteltrace for the OpenTelemetry tracer
+func UseTracer(t trace.Tracer) OptionFunc {
+	return func(m *Migrator) error {
+		m.tracer = t
+		return nil
+	}
+}